@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/output"
+	"github.com/abey/clean-git/internal/plan"
+)
+
+// writePlan records the full tip of every branch for apply. Branches whose
+// tip cannot be resolved are left out.
+func writePlan(out io.Writer, path string, cleanPlan *plan.Plan, branches []*git.Branch, branchService git.BranchService, recordFor func(*git.Branch) *output.Branch, records *output.Writer) {
+	fmt.Fprintln(out)
+	for _, branch := range branches {
+		record := recordFor(branch)
+		sha, err := branchService.ResolveBranchSHA(branch)
+		if err != nil {
+			fmt.Fprintf(out, "  ✗ Leaving %s out of the plan: could not resolve its tip: %v\n", branch.Name, err)
+			record.Outcome, record.Error = output.OutcomeFailed, err.Error()
+			records.Write(record)
+			continue
+		}
+		if cleanPlan.Action == plan.ActionDelete {
+			if err := branchService.CheckDeleteSafety(branch); err != nil {
+				record.Error = err.Error()
+				if cleanPlan.Force {
+					fmt.Fprintf(out, "  ! %s will be force deleted: %v\n", branch.Name, err)
+				} else {
+					fmt.Fprintf(out, "  ! %s will be refused unless the plan is made with --force: %v\n", branch.Name, err)
+				}
+			}
+		}
+
+		cleanPlan.Branches = append(cleanPlan.Branches, plan.NewBranch(branch, sha, strings.Join(record.Reasons, ", ")))
+		record.Outcome = output.OutcomePlanned
+		records.Write(record)
+	}
+
+	if err := plan.Write(path, cleanPlan); err != nil {
+		fatalWithRecords(records, errors.ExitGeneral, "%v", err)
+	}
+	fmt.Fprintf(out, "Wrote a plan to %s %d branch(es) to %s.\n", cleanPlan.Action, len(cleanPlan.Branches), path)
+	fmt.Fprintf(out, "Review it, then run 'clean-git apply %s'.\n", path)
+}
+
+func handleApplyCommand(args []string, configService config.Service, repoRoot string) {
+	applyFlags := flag.NewFlagSet("apply", flag.ExitOnError)
+	yes := applyFlags.Bool("yes", false, "Apply the plan without asking for confirmation")
+	outputFlag := applyFlags.String("output", string(output.FormatText), "Output format: text, json or ndjson (human-readable text then goes to stderr)")
+	jobs := applyFlags.String("jobs", "", "Override jobs: git commands to run at the same time")
+
+	applyFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s apply [OPTIONS] PLAN\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Delete or archive exactly the branches in PLAN, written by 'clean --plan PLAN'.\n")
+		fmt.Fprintf(os.Stderr, "A branch whose tip moved since the plan was made is skipped; remote branches\n")
+		fmt.Fprintf(os.Stderr, "are deleted with a lease so the remote refuses them if someone pushed since.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		applyFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nGlobal options like --dry-run are also available.\n")
+	}
+
+	applyFlags.Parse(args)
+	format := parseOutputFormat(*outputFlag)
+	if applyFlags.NArg() != 1 {
+		applyFlags.Usage()
+		errors.FatalError(errors.ExitGeneral, "Expected exactly one plan file")
+	}
+
+	if *jobs != "" {
+		if err := configService.Override(map[string]string{"jobs": *jobs}); err != nil {
+			errors.FatalError(errors.ExitConfig, "Invalid override: %v", err)
+		}
+	}
+
+	cleanPlan, err := plan.Read(applyFlags.Arg(0))
+	if err != nil {
+		errors.FatalError(errors.ExitGeneral, "%v", err)
+	}
+	if cleanPlan.Repository != repoRoot {
+		errors.FatalError(errors.ExitGeneral, "Plan was made for %s, not this repository (%s)", cleanPlan.Repository, repoRoot)
+	}
+
+	records, out := newRecordWriter(format, nil)
+	defer closeRecordWriter(records)
+
+	if len(cleanPlan.Branches) == 0 {
+		fmt.Fprintln(out, "The plan is empty. No branches were changed.")
+		return
+	}
+
+	fmt.Fprintf(out, "Plan made %s ago to %s %d branch(es):\n", formatDuration(time.Since(cleanPlan.CreatedAt)), cleanPlan.Action, len(cleanPlan.Branches))
+	now := time.Now()
+	var branches []*git.Branch
+	planned := make(map[string]*output.Branch)
+	for _, planBranch := range cleanPlan.Branches {
+		branch := planBranch.GitBranch()
+		branches = append(branches, branch)
+
+		record := output.NewBranch(*branch, now)
+		record.Qualifies, record.Reasons = true, []string{planBranch.Reason}
+		planned[branchKey(branch)] = record
+
+		fmt.Fprintf(out, "  - %s (%s) at %s: %s\n", branch.Name, record.Type, shortSHA(planBranch.SHA), planBranch.Reason)
+	}
+	recordFor := func(branch *git.Branch) *output.Branch {
+		return planned[branchKey(branch)]
+	}
+
+	cfg := configService.Config()
+	branchService := newBranchService(cfg)
+
+	if *dryRun {
+		fmt.Fprintln(out, "\n[DRY RUN] No actual changes performed.")
+		for _, branch := range branches {
+			record := recordFor(branch)
+			record.Outcome = output.OutcomeWouldDelete
+			if cleanPlan.Action == plan.ActionArchive {
+				record.Outcome = output.OutcomeWouldArchive
+			}
+			sha, err := branchService.ResolveBranchSHA(branch)
+			switch {
+			case err != nil:
+				record.Outcome, record.Error = output.OutcomeFailed, err.Error()
+				fmt.Fprintf(out, "  ✗ %s could not be resolved: %v\n", branch.Name, err)
+			case sha != branch.ExpectedSHA:
+				staleErr := &git.StaleTipError{Branch: branch.Name, Expected: branch.ExpectedSHA, Actual: sha}
+				record.Outcome, record.Error = output.OutcomeStale, staleErr.Error()
+				fmt.Fprintf(out, "  ! %s would be skipped: tip moved from %s to %s\n", branch.Name, shortSHA(branch.ExpectedSHA), shortSHA(sha))
+			}
+			records.Write(record)
+		}
+		return
+	}
+
+	if !*yes && stdinIsTerminal() {
+		fmt.Fprint(out, "\nApply this plan? (y/N): ")
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" && input != "yes" {
+			fmt.Fprintln(out, "Aborted. No branches were changed.")
+			return
+		}
+	}
+
+	remover := newRemoval(branchService, repoRoot, now, records, out)
+	remover.archive = cleanPlan.Action == plan.ActionArchive
+	remover.archiveRemote, remover.force = cleanPlan.ArchiveRemote, cleanPlan.Force
+	remover.jobs = cfg.Jobs
+	remover.run(branches, recordFor)
+	remover.printSummary()
+	remover.printRunID()
+	remover.exitIfInterrupted()
+}
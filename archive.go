@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/git"
+)
+
+func handleArchiveCommand(args []string, configService config.Service) {
+	archiveUsage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s archive list [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s archive purge --older-than DURATION [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Inspect or expire branches archived by 'clean --archive'.\n")
+		fmt.Fprintf(os.Stderr, "Archived branches live under %s<date>/heads/<name>, remote branches\n", git.ArchiveRefPrefix)
+		fmt.Fprintf(os.Stderr, "under %s<date>/remotes/<remote>/<name>.\n", git.ArchiveRefPrefix)
+		fmt.Fprintf(os.Stderr, "\nRun '%s archive SUBCOMMAND -h' for subcommand options.\n", os.Args[0])
+	}
+
+	if len(args) == 0 {
+		archiveUsage()
+		errors.FatalError(errors.ExitGeneral, "No archive subcommand specified")
+	}
+
+	cfg := configService.Config()
+	branchService := newBranchService(cfg)
+
+	switch args[0] {
+	case "list":
+		handleArchiveListCommand(args[1:], branchService)
+	case "purge":
+		handleArchivePurgeCommand(args[1:], branchService)
+	case "-h", "--help", "help":
+		archiveUsage()
+	default:
+		archiveUsage()
+		errors.FatalError(errors.ExitGeneral, "Unknown archive subcommand '%s'", args[0])
+	}
+}
+
+func handleArchiveListCommand(args []string, branchService git.BranchService) {
+	listFlags := flag.NewFlagSet("archive list", flag.ExitOnError)
+	remote := listFlags.String("remote", "", "List archive refs stored on this remote instead of locally")
+
+	listFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s archive list [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List archived branches, oldest first.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		listFlags.PrintDefaults()
+	}
+
+	listFlags.Parse(args)
+
+	archived, err := branchService.ListArchivedBranches(*remote)
+	if err != nil {
+		errors.FatalError(errors.ExitGit, "Failed to list archived branches: %v", err)
+	}
+
+	if len(archived) == 0 {
+		fmt.Println("No archived branches found.")
+		return
+	}
+
+	maxNameLen := len("BRANCH")
+	for _, ref := range archived {
+		if len(ref.BranchName()) > maxNameLen {
+			maxNameLen = len(ref.BranchName())
+		}
+	}
+
+	fmt.Printf("%-10s  %-*s  %s\n", "ARCHIVED", maxNameLen, "BRANCH", "SHA")
+	for _, ref := range archived {
+		fmt.Printf("%-10s  %-*s  %s\n", ref.ArchivedOn.Format("2006-01-02"), maxNameLen, ref.BranchName(), ref.SHA)
+		if *verbose {
+			fmt.Printf("  Ref: %s\n", ref.Ref)
+		}
+	}
+	fmt.Printf("\nTotal archived: %d\n", len(archived))
+}
+
+func handleArchivePurgeCommand(args []string, branchService git.BranchService) {
+	purgeFlags := flag.NewFlagSet("archive purge", flag.ExitOnError)
+	olderThan := purgeFlags.String("older-than", "", "Purge archives older than this, e.g. 90d or 6mo; a plain number means days (required)")
+	remote := purgeFlags.String("remote", "", "Purge archive refs stored on this remote instead of locally")
+
+	purgeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s archive purge --older-than DURATION [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Permanently delete archived branches older than the given age.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		purgeFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nGlobal options like --dry-run, --verbose are also available.\n")
+	}
+
+	purgeFlags.Parse(args)
+
+	if *olderThan == "" {
+		purgeFlags.Usage()
+		errors.FatalError(errors.ExitGeneral, "--older-than is required")
+	}
+	maxAge, err := parseMaxAge(*olderThan, 0)
+	if err != nil {
+		errors.FatalError(errors.ExitGeneral, "Invalid --older-than value: %v", err)
+	}
+
+	archived, err := branchService.ListArchivedBranches(*remote)
+	if err != nil {
+		errors.FatalError(errors.ExitGit, "Failed to list archived branches: %v", err)
+	}
+
+	cutoff := time.Now().Add(-time.Duration(maxAge))
+	var expired []git.ArchivedRef
+	for _, ref := range archived {
+		if ref.ArchivedOn.Before(cutoff) {
+			expired = append(expired, ref)
+		}
+	}
+
+	if len(expired) == 0 {
+		fmt.Printf("No archived branches older than %s.\n", maxAge)
+		return
+	}
+
+	if *dryRun {
+		fmt.Printf("[DRY RUN] Would purge %d archived branch(es):\n", len(expired))
+		for _, ref := range expired {
+			fmt.Printf("  - %s (archived %s)\n", ref.BranchName(), ref.ArchivedOn.Format("2006-01-02"))
+		}
+		return
+	}
+
+	var purged, failed int
+	for _, ref := range expired {
+		if err := branchService.DeleteArchivedBranch(ref); err != nil {
+			failed++
+			fmt.Printf("  ✗ Failed to purge %s: %v\n", ref.Ref, err)
+			printGitErrorHint(os.Stdout, err)
+			continue
+		}
+		purged++
+		fmt.Printf("  ✓ Purged %s (archived %s)\n", ref.BranchName(), ref.ArchivedOn.Format("2006-01-02"))
+	}
+
+	fmt.Printf("\nPurged %d archived branch(es)", purged)
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+	if failed > 0 {
+		os.Exit(int(errors.ExitGit))
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/errors"
+)
+
+// handleConfigSubcommand runs the scriptable `config get/set/unset/list/edit`
+// commands against the scope selected by --shared/--personal.
+func handleConfigSubcommand(name string, args []string, configService config.Service, usage func()) {
+	switch name {
+	case "get":
+		key := lookupConfigKey(args, 1, "get KEY")
+		fmt.Println(key.Get(configService.Config()))
+	case "set":
+		key := lookupConfigKey(args, 2, "set KEY VALUE")
+		updated := *configService.Config()
+		if err := key.Set(&updated, args[1]); err != nil {
+			errors.FatalError(errors.ExitConfig, "%v", err)
+		}
+		if err := configService.Update(&updated); err != nil {
+			errors.FatalError(errors.ExitConfig, "Failed to save configuration: %v", err)
+		}
+	case "unset":
+		key := lookupConfigKey(args, 1, "unset KEY")
+		if err := configService.Unset(key.Name); err != nil {
+			errors.FatalError(errors.ExitConfig, "Failed to unset %s: %v", key.Name, err)
+		}
+	case "list":
+		listFlags := flag.NewFlagSet("config list", flag.ExitOnError)
+		showOrigin := listFlags.Bool("show-origin", false, "Show which file (or the defaults) each value comes from")
+		listFlags.Parse(args)
+		listConfig(configService, *showOrigin)
+	case "edit":
+		if len(args) > 0 {
+			errors.FatalError(errors.ExitGeneral, "Usage: clean-git config edit")
+		}
+		if err := editConfig(configService); err != nil {
+			errors.FatalError(errors.ExitConfig, "%v", err)
+		}
+	case "list-profiles":
+		if len(args) > 0 {
+			errors.FatalError(errors.ExitGeneral, "Usage: clean-git config list-profiles")
+		}
+		listProfiles(configService)
+	case "validate":
+		if len(args) > 0 {
+			errors.FatalError(errors.ExitGeneral, "Usage: clean-git config validate")
+		}
+		if !validateConfig(configService) {
+			os.Exit(int(errors.ExitConfig))
+		}
+	default:
+		usage()
+		errors.FatalError(errors.ExitGeneral, "Unknown config subcommand '%s'", name)
+	}
+}
+
+func lookupConfigKey(args []string, count int, usage string) config.Key {
+	if len(args) != count {
+		errors.FatalError(errors.ExitGeneral, "Usage: clean-git config %s", usage)
+	}
+	key, err := config.LookupKey(args[0])
+	if err != nil {
+		errors.FatalError(errors.ExitConfig, "%v", err)
+	}
+	return key
+}
+
+func listConfig(configService config.Service, showOrigin bool) {
+	cfg := configService.Config()
+	for _, key := range config.Keys() {
+		if !showOrigin {
+			fmt.Printf("%s=%s\n", key.Name, key.Get(cfg))
+			continue
+		}
+		fmt.Printf("%s\t%s=%s\n", configService.Origin(key.Name), key.Name, key.Get(cfg))
+	}
+}
+
+func listProfiles(configService config.Service) {
+	profiles := configService.Profiles()
+	if len(profiles) == 0 {
+		fmt.Println("No profiles defined. Add them under 'profiles:' in a config file, e.g.")
+		fmt.Println("  profiles:")
+		fmt.Println("    sweep: {maxAge: 90d, archive: true}")
+		return
+	}
+
+	for i, profile := range profiles {
+		if i > 0 {
+			fmt.Println()
+		}
+		var marks []string
+		if profile.Default {
+			marks = append(marks, "default")
+		}
+		if profile.Active {
+			marks = append(marks, "active")
+		}
+		label := profile.Name
+		if len(marks) > 0 {
+			label += " (" + strings.Join(marks, ", ") + ")"
+		}
+		fmt.Printf("%s\t%s\n", label, profile.Path)
+
+		if len(profile.Changes) == 0 {
+			fmt.Println("  (changes nothing)")
+		}
+		for _, change := range profile.Changes {
+			if change.From == change.To {
+				fmt.Printf("  %s: %s (unchanged)\n", change.Key, change.To)
+				continue
+			}
+			fmt.Printf("  %s: %s -> %s\n", change.Key, change.From, change.To)
+		}
+	}
+}
+
+// validateConfig prints every problem found in the configuration and reports
+// whether it is usable. Syntax and schema errors are caught while loading, so
+// what is left are the checks that need the repository and the lint warnings.
+func validateConfig(configService config.Service) bool {
+	cfg := configService.Config()
+
+	var branchNames []string
+	branches, err := newBranchService(cfg).GetBranchesWithTrackedRemotes()
+	if err != nil {
+		fmt.Printf("warning: could not list branches, skipping branch checks: %v\n", err)
+	} else {
+		branchNames = make([]string, 0, len(branches))
+		for _, branch := range branches {
+			branchNames = append(branchNames, branch.Name)
+		}
+	}
+
+	var errorCount, warningCount int
+	for _, issue := range configService.Validate(branchNames) {
+		if issue.Warning {
+			warningCount++
+			fmt.Printf("warning: %s\n", issue)
+		} else {
+			errorCount++
+			fmt.Printf("error: %s\n", issue)
+		}
+	}
+
+	if errorCount == 0 && warningCount == 0 {
+		fmt.Println("Configuration is valid.")
+	} else {
+		fmt.Printf("%d error(s), %d warning(s)\n", errorCount, warningCount)
+	}
+	return errorCount == 0
+}
+
+// editConfig opens the current scope's file in the user's editor and saves it
+// only once it validates. On a terminal an invalid edit can be retried.
+func editConfig(configService config.Service) error {
+	path := configService.ConfigPath()
+	original, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if strings.TrimSpace(string(original)) == "{}" {
+		// An empty layer is saved as "{}"; start from a blank file instead
+		original = nil
+	}
+
+	tempFile, err := os.CreateTemp("", "clean-git-*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+	_, err = tempFile.Write(original)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		if err := runEditor(tempPath); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(tempPath)
+		if err != nil {
+			return fmt.Errorf("failed to read edited file: %w", err)
+		}
+		if string(edited) == string(original) {
+			fmt.Println("No changes made.")
+			return nil
+		}
+
+		err = configService.Replace(edited)
+		if err == nil {
+			fmt.Printf("Saved %s\n", path)
+			return nil
+		}
+		if !stdinIsTerminal() {
+			return fmt.Errorf("invalid configuration, nothing saved: %w", err)
+		}
+
+		fmt.Printf("Invalid configuration: %v\n", err)
+		fmt.Print("Edit again? (Y/n): ")
+		input, readErr := reader.ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		if readErr != nil || input == "n" || input == "no" {
+			fmt.Println("Nothing saved.")
+			return nil
+		}
+	}
+}
+
+// runEditor starts $VISUAL or $EDITOR (falling back to vi) through the shell,
+// like git does, so editor commands with arguments work.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %w", editor, err)
+	}
+	return nil
+}
+
+// configOverrides are the per-invocation flags clean and list accept to
+// override config keys. Like CLEAN_GIT_* variables they are never saved.
+type configOverrides struct {
+	values     map[string]*string
+	showConfig *bool
+}
+
+func addConfigOverrideFlags(flags *flag.FlagSet) *configOverrides {
+	return &configOverrides{
+		values: map[string]*string{
+			"baseBranches":   flags.String("base", "", "Override baseBranches (comma-separated)"),
+			"maxAge":         flags.String("max-age", "", "Override maxAge (e.g. 14d, 2w, 6mo)"),
+			"protectedRegex": flags.String("protect", "", "Override protectedRegex (comma-separated regexes)"),
+			"includeRegex":   flags.String("include", "", "Override includeRegex (comma-separated regexes)"),
+			"remoteName":     flags.String("remote", "", "Override remoteName"),
+			"jobs":           flags.String("jobs", "", "Override jobs: git commands to run at the same time"),
+		},
+		showConfig: flags.Bool("show-config", false, "Print the effective configuration and where each value comes from, then exit"),
+	}
+}
+
+// apply hands the flags that were set to the config service. It returns false
+// when --show-config printed the configuration and the command should stop.
+func (o *configOverrides) apply(configService config.Service) bool {
+	values := make(map[string]string)
+	for key, value := range o.values {
+		if *value != "" {
+			values[key] = *value
+		}
+	}
+	if err := configService.Override(values); err != nil {
+		errors.FatalError(errors.ExitConfig, "Invalid override: %v", err)
+	}
+
+	if *o.showConfig {
+		listConfig(configService, true)
+		return false
+	}
+	return true
+}
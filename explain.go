@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/evaluator"
+	"github.com/abey/clean-git/internal/git"
+)
+
+var checkMarks = map[evaluator.Result]string{
+	evaluator.ResultPass:    "✓",
+	evaluator.ResultFail:    "✗",
+	evaluator.ResultIgnored: "·",
+}
+
+func handleExplainCommand(args []string, configService config.Service) {
+	explainFlags := flag.NewFlagSet("explain", flag.ExitOnError)
+	localOnly := explainFlags.Bool("local-only", false, "Evaluate as 'clean --local-only' would")
+	remoteOnly := explainFlags.Bool("remote-only", false, "Evaluate as 'clean --remote-only' would")
+	goneFlag := explainFlags.Bool("gone", false, "Evaluate as 'clean --gone' would")
+	allRemoteFlag := explainFlags.Bool("all-remote", false, "Evaluate as 'clean --all-remote' would")
+	force := explainFlags.Bool("force", false, "Evaluate as 'clean --force' would")
+	fetchFlag := explainFlags.Bool("fetch", false, "Fetch and prune the remotes first, as 'clean --fetch' would")
+	overrides := addConfigOverrideFlags(explainFlags)
+
+	explainFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s explain [OPTIONS] BRANCH\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show why clean would or would not delete BRANCH: its merge status against every\n")
+		fmt.Fprintf(os.Stderr, "base branch and each check clean applies, with the value it compared.\n")
+		fmt.Fprintf(os.Stderr, "Use <remote>/BRANCH for the remote branch when a local one has the same name.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		explainFlags.PrintDefaults()
+	}
+
+	explainFlags.Parse(args)
+	if explainFlags.NArg() != 1 {
+		explainFlags.Usage()
+		errors.FatalError(errors.ExitGeneral, "Expected exactly one branch name")
+	}
+
+	if !configService.IsOnboarded() {
+		errors.FatalError(errors.ExitConfig, "Repository not configured. Run 'clean-git config' first")
+	}
+	if !overrides.apply(configService) {
+		return
+	}
+	cfg := configService.Config()
+
+	branchService := newBranchService(cfg)
+	refreshRemotes(os.Stdout, branchService, cfg, *fetchFlag || cfg.Fetch, time.Now())
+
+	branchEvaluator := evaluator.New(cfg, branchService, evaluator.Options{
+		LocalOnly:      *localOnly,
+		RemoteOnly:     *remoteOnly,
+		CleanGone:      *goneFlag || cfg.CleanGone,
+		CleanAllRemote: *allRemoteFlag || cfg.CleanAllRemote,
+		Force:          *force,
+	})
+	branch, err := branchEvaluator.FindBranch(explainFlags.Arg(0))
+	if err != nil {
+		errors.FatalError(errors.ExitGit, "%v", err)
+	}
+
+	printExplanation(branchEvaluator.Explain(branch), cfg)
+}
+
+func printExplanation(explanation *evaluator.Explanation, cfg *config.Config) {
+	branch := explanation.Branch
+	branchType := "local"
+	if branch.IsRemote {
+		branchType = "remote"
+	}
+	fmt.Printf("%s (%s)\n", branch.Name, branchType)
+	fmt.Printf("  Last commit %s ago by %s <%s> (%s)\n", formatDuration(time.Since(branch.LastCommitAt)), branch.AuthorUserName, branch.AuthorEmail, branch.LastCommitSHA)
+	if branch.Upstream != "" {
+		fmt.Printf("  Upstream: %s%s\n", branch.Upstream, formatTracking(&branch))
+	}
+
+	for _, base := range explanation.Bases {
+		fmt.Printf("\nBase branch %s: ", base.Base)
+		switch {
+		case base.Err != nil:
+			fmt.Printf("could not be checked: %v\n", base.Err)
+		case !base.Exists:
+			fmt.Println("not found in this repository, skipped")
+		case base.Partial:
+			fmt.Printf("partially merged (%d of %d commits), never cleaned\n", base.CommitsUpstream, base.CommitsUnique)
+		case !base.Merged:
+			fmt.Println("not merged")
+		default:
+			method := ""
+			if base.MergeMethod == git.MergeMethodSquash || base.MergeMethod == git.MergeMethodRebase {
+				method = fmt.Sprintf(" (%s)", base.MergeMethod)
+			}
+			fmt.Printf("merged%s, checking against maxAge %s\n", method, cfg.MaxAge)
+			printDecision(base.Decision)
+		}
+	}
+
+	fmt.Print("\nUpstream gone: ")
+	switch {
+	case branch.IsRemote:
+		fmt.Println("does not apply to remote branches")
+	case !branch.UpstreamGone:
+		fmt.Println("no")
+	case explanation.Gone == nil:
+		fmt.Println("yes, but cleanGone is off (use --gone)")
+	default:
+		fmt.Printf("yes, checking against goneMaxAge %s\n", cfg.GoneMaxAge)
+		printDecision(explanation.Gone)
+	}
+
+	fmt.Print("\nNo local copy: ")
+	switch {
+	case !branch.IsRemote:
+		fmt.Println("does not apply to local branches")
+	case branch.HasLocal:
+		fmt.Println("no")
+	case explanation.AllRemote != nil:
+		fmt.Printf("yes, checking against allRemoteMaxAge %s\n", cfg.AllRemoteMaxAge)
+		printDecision(explanation.AllRemote)
+	case slices.Contains(cfg.BaseBranches, branch.Name):
+		fmt.Println("yes, but base branches are never cleaned this way")
+	default:
+		fmt.Println("yes, but cleanAllRemote is off (use --all-remote)")
+	}
+
+	fmt.Println()
+	if explanation.Qualifying != nil {
+		fmt.Printf("Result: qualifies for clean (%s)\n", explanation.Qualifying.Rule)
+	} else {
+		fmt.Println("Result: not cleaned")
+	}
+}
+
+func printDecision(decision *evaluator.Decision) {
+	for _, check := range decision.Checks {
+		fmt.Printf("  %s %-24s %s\n", checkMarks[check.Result], check.Label(), check.Value)
+	}
+	if decision.Qualifies {
+		fmt.Println("  => qualifies")
+	} else {
+		fmt.Printf("  => skipped: %s\n", decision.Reason())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/git"
+)
+
+// refreshRemotes fetches and prunes the configured remotes when fetch is set,
+// so branches are evaluated against what the remotes have now. Either way it
+// then warns when the last fetch is older than fetchWarnAge.
+func refreshRemotes(w io.Writer, branchService git.BranchService, cfg *config.Config, fetch bool, now time.Time) {
+	if fetch {
+		names := make([]string, 0, len(cfg.EffectiveRemotes()))
+		for _, remote := range cfg.EffectiveRemotes() {
+			names = append(names, remote.Name)
+		}
+		fmt.Fprintf(w, "Fetching %s...\n", strings.Join(names, ", "))
+
+		pruned, err := branchService.FetchRemotes()
+		for _, ref := range pruned {
+			fmt.Fprintf(w, "  Pruned %s (deleted on the remote)\n", ref)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			printGitErrorHint(w, err)
+		}
+	}
+	warnIfStaleFetch(branchService, time.Duration(cfg.FetchWarnAge), now)
+}
+
+func warnIfStaleFetch(branchService git.BranchService, warnAge time.Duration, now time.Time) {
+	if warnAge <= 0 {
+		return
+	}
+	lastFetch, err := branchService.LastFetch()
+	if err != nil {
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "Warning: could not tell when the remotes were last fetched: %v\n", err)
+		}
+		return
+	}
+	// No FETCH_HEAD: a fresh clone, or a repository without remotes
+	if lastFetch.IsZero() {
+		return
+	}
+	if age := now.Sub(lastFetch); age > warnAge {
+		fmt.Fprintf(os.Stderr, "Warning: the remotes were last fetched %s ago; remote branches may be out of date (use --fetch)\n", formatDuration(age))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/abey/clean-git/internal/git"
+)
+
+// gitErrorHint suggests what to do about a git failure clean-git recognises,
+// or returns "" for the others.
+func gitErrorHint(err error) string {
+	switch {
+	case errors.Is(err, git.ErrAuthFailed):
+		return "the remote refused the credentials; check them, or set up a credential helper or an SSH agent for the remote"
+	case errors.Is(err, git.ErrProtectedByRemote):
+		return "the remote protects this branch; add it to protectedRegex so clean-git leaves it alone"
+	case errors.Is(err, git.ErrCheckedOutInWorktree):
+		return "switch the worktree it is checked out in to another branch, or remove it with 'git worktree remove'"
+	case errors.Is(err, git.ErrBranchNotFound):
+		return "it no longer exists; --fetch (or 'git fetch --prune') drops remote-tracking branches deleted on the remote"
+	case errors.Is(err, git.ErrRemoteNotOwned):
+		return "clean-git only changes remotes with the push role; check the remotes config key"
+	case errors.Is(err, git.ErrRemoteRejected):
+		return "the remote rejected the push; 'git fetch' and check the branch before trying again"
+	}
+	return ""
+}
+
+// isAuthFailure reports whether err is the remote refusing our credentials,
+// which every further push to it would run into as well.
+func isAuthFailure(err error) bool {
+	return errors.Is(err, git.ErrAuthFailed)
+}
+
+// printGitErrorHint prints the hint for err, if any, under the failure it explains.
+func printGitErrorHint(w io.Writer, err error) {
+	if hint := gitErrorHint(err); hint != "" {
+		fmt.Fprintf(w, "    hint: %s\n", hint)
+	}
+}
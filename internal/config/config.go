@@ -8,11 +8,25 @@ import (
 )
 
 type Config struct {
-	BaseBranches   []string      `yaml:"baseBranches,omitempty"`
-	MaxAge         time.Duration `yaml:"maxAge,omitempty"`
-	ProtectedRegex []string      `yaml:"protectedRegex,omitempty"`
-	IncludeRegex   []string      `yaml:"includeRegex,omitempty"`
-	RemoteName     string        `yaml:"remoteName,omitempty"`
+	BaseBranches       []string `yaml:"baseBranches,omitempty"`
+	MaxAge             Duration `yaml:"maxAge,omitempty"`
+	ProtectedRegex     []string `yaml:"protectedRegex,omitempty"`
+	IncludeRegex       []string `yaml:"includeRegex,omitempty"`
+	RemoteName         string   `yaml:"remoteName,omitempty"`
+	Remotes            []Remote `yaml:"remotes,omitempty"` // replaces RemoteName when set
+	Archive            bool     `yaml:"archive,omitempty"`
+	ArchiveRemote      bool     `yaml:"archiveRemote,omitempty"`
+	DetectSquashMerges bool     `yaml:"detectSquashMerges"` // no omitempty: an explicit false must survive a save
+	DetectRebaseMerges bool     `yaml:"detectRebaseMerges"`
+	CleanGone          bool     `yaml:"cleanGone,omitempty"`
+	GoneMaxAge         Duration `yaml:"goneMaxAge,omitempty"`
+	CleanAllRemote     bool     `yaml:"cleanAllRemote,omitempty"`
+	AllRemoteMaxAge    Duration `yaml:"allRemoteMaxAge,omitempty"`
+	Fetch              bool     `yaml:"fetch,omitempty"`
+	FetchWarnAge       Duration `yaml:"fetchWarnAge"` // no omitempty: zero turns the warning off
+	Jobs               int      `yaml:"jobs,omitempty"`
+	GitTimeout         Duration `yaml:"gitTimeout,omitempty"`
+	DefaultProfile     string   `yaml:"defaultProfile,omitempty"`
 }
 
 type Service interface {
@@ -21,24 +35,64 @@ type Service interface {
 	Update(cfg *Config) error
 	IsOnboarded() bool
 	ConfigPath() string
+	SetScope(scope Scope) error
+	LockedKeys() []string
+	IsLocked(key string) bool
+	Origin(key string) Origin
+	Unset(key string) error
+	Replace(data []byte) error
+	Validate(branchNames []string) []Issue
+	Override(values map[string]string) error
+	UseProfile(name string) error
+	Profiles() []Profile
+	SetProfile(name string, values map[string]string) error
+}
+
+// Origin names the layer (and its file, if any) a resolved value comes from.
+type Origin struct {
+	Layer string
+	Path  string
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		BaseBranches:   []string{"main", "master", "develop"},
-		MaxAge:         720 * time.Hour * 24, // 30 days
-		ProtectedRegex: []string{"release/*", "hotfix/*"},
-		IncludeRegex:   []string{".*"},
-		RemoteName:     "origin",
+		BaseBranches:       []string{"main", "master", "develop"},
+		MaxAge:             720 * Day,                         // roughly two years
+		ProtectedRegex:     []string{"release/*", "hotfix/*"}, // saved configs inherit defaults, so never change these silently
+		IncludeRegex:       []string{".*"},
+		RemoteName:         "origin",
+		DetectSquashMerges: true,
+		DetectRebaseMerges: true,
+		GoneMaxAge:         7 * Day,
+		AllRemoteMaxAge:    90 * Day,
+		FetchWarnAge:       7 * Day,
+		Jobs:               4,
+		GitTimeout:         Duration(5 * time.Minute),
 	}
 }
 
 func getGlobalConfigPath() (string, error) {
+	return GetDefaultConfigPath()
+}
+
+func getLegacyConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(homeDir, ".clean-git", "config.yaml"), nil
+	return filepath.Join(homeDir, LegacyConfigFile), nil
+}
+
+func getRepoConfigPath(repoRoot string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	key, err := RepoConfigKey(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ConfigDir, RepoConfigDir, key+".yaml"), nil
 }
 
 func ensureConfigDirExists(configPath string) error {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -47,7 +48,7 @@ func TestConfigService(t *testing.T) {
 		cfg := service.Config()
 		assert.Equal(t, []string{"main", "master", "develop"}, cfg.BaseBranches)
 		assert.Equal(t, []string{".*"}, cfg.IncludeRegex)
-		assert.Equal(t, 720*time.Hour*24, cfg.MaxAge)
+		assert.Equal(t, 720*Day, cfg.MaxAge)
 		assert.Equal(t, []string{"release/*", "hotfix/*"}, cfg.ProtectedRegex)
 		assert.Equal(t, []string{".*"}, cfg.IncludeRegex)
 		assert.Equal(t, "origin", cfg.RemoteName)
@@ -61,7 +62,7 @@ func TestConfigService(t *testing.T) {
 
 		cfg := service.Config()
 		cfg.BaseBranches = []string{"main", "develop"}
-		cfg.MaxAge = 168 * time.Hour // 7 days
+		cfg.MaxAge = Duration(168 * time.Hour) // 7 days
 		cfg.ProtectedRegex = []string{"feature/*"}
 		cfg.IncludeRegex = []string{"feature/.*"}
 		cfg.RemoteName = "upstream"
@@ -74,7 +75,7 @@ func TestConfigService(t *testing.T) {
 
 		cfg = service.Config()
 		assert.Equal(t, []string{"main", "develop"}, cfg.BaseBranches)
-		assert.Equal(t, 168*time.Hour, cfg.MaxAge)
+		assert.Equal(t, Duration(168*time.Hour), cfg.MaxAge)
 		assert.Equal(t, []string{"feature/*"}, cfg.ProtectedRegex)
 		assert.Equal(t, []string{"feature/.*"}, cfg.IncludeRegex)
 		assert.Equal(t, "upstream", cfg.RemoteName)
@@ -94,6 +95,699 @@ func TestConfigService(t *testing.T) {
 	})
 }
 
+func TestLayeredConfig(t *testing.T) {
+	writeFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("RepoOverridesGlobalOverridesDefaults", func(t *testing.T) {
+		tempDir := t.TempDir()
+		homeDir, restore := setupHome(t, tempDir)
+		defer restore()
+
+		writeFile(t, filepath.Join(homeDir, ConfigDir, GlobalConfigFile), "remoteName: upstream\nmaxAge: 48h\n")
+		repoPath, err := getRepoConfigPath(tempDir)
+		require.NoError(t, err)
+		writeFile(t, repoPath, "maxAge: 24h\n")
+
+		cfg := newServiceFor(t, tempDir).Config()
+		assert.Equal(t, "upstream", cfg.RemoteName)
+		assert.Equal(t, Duration(24*time.Hour), cfg.MaxAge)
+		assert.Equal(t, []string{"main", "master", "develop"}, cfg.BaseBranches)
+	})
+
+	t.Run("SaveWritesOnlyChangedKeysToRepoLayer", func(t *testing.T) {
+		tempDir := t.TempDir()
+		homeDir, restore := setupHome(t, tempDir)
+		defer restore()
+
+		globalPath := filepath.Join(homeDir, ConfigDir, GlobalConfigFile)
+		writeFile(t, globalPath, "remoteName: upstream\n")
+		service := newServiceFor(t, tempDir)
+
+		service.Config().BaseBranches = []string{"trunk"}
+		service.Config().ProtectedRegex = nil
+		require.NoError(t, service.Save())
+
+		data, err := os.ReadFile(service.ConfigPath())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "trunk")
+		assert.Contains(t, string(data), "protectedRegex: null")
+		assert.NotContains(t, string(data), "remoteName")
+		assert.NotContains(t, string(data), "maxAge:")
+
+		global, err := os.ReadFile(globalPath)
+		require.NoError(t, err)
+		assert.Equal(t, "remoteName: upstream\n", string(global))
+
+		cfg := newServiceFor(t, tempDir).Config()
+		assert.Equal(t, []string{"trunk"}, cfg.BaseBranches)
+		assert.Empty(t, cfg.ProtectedRegex)
+		assert.Equal(t, "upstream", cfg.RemoteName)
+	})
+
+	t.Run("RepositoriesAreIndependent", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		repo1 := filepath.Join(tempDir, "repo1")
+		repo2 := filepath.Join(tempDir, "repo2")
+		service1 := newServiceFor(t, repo1)
+		service2 := newServiceFor(t, repo2)
+		assert.NotEqual(t, service1.ConfigPath(), service2.ConfigPath())
+
+		service1.Config().RemoteName = "fork"
+		require.NoError(t, service1.Save())
+
+		assert.True(t, service1.IsOnboarded())
+		assert.False(t, newServiceFor(t, repo2).IsOnboarded())
+		assert.Equal(t, "origin", newServiceFor(t, repo2).Config().RemoteName)
+	})
+
+	t.Run("MigratesLegacyConfig", func(t *testing.T) {
+		tempDir := t.TempDir()
+		homeDir, restore := setupHome(t, tempDir)
+		defer restore()
+
+		legacyPath := filepath.Join(homeDir, LegacyConfigFile)
+		writeFile(t, legacyPath, "remoteName: upstream\n")
+
+		service := newServiceFor(t, tempDir)
+		assert.Equal(t, "upstream", service.Config().RemoteName)
+		assert.True(t, service.IsOnboarded())
+
+		_, err := os.Stat(legacyPath)
+		assert.True(t, os.IsNotExist(err))
+		data, err := os.ReadFile(filepath.Join(homeDir, ConfigDir, GlobalConfigFile))
+		require.NoError(t, err)
+		assert.Equal(t, "remoteName: upstream\n", string(data))
+
+		_, err = os.Stat(service.ConfigPath())
+		assert.True(t, os.IsNotExist(err), "no repository file is needed")
+
+		// The legacy file applied to every repository, and so does the global one
+		other := newServiceFor(t, filepath.Join(tempDir, "other"))
+		assert.True(t, other.IsOnboarded())
+		assert.Equal(t, "upstream", other.Config().RemoteName)
+	})
+}
+
+func TestSharedConfig(t *testing.T) {
+	writeShared := func(t *testing.T, repoRoot, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(repoRoot, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(repoRoot, SharedConfigFile), []byte(content), 0644))
+	}
+
+	t.Run("LockedKeysIgnorePersonalValues", func(t *testing.T) {
+		tempDir := t.TempDir()
+		homeDir, restore := setupHome(t, tempDir)
+		defer restore()
+
+		repoRoot := filepath.Join(tempDir, "repo")
+		writeShared(t, repoRoot, "protectedRegex: [^prod/]\nmaxAge: 240h\noverridable: [maxAge]\n")
+		globalPath := filepath.Join(homeDir, ConfigDir, GlobalConfigFile)
+		require.NoError(t, os.MkdirAll(filepath.Dir(globalPath), 0755))
+		require.NoError(t, os.WriteFile(globalPath, []byte("protectedRegex: [mine]\nmaxAge: 24h\n"), 0644))
+
+		service := newServiceFor(t, repoRoot)
+		cfg := service.Config()
+		assert.Equal(t, []string{"^prod/"}, cfg.ProtectedRegex)
+		assert.Equal(t, Duration(24*time.Hour), cfg.MaxAge)
+		assert.Equal(t, []string{"protectedRegex"}, service.LockedKeys())
+		assert.True(t, service.IsLocked("protectedRegex"))
+		assert.False(t, service.IsLocked("maxAge"))
+		assert.True(t, service.IsOnboarded())
+	})
+
+	t.Run("PersonalSaveRefusesLockedKeys", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		repoRoot := filepath.Join(tempDir, "repo")
+		writeShared(t, repoRoot, "remoteName: upstream\n")
+		service := newServiceFor(t, repoRoot)
+
+		service.Config().MaxAge = Duration(48 * time.Hour)
+		require.NoError(t, service.Save())
+
+		service.Config().RemoteName = "fork"
+		err := service.Save()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not overridable")
+	})
+
+	t.Run("SharedScopeWritesTeamFile", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		repoRoot := filepath.Join(tempDir, "repo")
+		writeShared(t, repoRoot, "remoteName: upstream\noverridable: [maxAge]\n")
+		service := newServiceFor(t, repoRoot)
+
+		// A personal override must not leak into the team file
+		service.Config().MaxAge = Duration(48 * time.Hour)
+		require.NoError(t, service.Save())
+
+		require.NoError(t, service.SetScope(ScopeShared))
+		assert.Equal(t, filepath.Join(repoRoot, SharedConfigFile), service.ConfigPath())
+		assert.Equal(t, DefaultConfig().MaxAge, service.Config().MaxAge)
+
+		service.Config().BaseBranches = []string{"trunk"}
+		require.NoError(t, service.Save())
+
+		data, err := os.ReadFile(filepath.Join(repoRoot, SharedConfigFile))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "trunk")
+		assert.Contains(t, string(data), "remoteName: upstream")
+		assert.Contains(t, string(data), "overridable:")
+		assert.NotContains(t, string(data), "maxAge:")
+
+		cfg := newServiceFor(t, repoRoot).Config()
+		assert.Equal(t, []string{"trunk"}, cfg.BaseBranches)
+		assert.Equal(t, Duration(48*time.Hour), cfg.MaxAge)
+	})
+
+	t.Run("InvalidScope", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		assert.Error(t, newServiceFor(t, tempDir).SetScope("team"))
+	})
+}
+
+func TestConfigKeys(t *testing.T) {
+	t.Run("SetAndGet", func(t *testing.T) {
+		cfg := DefaultConfig()
+		for name, value := range map[string]string{
+			"baseBranches":       "main, develop",
+			"maxAge":             "14",
+			"protectedRegex":     "^release/,^hotfix/",
+			"remoteName":         "upstream",
+			"detectSquashMerges": "false",
+		} {
+			key, err := LookupKey(name)
+			require.NoError(t, err)
+			require.NoError(t, key.Set(cfg, value))
+		}
+
+		assert.Equal(t, []string{"main", "develop"}, cfg.BaseBranches)
+		assert.Equal(t, 14*Day, cfg.MaxAge)
+		assert.False(t, cfg.DetectSquashMerges)
+
+		key, err := LookupKey("maxAge")
+		require.NoError(t, err)
+		assert.Equal(t, "2w", key.Get(cfg))
+		key, err = LookupKey("baseBranches")
+		require.NoError(t, err)
+		assert.Equal(t, "main,develop", key.Get(cfg))
+	})
+
+	t.Run("RemotesWithRoles", func(t *testing.T) {
+		cfg := DefaultConfig()
+		assert.Equal(t, []Remote{{Name: "origin", Roles: []RemoteRole{RolePush, RoleMergeSource}}}, cfg.EffectiveRemotes())
+
+		key, err := LookupKey("remotes")
+		require.NoError(t, err)
+		require.NoError(t, key.Set(cfg, "origin:push, upstream:merge-source, mirror"))
+		assert.Equal(t, []Remote{
+			{Name: "origin", Roles: []RemoteRole{RolePush}},
+			{Name: "upstream", Roles: []RemoteRole{RoleMergeSource}},
+			{Name: "mirror", Roles: []RemoteRole{RolePush, RoleMergeSource}},
+		}, cfg.EffectiveRemotes())
+		assert.Equal(t, "origin:push,upstream:merge-source,mirror:push+merge-source", key.Get(cfg))
+
+		for _, invalid := range []string{"origin:pull", "origin,origin", "my remote:push", "origin:"} {
+			assert.Error(t, key.Set(DefaultConfig(), invalid), invalid)
+		}
+		assert.ErrorContains(t, key.Set(DefaultConfig(), "team/fork:push"), "containing '/' are not supported")
+
+		_, issues := decodeLayer(LayerRepo, "repo.yaml", []byte("remotes:\n  - name: origin\n    roles: [push, pull]\n"))
+		require.Len(t, issues, 1)
+		assert.Equal(t, "remotes", issues[0].Key)
+		assert.Contains(t, issues[0].Message, "unknown role 'pull'")
+	})
+
+	t.Run("SetValidatesLikeThePrompts", func(t *testing.T) {
+		cfg := DefaultConfig()
+		for name, value := range map[string]string{
+			"baseBranches":    " , ",
+			"maxAge":          "-1",
+			"goneMaxAge":      "a week",
+			"protectedRegex":  "[",
+			"remoteName":      "my remote",
+			"archive":         "sometimes",
+			"jobs":            "0",
+			"gitTimeout":      "soon",
+			"remotes":         "origin:pull",
+			"allRemoteMaxAge": "a quarter",
+			"fetch":           "often",
+			"fetchWarnAge":    "-2",
+		} {
+			key, err := LookupKey(name)
+			require.NoError(t, err)
+			assert.Error(t, key.Set(cfg, value), name)
+		}
+		assert.Equal(t, DefaultConfig(), cfg)
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		_, err := LookupKey("maxage")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "maxAge")
+	})
+
+	t.Run("EveryKeyIsAConfigField", func(t *testing.T) {
+		values, err := configToMap(&Config{
+			BaseBranches: []string{"main"}, MaxAge: Duration(time.Hour), ProtectedRegex: []string{"x"},
+			IncludeRegex: []string{"y"}, RemoteName: "origin", Archive: true, ArchiveRemote: true,
+			CleanGone: true, GoneMaxAge: Duration(time.Hour), DefaultProfile: "safe",
+			Jobs: 2, GitTimeout: Duration(time.Minute),
+			Remotes:        []Remote{{Name: "origin", Roles: []RemoteRole{RolePush}}},
+			CleanAllRemote: true, AllRemoteMaxAge: Duration(time.Hour),
+			Fetch: true, FetchWarnAge: Duration(time.Hour),
+		})
+		require.NoError(t, err)
+		for _, key := range Keys() {
+			assert.Contains(t, values, key.Name)
+		}
+		assert.Len(t, Keys(), len(values))
+	})
+}
+
+func TestConfigService_OriginUnsetReplace(t *testing.T) {
+	t.Run("OriginFollowsLayers", func(t *testing.T) {
+		tempDir := t.TempDir()
+		homeDir, restore := setupHome(t, tempDir)
+		defer restore()
+
+		globalPath := filepath.Join(homeDir, ConfigDir, GlobalConfigFile)
+		require.NoError(t, os.MkdirAll(filepath.Dir(globalPath), 0755))
+		require.NoError(t, os.WriteFile(globalPath, []byte("maxAge: 24h\n"), 0644))
+
+		service := newServiceFor(t, tempDir)
+		service.Config().RemoteName = "upstream"
+		require.NoError(t, service.Save())
+
+		assert.Equal(t, Origin{Layer: LayerDefaults}, service.Origin("baseBranches"))
+		assert.Equal(t, Origin{Layer: LayerGlobal, Path: globalPath}, service.Origin("maxAge"))
+		assert.Equal(t, Origin{Layer: LayerRepo, Path: service.ConfigPath()}, service.Origin("remoteName"))
+	})
+
+	t.Run("UnsetFallsBackToInheritedValue", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		service := newServiceFor(t, tempDir)
+		service.Config().RemoteName = "upstream"
+		require.NoError(t, service.Save())
+
+		require.NoError(t, service.Unset("remoteName"))
+		assert.Equal(t, "origin", service.Config().RemoteName)
+		assert.Equal(t, "origin", newServiceFor(t, tempDir).Config().RemoteName)
+		assert.True(t, service.IsOnboarded())
+
+		assert.Error(t, service.Unset("bogus"))
+	})
+
+	t.Run("ReplaceValidatesBeforeWriting", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		service := newServiceFor(t, tempDir)
+		require.NoError(t, service.Replace([]byte("# mine\nmaxAge: 48h\n")))
+		assert.Equal(t, Duration(48*time.Hour), service.Config().MaxAge)
+
+		for _, invalid := range []string{
+			"maxAge: [",
+			"bogus: true\n",
+			"protectedRegex: ['(']\n",
+			"remoteName: a b\n",
+			"overridable: [maxAge]\n",
+		} {
+			assert.Error(t, service.Replace([]byte(invalid)), invalid)
+		}
+
+		data, err := os.ReadFile(service.ConfigPath())
+		require.NoError(t, err)
+		assert.Equal(t, "# mine\nmaxAge: 48h\n", string(data))
+		assert.Equal(t, Duration(48*time.Hour), service.Config().MaxAge)
+	})
+
+	t.Run("ReplaceRefusesLockedKeys", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, SharedConfigFile), []byte("remoteName: upstream\n"), 0644))
+		service := newServiceFor(t, tempDir)
+
+		err := service.Replace([]byte("remoteName: fork\n"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not overridable")
+	})
+}
+
+func TestConfigValidation(t *testing.T) {
+	t.Run("StrictDecodingReportsPositions", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		content := "maxage: 3\nmaxAge: 0s\nprotectedRegex: [ok, \"(\"]\narchive: maybe\noverridable: [maxAge]\n"
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, SharedConfigFile), []byte("{}\n"), 0644))
+		service := newServiceFor(t, tempDir)
+
+		err := service.Replace([]byte(content))
+		require.Error(t, err)
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr)
+
+		var found []string
+		for _, issue := range validationErr.Issues {
+			found = append(found, fmt.Sprintf("%d:%d %s", issue.Line, issue.Column, issue.Key))
+			assert.False(t, issue.Warning)
+		}
+		assert.Equal(t, []string{"1:1 maxage", "2:9 maxAge", "3:22 protectedRegex", "4:10 archive", "5:1 overridable"}, found)
+	})
+
+	t.Run("LoadRejectsInvalidFiles", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, SharedConfigFile), []byte("includeRegex: [\"[\"]\n"), 0644))
+		_, err := NewService(tempDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), SharedConfigFile+":1:16: includeRegex: invalid regex pattern")
+	})
+
+	t.Run("SaveRejectsZeroMaxAge", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		service := newServiceFor(t, tempDir)
+		service.Config().MaxAge = 0
+		assert.Error(t, service.Save())
+		assert.False(t, service.IsOnboarded())
+	})
+
+	t.Run("LintFindsLikelyMistakes", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		content := "baseBranches: [main, trunk]\nprotectedRegex: [release/*, ^feature/keep]\nincludeRegex: [^feature/]\n"
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, SharedConfigFile), []byte(content), 0644))
+		service := newServiceFor(t, tempDir)
+
+		issues := service.Validate([]string{"main", "feature/keep", "feature/old", "release/1.0"})
+		require.Len(t, issues, 3)
+		for _, issue := range issues {
+			assert.True(t, issue.Warning)
+			assert.Equal(t, filepath.Join(tempDir, SharedConfigFile), issue.Path)
+		}
+		assert.Equal(t, "protectedRegex", issues[0].Key)
+		assert.Equal(t, 2, issues[0].Line)
+		assert.Contains(t, issues[0].Message, "did you mean '^release/'")
+		assert.Equal(t, "includeRegex", issues[1].Key)
+		assert.Contains(t, issues[1].Message, "feature/keep")
+		assert.Equal(t, "baseBranches", issues[2].Key)
+		assert.Contains(t, issues[2].Message, "trunk")
+	})
+
+	t.Run("LintFlagsTheDefaultPatterns", func(t *testing.T) {
+		issues := Lint(DefaultConfig(), nil)
+		require.Len(t, issues, 2)
+		for _, issue := range issues {
+			assert.Equal(t, "protectedRegex", issue.Key)
+			assert.True(t, issue.Warning)
+		}
+	})
+
+	t.Run("LintRemotesWithoutRoles", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ProtectedRegex = []string{"^release/", "^hotfix/"}
+		cfg.Remotes = []Remote{{Name: "upstream", Roles: []RemoteRole{RoleMergeSource}}}
+
+		issues := Lint(cfg, nil)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "remotes", issues[0].Key)
+		assert.Contains(t, issues[0].Message, "never cleaned")
+	})
+
+	t.Run("LintMissingBaseBranches", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ProtectedRegex = []string{"^release/", "^hotfix/"}
+		issues := Lint(cfg, []string{"feature/x"})
+		require.Len(t, issues, 1)
+		assert.False(t, issues[0].Warning)
+
+		// Catch-all include patterns are expected to overlap protected branches
+		assert.Empty(t, Lint(cfg, []string{"main", "master", "develop", "release/1.0"}))
+		assert.Empty(t, Lint(cfg, nil))
+	})
+}
+
+func TestDuration(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		for input, expected := range map[string]Duration{
+			"90d":     90 * Day,
+			"2w":      2 * Week,
+			"6mo":     6 * Month,
+			"1y":      Year,
+			"36h":     Duration(36 * time.Hour),
+			"1y6mo":   Year + 6*Month,
+			"30":      30 * Day,
+			"24h0m0s": Day,
+		} {
+			parsed, err := ParseDuration(input)
+			require.NoError(t, err, input)
+			assert.Equal(t, expected, parsed, input)
+		}
+
+		for _, invalid := range []string{"", "-3", "3x", "d", "2w junk", "-2h", "99999999999d", "200000"} {
+			_, err := ParseDuration(invalid)
+			assert.Error(t, err, invalid)
+		}
+	})
+
+	t.Run("MinutesAreRefused", func(t *testing.T) {
+		for _, input := range []string{"6m", "1y6m", "6m2d"} {
+			_, err := ParseDuration(input)
+			require.Error(t, err, input)
+			assert.Contains(t, err.Error(), "did you mean 6mo?", input)
+		}
+		parsed, err := ParseDuration("90m0s")
+		require.NoError(t, err, "older versions wrote minutes in this form")
+		assert.Equal(t, Duration(90*time.Minute), parsed)
+	})
+
+	t.Run("String", func(t *testing.T) {
+		assert.Equal(t, "30d", (30 * Day).String())
+		assert.Equal(t, "2w", (14 * Day).String())
+		assert.Equal(t, "1y", Year.String())
+		assert.Equal(t, "36h", Duration(36*time.Hour).String())
+		assert.Equal(t, "1h30m0s", Duration(90*time.Minute).String(), "no branch age unit fits")
+	})
+
+	t.Run("YAMLRoundTripAndLegacyNanoseconds", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		// Older versions wrote time.Duration as nanoseconds
+		legacy := fmt.Sprintf("maxAge: %d\ngoneMaxAge: 36h\n", int64(90*24*time.Hour))
+		service := newServiceFor(t, tempDir)
+		require.NoError(t, service.Replace([]byte(legacy)))
+		assert.Equal(t, 90*Day, service.Config().MaxAge)
+		assert.Equal(t, Duration(36*time.Hour), service.Config().GoneMaxAge)
+
+		service.Config().MaxAge = 6 * Month
+		require.NoError(t, service.Save())
+		data, err := os.ReadFile(service.ConfigPath())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "maxAge: 180d")
+		assert.Contains(t, string(data), "goneMaxAge: 36h")
+
+		require.NoError(t, service.Replace([]byte("maxAge: 30\n")))
+		assert.Equal(t, 30*Day, service.Config().MaxAge, "a bare number means days, as on the command line")
+	})
+}
+
+func TestConfigOverrides(t *testing.T) {
+	t.Run("EnvVarNames", func(t *testing.T) {
+		for name, env := range map[string]string{
+			"maxAge":             "CLEAN_GIT_MAX_AGE",
+			"baseBranches":       "CLEAN_GIT_BASE_BRANCHES",
+			"detectSquashMerges": "CLEAN_GIT_DETECT_SQUASH_MERGES",
+			"archive":            "CLEAN_GIT_ARCHIVE",
+		} {
+			key, err := LookupKey(name)
+			require.NoError(t, err)
+			assert.Equal(t, env, key.EnvVar())
+		}
+	})
+
+	t.Run("EnvAndFlagsOverrideFiles", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+		t.Setenv("CLEAN_GIT_MAX_AGE", "3d")
+		t.Setenv("CLEAN_GIT_DETECT_SQUASH_MERGES", "false")
+		t.Setenv("CLEAN_GIT_REMOTE_NAME", "")
+
+		service := newServiceFor(t, tempDir)
+		require.NoError(t, service.Replace([]byte("maxAge: 10d\nremoteName: fork\n")))
+
+		cfg := service.Config()
+		assert.Equal(t, 3*Day, cfg.MaxAge)
+		assert.False(t, cfg.DetectSquashMerges)
+		assert.Equal(t, "fork", cfg.RemoteName)
+		assert.Equal(t, Origin{Layer: LayerEnv, Path: "CLEAN_GIT_MAX_AGE"}, service.Origin("maxAge"))
+
+		require.NoError(t, service.Override(map[string]string{"remoteName": "upstream", "maxAge": "1d"}))
+		assert.Equal(t, "upstream", service.Config().RemoteName)
+		assert.Equal(t, Day, service.Config().MaxAge)
+		assert.Equal(t, "command line", service.Origin("remoteName").String())
+
+		assert.Error(t, service.Override(map[string]string{"remoteName": "a b"}))
+	})
+
+	t.Run("OverridesAreNeverSaved", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+		t.Setenv("CLEAN_GIT_MAX_AGE", "3d")
+		t.Setenv("CLEAN_GIT_DETECT_SQUASH_MERGES", "false")
+
+		service := newServiceFor(t, tempDir)
+		require.NoError(t, service.Override(map[string]string{"remoteName": "upstream"}))
+		service.Config().BaseBranches = []string{"trunk"}
+		require.NoError(t, service.Save())
+
+		data, err := os.ReadFile(service.ConfigPath())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "trunk")
+		assert.NotContains(t, string(data), "maxAge")
+		assert.NotContains(t, string(data), "upstream")
+		assert.NotContains(t, string(data), "detectSquashMerges")
+
+		// An overridden key the caller changes on purpose is saved
+		service.Config().MaxAge = 10 * Day
+		require.NoError(t, service.Save())
+		data, err = os.ReadFile(service.ConfigPath())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "maxAge: 10d")
+	})
+
+	t.Run("InvalidEnvValue", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+		t.Setenv("CLEAN_GIT_MAX_AGE", "soon")
+
+		_, err := NewService(tempDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CLEAN_GIT_MAX_AGE")
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	t.Run("SelectedByFlagOrDefault", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, SharedConfigFile), []byte(
+			"profiles:\n  safe:\n    maxAge: 90d\n  sweep:\n    maxAge: 1d\n    archive: true\n"), 0644))
+		service := newServiceFor(t, tempDir)
+		assert.Equal(t, 720*Day, service.Config().MaxAge)
+
+		require.NoError(t, service.Replace([]byte("defaultProfile: safe\n")))
+		assert.Equal(t, 90*Day, service.Config().MaxAge)
+		assert.Equal(t, "profile:safe", service.Origin("maxAge").String())
+
+		require.NoError(t, service.UseProfile("sweep"))
+		assert.Equal(t, Day, service.Config().MaxAge)
+		assert.True(t, service.Config().Archive)
+
+		err := service.UseProfile("nope")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown profile 'nope'")
+		assert.Equal(t, Day, service.Config().MaxAge)
+	})
+
+	t.Run("ListShowsChanges", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, SharedConfigFile), []byte(
+			"profiles:\n  sweep:\n    maxAge: 1d\n"), 0644))
+		service := newServiceFor(t, tempDir)
+		// A personal profile of the same name replaces the shared one
+		require.NoError(t, service.SetProfile("sweep", map[string]string{"archive": "true"}))
+		require.NoError(t, service.SetProfile("safe", map[string]string{"maxAge": "1y"}))
+
+		profiles := service.Profiles()
+		require.Len(t, profiles, 2)
+		assert.Equal(t, "safe", profiles[0].Name)
+		assert.Equal(t, []ProfileChange{{Key: "maxAge", From: "720d", To: "1y"}}, profiles[0].Changes)
+		assert.Equal(t, "sweep", profiles[1].Name)
+		assert.Equal(t, service.ConfigPath(), profiles[1].Path)
+		assert.Equal(t, []ProfileChange{{Key: "archive", From: "false", To: "true"}}, profiles[1].Changes)
+
+		assert.Error(t, service.SetProfile("bad", map[string]string{"maxAge": "soon"}))
+		assert.Error(t, service.SetProfile("bad", map[string]string{"defaultProfile": "safe"}))
+	})
+
+	t.Run("ProfileValuesAreNeverSaved", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		service := newServiceFor(t, tempDir)
+		require.NoError(t, service.SetProfile("sweep", map[string]string{"maxAge": "1d"}))
+		require.NoError(t, service.UseProfile("sweep"))
+		service.Config().RemoteName = "fork"
+		require.NoError(t, service.Save())
+
+		data, err := os.ReadFile(service.ConfigPath())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "remoteName: fork")
+		assert.Equal(t, 1, strings.Count(string(data), "maxAge"))
+		assert.Contains(t, string(data), "sweep:")
+	})
+
+	t.Run("StrictProfileDecoding", func(t *testing.T) {
+		tempDir := t.TempDir()
+		_, restore := setupHome(t, tempDir)
+		defer restore()
+
+		service := newServiceFor(t, tempDir)
+		err := service.Replace([]byte("profiles:\n  sweep:\n    maxAeg: 1d\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "profiles.sweep.maxAeg")
+
+		err = service.Replace([]byte("profiles:\n  sweep:\n    defaultProfile: safe\n"))
+		require.Error(t, err)
+
+		err = service.Replace([]byte("defaultProfile: missing\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown profile 'missing'")
+	})
+}
+
 func TestFindGitRepoRoot(t *testing.T) {
 	t.Run("FindFromNestedDirectory", func(t *testing.T) {
 		tempDir := t.TempDir()
@@ -209,7 +903,7 @@ func TestConfigService_Update(t *testing.T) {
 
 	customConfig := &Config{
 		BaseBranches:   []string{"main", "staging"},
-		MaxAge:         48 * time.Hour,
+		MaxAge:         Duration(48 * time.Hour),
 		ProtectedRegex: []string{"prod/*"},
 		RemoteName:     "upstream",
 	}
@@ -219,7 +913,7 @@ func TestConfigService_Update(t *testing.T) {
 
 	cfg := service.Config()
 	assert.Equal(t, []string{"main", "staging"}, cfg.BaseBranches)
-	assert.Equal(t, 48*time.Hour, cfg.MaxAge)
+	assert.Equal(t, Duration(48*time.Hour), cfg.MaxAge)
 	assert.Equal(t, []string{"prod/*"}, cfg.ProtectedRegex)
 	assert.Equal(t, "upstream", cfg.RemoteName)
 
@@ -233,23 +927,15 @@ func TestConfigService_Update(t *testing.T) {
 func TestConfigService_SaveErrors(t *testing.T) {
 	t.Run("ReadOnlyConfigFile", func(t *testing.T) {
 		tempDir := t.TempDir()
-		homeDir, restore := setupHome(t, tempDir)
-		configDir := filepath.Join(homeDir, ".clean-git")
-		err := os.MkdirAll(configDir, 0755) // 0755: read + write + execute
-		require.NoError(t, err)
-
-		configPath := filepath.Join(configDir, "config.yaml")
-		validYAML := `baseBranches: [main]
-maxAge: 24h`
-		err = os.WriteFile(configPath, []byte(validYAML), 0644)
-		require.NoError(t, err)
-
-		err = os.Chmod(configPath, 0444)
-		require.NoError(t, err)
-
+		_, restore := setupHome(t, tempDir)
 		defer restore()
 		service := newServiceFor(t, tempDir)
 
+		require.NoError(t, service.Save())
+		err := os.Chmod(service.ConfigPath(), 0444)
+		require.NoError(t, err)
+
+		service.Config().RemoteName = "upstream"
 		err = service.Save()
 		assert.Error(t, err)
 	})
@@ -312,10 +998,11 @@ maxAge: 168h`
 
 		cfg := service.Config()
 		assert.Equal(t, []string{"main"}, cfg.BaseBranches)
-		assert.Equal(t, 168*time.Hour, cfg.MaxAge)
-		assert.Empty(t, cfg.ProtectedRegex)
-		assert.Empty(t, cfg.IncludeRegex)
-		assert.Empty(t, cfg.RemoteName)
+		assert.Equal(t, Duration(168*time.Hour), cfg.MaxAge)
+		// Keys the file does not set fall through to the defaults
+		assert.Equal(t, DefaultConfig().ProtectedRegex, cfg.ProtectedRegex)
+		assert.Equal(t, []string{".*"}, cfg.IncludeRegex)
+		assert.Equal(t, "origin", cfg.RemoteName)
 	})
 }
 
@@ -328,7 +1015,7 @@ func TestUtilityFunctions(t *testing.T) {
 		configPath, err := getGlobalConfigPath()
 		require.NoError(t, err)
 
-		expectedPath := filepath.Join(homeDir, ".clean-git", "config.yaml")
+		expectedPath := filepath.Join(homeDir, ".clean-git", "configs", "global.yaml")
 		assert.Equal(t, expectedPath, configPath)
 	})
 
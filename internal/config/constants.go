@@ -4,4 +4,36 @@ const (
 	ConfigDir = ".clean-git/configs"
 
 	GlobalConfigFile = "global.yaml"
+
+	// RepoConfigDir holds one file per repository, relative to ConfigDir
+	RepoConfigDir = "repos"
+
+	// SharedConfigFile is the team configuration committed at the repository root
+	SharedConfigFile = ".clean-git.yaml"
+
+	// LegacyConfigFile is the single shared file used before per-repository configs
+	LegacyConfigFile = ".clean-git/config.yaml"
+)
+
+// Configuration layers, lowest precedence first
+const (
+	LayerDefaults = "defaults"
+	LayerShared   = "shared"
+	LayerGlobal   = "global"
+	LayerRepo     = "repo"
+
+	// LayerProfile is the selected profile, applied on top of the files
+	LayerProfile = "profile"
+
+	// Per-invocation overrides, applied on top of every file and never saved
+	LayerEnv   = "env"
+	LayerFlags = "flags"
+)
+
+// Scope selects which file `clean-git config` writes to
+type Scope string
+
+const (
+	ScopePersonal Scope = "personal"
+	ScopeShared   Scope = "shared"
 )
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration written the way people think about branch
+// ages: "90d", "2w", "6mo", "1y" or "36h". It marshals to that form and still
+// reads the raw nanosecond integers older config files contain.
+type Duration time.Duration
+
+const (
+	Day   = Duration(24 * time.Hour)
+	Week  = 7 * Day
+	Month = 30 * Day
+	Year  = 365 * Day
+)
+
+var durationUnits = map[string]Duration{
+	"y":  Year,
+	"mo": Month,
+	"w":  Week,
+	"d":  Day,
+	"h":  Duration(time.Hour),
+}
+
+var durationPart = regexp.MustCompile(`(\d+)(mo|y|w|d|h)`)
+
+// minutesPart catches "6m", which is far more likely a typo for six months
+// than a branch age of six minutes.
+var minutesPart = regexp.MustCompile(`(\d+)m($|[^o])`)
+
+// ParseDuration parses durations such as "90d", "2w", "6mo", "1y", "36h" or
+// combinations like "1y6mo". A bare number is a number of days, which is what
+// the prompts used to ask for. Minutes and seconds are not branch ages and are
+// only read back from the "720h0m0s" spelling older versions wrote.
+func ParseDuration(input string) (Duration, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, fmt.Errorf("duration is empty")
+	}
+	if days, err := strconv.Atoi(input); err == nil {
+		if days < 0 {
+			return 0, fmt.Errorf("days must be positive, got %d", days)
+		}
+		if Duration(days) > math.MaxInt64/Day {
+			return 0, fmt.Errorf("invalid duration '%s': too long", input)
+		}
+		return Duration(days) * Day, nil
+	}
+
+	matches := durationPart.FindAllStringSubmatchIndex(input, -1)
+	var total Duration
+	end := 0
+	for _, match := range matches {
+		if match[0] != end {
+			break
+		}
+		count, err := strconv.Atoi(input[match[2]:match[3]])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", input, err)
+		}
+		unit := durationUnits[input[match[4]:match[5]]]
+		if Duration(count) > (math.MaxInt64-total)/unit {
+			return 0, fmt.Errorf("invalid duration '%s': too long", input)
+		}
+		total += Duration(count) * unit
+		end = match[1]
+	}
+	if end == len(input) {
+		return total, nil
+	}
+
+	// Spellings written by older versions, e.g. "24h0m0s", always end in seconds
+	if strings.HasSuffix(input, "s") {
+		if parsed, err := time.ParseDuration(input); err == nil && parsed >= 0 {
+			return Duration(parsed), nil
+		}
+	}
+	if match := minutesPart.FindStringSubmatch(input); match != nil {
+		return 0, fmt.Errorf("invalid duration '%s': did you mean %smo? minutes are not supported", input, match[1])
+	}
+	return 0, fmt.Errorf("invalid duration '%s': expected e.g. 90d, 2w, 6mo, 1y or 36h", input)
+}
+
+// String uses the largest unit that divides d exactly, so ParseDuration gives
+// back the same value. Months are never chosen since 30 days reads better.
+func (d Duration) String() string {
+	if d == 0 {
+		return "0d"
+	}
+	if d < 0 {
+		return time.Duration(d).String()
+	}
+	for _, unit := range []string{"y", "w", "d", "h"} {
+		if size := durationUnits[unit]; d%size == 0 {
+			return fmt.Sprintf("%d%s", d/size, unit)
+		}
+	}
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if node.Tag == "!!int" {
+		// Older versions stored a raw time.Duration
+		nanoseconds, err := strconv.ParseInt(node.Value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s': %w", node.Value, err)
+		}
+		// A hand-written "30" means days, as it does on the command line; no
+		// legacy nanosecond value is that small
+		if nanoseconds >= int64(time.Second) {
+			*d = Duration(nanoseconds)
+			return nil
+		}
+	}
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("cannot unmarshal %s into a duration", node.Tag)
+	}
+	parsed, err := ParseDuration(node.Value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key is one setting addressable by `clean-git config get/set/unset`, named by
+// its YAML key. Values are read and written in the same text form the
+// interactive prompts accept.
+type Key struct {
+	Name        string
+	Description string
+	get         func(cfg *Config) string
+	set         func(cfg *Config, value string) error
+}
+
+// remoteNamePattern also restricts profile names
+var remoteNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+var keys = []Key{
+	{
+		Name:        "baseBranches",
+		Description: "branches to keep and compare against (comma-separated)",
+		get:         func(cfg *Config) string { return strings.Join(cfg.BaseBranches, ",") },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseList(value, false)
+			if err != nil {
+				return err
+			}
+			cfg.BaseBranches = parsed
+			return nil
+		},
+	},
+	{
+		Name:        "maxAge",
+		Description: "age after which a branch is stale (e.g. 30d, 2w, 6mo, 1y)",
+		get:         func(cfg *Config) string { return cfg.MaxAge.String() },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			cfg.MaxAge = parsed
+			return nil
+		},
+	},
+	{
+		Name:        "protectedRegex",
+		Description: "patterns of branches never to clean (comma-separated regexes)",
+		get:         func(cfg *Config) string { return strings.Join(cfg.ProtectedRegex, ",") },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseList(value, true)
+			if err != nil {
+				return err
+			}
+			cfg.ProtectedRegex = parsed
+			return nil
+		},
+	},
+	{
+		Name:        "includeRegex",
+		Description: "patterns of branches to consider (comma-separated regexes)",
+		get:         func(cfg *Config) string { return strings.Join(cfg.IncludeRegex, ",") },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseList(value, true)
+			if err != nil {
+				return err
+			}
+			cfg.IncludeRegex = parsed
+			return nil
+		},
+	},
+	{
+		Name:        "remoteName",
+		Description: "remote whose branches are cleaned",
+		get:         func(cfg *Config) string { return cfg.RemoteName },
+		set: func(cfg *Config, value string) error {
+			if err := ValidateRemoteName(value); err != nil {
+				return err
+			}
+			cfg.RemoteName = value
+			return nil
+		},
+	},
+	{
+		Name:        "remotes",
+		Description: "remotes and their roles, replacing remoteName (e.g. origin:push,upstream:merge-source)",
+		get:         func(cfg *Config) string { return FormatRemotes(cfg.Remotes) },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseRemotes(value)
+			if err != nil {
+				return err
+			}
+			cfg.Remotes = parsed
+			return nil
+		},
+	},
+	boolKey("archive", "archive branches instead of deleting them",
+		func(cfg *Config) *bool { return &cfg.Archive }),
+	boolKey("archiveRemote", "also keep archive refs on the remote",
+		func(cfg *Config) *bool { return &cfg.ArchiveRemote }),
+	boolKey("detectSquashMerges", "treat squash-merged branches as merged",
+		func(cfg *Config) *bool { return &cfg.DetectSquashMerges }),
+	boolKey("detectRebaseMerges", "treat rebase-merged branches as merged",
+		func(cfg *Config) *bool { return &cfg.DetectRebaseMerges }),
+	boolKey("cleanGone", "clean branches whose upstream was deleted",
+		func(cfg *Config) *bool { return &cfg.CleanGone }),
+	{
+		Name:        "goneMaxAge",
+		Description: "age after which a gone branch is cleaned (e.g. 7d)",
+		get:         func(cfg *Config) string { return cfg.GoneMaxAge.String() },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			cfg.GoneMaxAge = parsed
+			return nil
+		},
+	},
+	boolKey("cleanAllRemote", "clean remote branches without a local copy, unmerged ones only with --force",
+		func(cfg *Config) *bool { return &cfg.CleanAllRemote }),
+	{
+		Name:        "allRemoteMaxAge",
+		Description: "age after which a remote branch without a local copy is cleaned (e.g. 90d)",
+		get:         func(cfg *Config) string { return cfg.AllRemoteMaxAge.String() },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			cfg.AllRemoteMaxAge = parsed
+			return nil
+		},
+	},
+	boolKey("fetch", "fetch and prune the remotes before evaluating branches",
+		func(cfg *Config) *bool { return &cfg.Fetch }),
+	{
+		Name:        "fetchWarnAge",
+		Description: "warn when the last fetch is older than this (e.g. 7d, 0 to never warn)",
+		get:         func(cfg *Config) string { return cfg.FetchWarnAge.String() },
+		set: func(cfg *Config, value string) error {
+			parsed, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			cfg.FetchWarnAge = parsed
+			return nil
+		},
+	},
+	{
+		Name:        "jobs",
+		Description: "git commands to run at the same time",
+		get:         func(cfg *Config) string { return strconv.Itoa(cfg.Jobs) },
+		set: func(cfg *Config, value string) error {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid number of jobs '%s': expected a whole number of at least 1", value)
+			}
+			cfg.Jobs = parsed
+			return nil
+		},
+	},
+	{
+		Name:        "gitTimeout",
+		Description: "time limit for each git command, 0 for none (e.g. 5m)",
+		get:         func(cfg *Config) string { return cfg.GitTimeout.String() },
+		set: func(cfg *Config, value string) error {
+			if value == "0" {
+				cfg.GitTimeout = 0
+				return nil
+			}
+			parsed, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			cfg.GitTimeout = parsed
+			return nil
+		},
+	},
+	{
+		Name:        defaultProfileKey,
+		Description: "profile applied when --profile is not given",
+		get:         func(cfg *Config) string { return cfg.DefaultProfile },
+		set: func(cfg *Config, value string) error {
+			if err := ValidateProfileName(value); err != nil {
+				return err
+			}
+			cfg.DefaultProfile = value
+			return nil
+		},
+	},
+}
+
+const defaultProfileKey = "defaultProfile"
+
+func boolKey(name, description string, field func(cfg *Config) *bool) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		get:         func(cfg *Config) string { return strconv.FormatBool(*field(cfg)) },
+		set: func(cfg *Config, value string) error {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean '%s': expected true or false", value)
+			}
+			*field(cfg) = parsed
+			return nil
+		},
+	}
+}
+
+// Keys returns every configurable key in the order the config file documents them.
+func Keys() []Key {
+	return append([]Key(nil), keys...)
+}
+
+// LookupKey finds a key by its YAML name.
+func LookupKey(name string) (Key, error) {
+	for _, key := range keys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, key.Name)
+	}
+	sort.Strings(names)
+	return Key{}, fmt.Errorf("unknown config key '%s' (known keys: %s)", name, strings.Join(names, ", "))
+}
+
+// Get formats the key's value in cfg.
+func (k Key) Get(cfg *Config) string {
+	return k.get(cfg)
+}
+
+// Set parses value and stores it in cfg, leaving cfg untouched on error.
+func (k Key) Set(cfg *Config, value string) error {
+	if err := k.set(cfg, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", k.Name, err)
+	}
+	return nil
+}
+
+// ParseList splits a comma-separated list, dropping empty entries. With
+// validateRegex every entry must compile as a regular expression.
+func ParseList(input string, validateRegex bool) ([]string, error) {
+	parts := strings.Split(input, ",")
+	result := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+
+		if validateRegex {
+			if _, err := regexp.Compile(trimmed); err != nil {
+				return nil, fmt.Errorf("invalid regex pattern '%s': %w", trimmed, err)
+			}
+		}
+
+		result = append(result, trimmed)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("at least one value is required")
+	}
+
+	return result, nil
+}
+
+// ValidateRemoteName allows letters, numbers, hyphens and underscores. Git
+// accepts "/" in remote names, but archive refs keep the remote as a single
+// path segment, so such remotes are refused with their own message.
+func ValidateRemoteName(name string) error {
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("invalid remote name '%s': remote names containing '/' are not supported", name)
+	}
+	if !remoteNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid remote name '%s': must contain only letters, numbers, hyphens, and underscores", name)
+	}
+	return nil
+}
+
+// ValidateProfileName applies the remote name rules to profile names.
+func ValidateProfileName(name string) error {
+	if !remoteNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': must contain only letters, numbers, hyphens, and underscores", name)
+	}
+	return nil
+}
+
+// Validate applies the checks the prompts and `config set` enforce to a
+// configuration that was written by hand.
+func (c *Config) Validate() error {
+	for _, pattern := range c.ProtectedRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("protectedRegex: invalid regex pattern '%s': %w", pattern, err)
+		}
+	}
+	for _, pattern := range c.IncludeRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("includeRegex: invalid regex pattern '%s': %w", pattern, err)
+		}
+	}
+	if c.RemoteName != "" {
+		if err := ValidateRemoteName(c.RemoteName); err != nil {
+			return fmt.Errorf("remoteName: %w", err)
+		}
+	}
+	if err := ValidateRemotes(c.Remotes); err != nil {
+		return fmt.Errorf("remotes: %w", err)
+	}
+	if c.MaxAge <= 0 {
+		return fmt.Errorf("maxAge must be greater than zero; a zero maxAge treats every merged branch as stale")
+	}
+	if c.GoneMaxAge < 0 {
+		return fmt.Errorf("goneMaxAge must not be negative")
+	}
+	if c.AllRemoteMaxAge < 0 {
+		return fmt.Errorf("allRemoteMaxAge must not be negative")
+	}
+	if c.FetchWarnAge < 0 {
+		return fmt.Errorf("fetchWarnAge must not be negative")
+	}
+	if c.Jobs < 0 {
+		return fmt.Errorf("jobs must not be negative")
+	}
+	if c.GitTimeout < 0 {
+		return fmt.Errorf("gitTimeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// layer holds the keys one configuration source sets. Keeping raw key maps
+// (instead of a Config per layer) is what lets an unset key fall through to
+// the layer below while an explicit empty value still overrides it.
+type layer struct {
+	name   string
+	path   string
+	values map[string]interface{}
+	// overridable is only used by the shared layer
+	overridable []string
+	// profiles holds named sets of keys applied on top of the files on request
+	profiles map[string]map[string]interface{}
+	// positions locates each key in the file, for reporting
+	positions map[string]position
+}
+
+const (
+	// overridableKey lists, in the shared file, the keys personal configs may override
+	overridableKey = "overridable"
+	// profilesKey maps profile names to the keys they set
+	profilesKey = "profiles"
+)
+
+func (l layer) without(keys map[string]bool) layer {
+	l.values = withoutKeys(l.values, keys)
+	if l.profiles != nil {
+		profiles := make(map[string]map[string]interface{})
+		for name, values := range l.profiles {
+			profiles[name] = withoutKeys(values, keys)
+		}
+		l.profiles = profiles
+	}
+	return l
+}
+
+func withoutKeys(values map[string]interface{}, keys map[string]bool) map[string]interface{} {
+	kept := make(map[string]interface{})
+	for key, value := range values {
+		if !keys[key] {
+			kept[key] = value
+		}
+	}
+	return kept
+}
+
+func configToMap(cfg *Config) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func mapToConfig(values map[string]interface{}) (*Config, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// normalizeValues round-trips values through Config so that equivalent
+// spellings (e.g. "24h" and "24h0m0s") compare equal.
+func normalizeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	cfg, err := mapToConfig(values)
+	if err != nil {
+		return nil, err
+	}
+	return configToMap(cfg)
+}
+
+// mergeLayers applies layers in order; later layers replace whole keys.
+func mergeLayers(layers []layer) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, l := range layers {
+		for key, value := range l.values {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+func readLayer(name, path string) (layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return layer{name: name, path: path, values: make(map[string]interface{})}, nil
+		}
+		return layer{name: name, path: path}, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return parseLayer(name, path, data)
+}
+
+// parseLayer strictly decodes a config file; see decodeLayer.
+func parseLayer(name, path string, data []byte) (layer, error) {
+	l, issues := decodeLayer(name, path, data)
+	if len(issues) > 0 {
+		return layer{name: name, path: path, values: make(map[string]interface{})},
+			fmt.Errorf("failed to parse config file %s: %w", path, &ValidationError{Issues: issues})
+	}
+	return l, nil
+}
+
+func writeLayer(l layer) error {
+	if err := ensureConfigDirExists(l.path); err != nil {
+		return err
+	}
+	values := l.values
+	if len(l.overridable) > 0 || len(l.profiles) > 0 {
+		values = make(map[string]interface{})
+		for key, value := range l.values {
+			values[key] = value
+		}
+		if len(l.overridable) > 0 {
+			values[overridableKey] = l.overridable
+		}
+		if len(l.profiles) > 0 {
+			values[profilesKey] = l.profiles
+		}
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, data, 0644)
+}
+
+// diffLayer returns the values target must hold so that target merged over
+// below yields cfg. Keys target already sets stay pinned even when they match
+// below; other keys are only written when they differ from what is inherited.
+func diffLayer(cfg *Config, target layer, below map[string]interface{}) (map[string]interface{}, error) {
+	current, err := configToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	inherited, err := normalizeValues(below)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	for key, value := range target.values {
+		values[key] = value
+	}
+
+	keys := make(map[string]bool)
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range inherited {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		// Marshalling drops empty fields, so a missing key means an explicitly empty value
+		value := current[key]
+		_, pinned := values[key]
+		if value == nil && inherited[key] == nil {
+			delete(values, key)
+			continue
+		}
+		if pinned || !reflect.DeepEqual(value, inherited[key]) {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// migrateLegacyConfig moves the pre-layering single config file into the
+// global layer, since it used to apply to every repository.
+func migrateLegacyConfig(legacyPath, globalPath string) error {
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(globalPath); err == nil {
+		return nil
+	}
+	if err := ensureConfigDirExists(globalPath); err != nil {
+		return err
+	}
+	if err := os.Rename(legacyPath, globalPath); err != nil {
+		return fmt.Errorf("failed to migrate %s to %s: %w", legacyPath, globalPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override config keys
+const EnvPrefix = "CLEAN_GIT_"
+
+// EnvVar names the environment variable overriding key, e.g.
+// CLEAN_GIT_MAX_AGE for maxAge.
+func (k Key) EnvVar() string {
+	var name strings.Builder
+	name.WriteString(EnvPrefix)
+	for i, r := range k.Name {
+		if unicode.IsUpper(r) && i > 0 {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+// value returns the key's field in the form the layers hold, keeping zero
+// values such as false that marshalling the whole Config would drop.
+func (k Key) value(cfg *Config) (interface{}, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if tag != k.Name {
+			continue
+		}
+		data, err := yaml.Marshal(map[string]interface{}{tag: v.Field(i).Interface()})
+		if err != nil {
+			return nil, err
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+		return values[tag], nil
+	}
+	return nil, fmt.Errorf("no config field for key '%s'", k.Name)
+}
+
+// overrideLayer parses raw per-invocation values with the same validation as
+// `config set`. source, if given, names where each value came from in errors.
+func overrideLayer(name string, raw map[string]string, source func(key Key) string) (layer, error) {
+	l := layer{name: name, values: make(map[string]interface{})}
+	for keyName, input := range raw {
+		key, err := LookupKey(keyName)
+		if err != nil {
+			return l, err
+		}
+		cfg := DefaultConfig()
+		if err := key.Set(cfg, input); err != nil {
+			if source != nil {
+				return l, fmt.Errorf("%s: %w", source(key), err)
+			}
+			return l, err
+		}
+		value, err := key.value(cfg)
+		if err != nil {
+			return l, err
+		}
+		l.values[keyName] = value
+	}
+	return l, nil
+}
+
+// envOverrides reads CLEAN_GIT_* variables. Empty variables are ignored so
+// they can be cleared with VAR= in scripts.
+func envOverrides() (layer, error) {
+	raw := make(map[string]string)
+	for _, key := range keys {
+		if value := os.Getenv(key.EnvVar()); value != "" {
+			raw[key.Name] = value
+		}
+	}
+	return overrideLayer(LayerEnv, raw, Key.EnvVar)
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Layer == LayerEnv:
+		return "env:" + o.Path
+	case o.Layer == LayerFlags:
+		return "command line"
+	case o.Layer == LayerProfile:
+		return "profile:" + o.Path
+	case o.Path != "":
+		return "file:" + o.Path
+	}
+	return o.Layer
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 func FindGitRepoRoot() (string, error) {
@@ -35,3 +40,33 @@ func GetDefaultConfigPath() (string, error) {
 	}
 	return filepath.Join(homeDir, ConfigDir, GlobalConfigFile), nil
 }
+
+// RepoConfigKey names a repository's config file: the directory name for
+// readability plus a hash of the absolute path so same-named clones differ.
+func RepoConfigKey(repoRoot string) (string, error) {
+	absRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(absRoot))
+
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, filepath.Base(absRoot))
+	return name + "-" + hex.EncodeToString(sum[:6]), nil
+}
+
+// FindGitCommonDir resolves the git directory shared by all worktrees of a
+// repository. A linked worktree's .git file points at a directory of its own,
+// so state that belongs to the repository, like the deletion journal, must
+// not be kept there.
+func FindGitCommonDir(repoRoot string) (string, error) {
+	output, err := exec.Command("git", "-C", repoRoot, "rev-parse", "--path-format=absolute", "--git-common-dir").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse --git-common-dir failed in %s: %w", repoRoot, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Profile is a named set of keys applied on top of the config files, e.g. a
+// conservative "safe" cleanup and an aggressive "sweep" of the remote.
+type Profile struct {
+	Name string
+	// Path is the file that defines the profile
+	Path    string
+	Default bool
+	Active  bool
+	Changes []ProfileChange
+}
+
+// ProfileChange is one key a profile sets, formatted like `config get`.
+type ProfileChange struct {
+	Key  string
+	From string
+	To   string
+}
+
+// findProfile looks a profile up in the layers visible from the current scope.
+// A profile defined in a later layer replaces the whole profile of that name.
+func (s *repoConfigService) findProfile(name string) (layer, bool) {
+	layers := s.effectiveLayers()[:s.targetIndex()+1]
+	for i := len(layers) - 1; i >= 0; i-- {
+		if values, ok := layers[i].profiles[name]; ok {
+			return layer{name: LayerProfile, path: layers[i].path, values: values}, true
+		}
+	}
+	return layer{}, false
+}
+
+// selectProfile returns the name and layer of the profile chosen with
+// UseProfile, or else the one defaultProfile names. files and overrides are
+// the layers below and above it.
+func (s *repoConfigService) selectProfile(files, overrides []layer) (string, layer, error) {
+	name := s.profile
+	if name == "" {
+		if value, ok := mergeLayers(append(append([]layer(nil), files...), overrides...))[defaultProfileKey].(string); ok {
+			name = value
+		}
+	}
+	if name == "" {
+		return "", layer{name: LayerProfile, values: make(map[string]interface{})}, nil
+	}
+
+	profile, ok := s.findProfile(name)
+	if !ok {
+		return "", layer{}, fmt.Errorf("unknown profile '%s'", name)
+	}
+	return name, profile, nil
+}
+
+// UseProfile applies the named profile instead of the default one.
+func (s *repoConfigService) UseProfile(name string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	previous := s.profile
+	s.profile = name
+	if err := s.resolve(); err != nil {
+		s.profile = previous
+		return err
+	}
+	return nil
+}
+
+// Profiles lists every profile visible from the current scope with the keys it
+// changes relative to the config files.
+func (s *repoConfigService) Profiles() []Profile {
+	layers := s.effectiveLayers()[:s.targetIndex()+1]
+	base, err := mapToConfig(mergeLayers(layers))
+	if err != nil {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, l := range layers {
+		for name := range l.profiles {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var profiles []Profile
+	for _, name := range sorted {
+		profileLayer, _ := s.findProfile(name)
+		profiled, err := mapToConfig(mergeLayers(append(append([]layer(nil), layers...), profileLayer)))
+		if err != nil {
+			continue
+		}
+
+		profile := Profile{
+			Name:    name,
+			Path:    profileLayer.path,
+			Default: base.DefaultProfile == name,
+			Active:  s.activeProfileName == name,
+		}
+		for _, key := range keys {
+			if _, ok := profileLayer.values[key.Name]; ok {
+				profile.Changes = append(profile.Changes, ProfileChange{Key: key.Name, From: key.Get(base), To: key.Get(profiled)})
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// SetProfile validates values like `config set` and stores them as the named
+// profile in the current scope's file, replacing any profile of that name.
+func (s *repoConfigService) SetProfile(name string, values map[string]string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if _, ok := values[defaultProfileKey]; ok {
+		return fmt.Errorf("%s cannot be set inside a profile", defaultProfileKey)
+	}
+	profile, err := overrideLayer(LayerProfile, values, nil)
+	if err != nil {
+		return err
+	}
+
+	index := s.targetIndex()
+	target := s.layers[index]
+	if s.scope == ScopePersonal {
+		for _, key := range s.LockedKeys() {
+			if _, ok := profile.values[key]; ok {
+				return fmt.Errorf("%s is set by %s and is not overridable", key, SharedConfigFile)
+			}
+		}
+	}
+
+	profiles := make(map[string]map[string]interface{})
+	for existing, settings := range target.profiles {
+		profiles[existing] = settings
+	}
+	profiles[name] = profile.values
+	target.profiles = profiles
+
+	if err := writeLayer(target); err != nil {
+		return err
+	}
+	s.layers[index] = target
+	return s.resolve()
+}
+
+// savedValues returns Config() with every key still holding the value the
+// profile or an override gave it put back to what the files say, so Save
+// never persists them. Keys the caller changed since resolving are kept.
+func (s *repoConfigService) savedValues() (*Config, error) {
+	current, err := configToMap(s.config)
+	if err != nil {
+		return nil, err
+	}
+	files, err := normalizeValues(mergeLayers(s.effectiveLayers()[:s.targetIndex()+1]))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range append([]layer{s.activeProfile}, s.overrides...) {
+		for key := range l.values {
+			if !reflect.DeepEqual(current[key], s.resolved[key]) {
+				continue
+			}
+			if value, ok := files[key]; ok {
+				current[key] = value
+			} else {
+				delete(current, key)
+			}
+		}
+	}
+	return mapToConfig(current)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteRole is what clean-git may do with a remote.
+type RemoteRole string
+
+const (
+	// RolePush marks a remote we own: its branches are listed and may be deleted
+	RolePush RemoteRole = "push"
+	// RoleMergeSource marks a remote whose base branches count for merge status
+	RoleMergeSource RemoteRole = "merge-source"
+)
+
+var remoteRoles = []RemoteRole{RolePush, RoleMergeSource}
+
+// Remote is one entry of the remotes key, e.g. a fork (push) and the
+// canonical repository it was forked from (merge-source).
+type Remote struct {
+	Name  string       `yaml:"name"`
+	Roles []RemoteRole `yaml:"roles"`
+}
+
+// Has reports whether the remote has role.
+func (r Remote) Has(role RemoteRole) bool {
+	for _, candidate := range r.Roles {
+		if candidate == role {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRemotes returns the remotes clean-git works with: the remotes key
+// when set, otherwise remoteName in both roles.
+func (c *Config) EffectiveRemotes() []Remote {
+	if len(c.Remotes) > 0 {
+		return c.Remotes
+	}
+	name := c.RemoteName
+	if name == "" {
+		name = "origin"
+	}
+	return []Remote{{Name: name, Roles: []RemoteRole{RolePush, RoleMergeSource}}}
+}
+
+// ParseRemotes reads the `config set` form of the remotes key: a
+// comma-separated list of name:role entries, with several roles joined by
+// "+" (origin:push+merge-source). A name without roles has both.
+func ParseRemotes(input string) ([]Remote, error) {
+	entries, err := ParseList(input, false)
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := make([]Remote, 0, len(entries))
+	for _, entry := range entries {
+		name, roles, hasRoles := strings.Cut(entry, ":")
+		remote := Remote{Name: strings.TrimSpace(name)}
+		if !hasRoles {
+			remote.Roles = append(remote.Roles, remoteRoles...)
+		}
+		for _, role := range strings.Split(roles, "+") {
+			if role = strings.TrimSpace(role); role != "" {
+				remote.Roles = append(remote.Roles, RemoteRole(role))
+			}
+		}
+		remotes = append(remotes, remote)
+	}
+	if err := ValidateRemotes(remotes); err != nil {
+		return nil, err
+	}
+	return remotes, nil
+}
+
+// FormatRemotes is the inverse of ParseRemotes.
+func FormatRemotes(remotes []Remote) string {
+	entries := make([]string, len(remotes))
+	for i, remote := range remotes {
+		roles := make([]string, len(remote.Roles))
+		for j, role := range remote.Roles {
+			roles[j] = string(role)
+		}
+		entries[i] = remote.Name + ":" + strings.Join(roles, "+")
+	}
+	return strings.Join(entries, ",")
+}
+
+// ValidateRemotes checks names and roles and that no remote is listed twice.
+func ValidateRemotes(remotes []Remote) error {
+	seen := make(map[string]bool)
+	for _, remote := range remotes {
+		if err := ValidateRemoteName(remote.Name); err != nil {
+			return err
+		}
+		if seen[remote.Name] {
+			return fmt.Errorf("remote '%s' is listed twice", remote.Name)
+		}
+		seen[remote.Name] = true
+
+		if len(remote.Roles) == 0 {
+			return fmt.Errorf("remote '%s' has no roles (expected %s)", remote.Name, roleNames())
+		}
+		for _, role := range remote.Roles {
+			if !validRole(role) {
+				return fmt.Errorf("unknown role '%s' for remote '%s' (expected %s)", role, remote.Name, roleNames())
+			}
+		}
+	}
+	return nil
+}
+
+func validRole(role RemoteRole) bool {
+	for _, known := range remoteRoles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+func roleNames() string {
+	names := make([]string, len(remoteRoles))
+	for i, role := range remoteRoles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, " or ")
+}
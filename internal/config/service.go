@@ -1,18 +1,34 @@
 package config
 
 import (
-	"errors"
 	"fmt"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"reflect"
+	"sort"
 )
 
-
+// repoConfigService resolves the configuration of one repository from the
+// built-in defaults, the team's shared file, the global file and the
+// repository's own file, in that order. Keys set by the shared file are locked
+// against the personal layers unless the shared file lists them as overridable.
+// The selected profile, CLEAN_GIT_* variables and command-line flags are
+// applied on top of all of them but never saved.
 type repoConfigService struct {
 	repoRoot   string
 	configPath string
-	config     *Config
+	globalPath string
+	sharedPath string
+	layers     []layer
+	overrides  []layer
+	// profile is the name passed to UseProfile; activeProfile is the one applied
+	profile           string
+	activeProfile     layer
+	activeProfileName string
+	scope             Scope
+	config            *Config
+	// resolved is config as last resolved, to tell overridden values apart from edits
+	resolved   map[string]interface{}
 	onboarding bool
 }
 
@@ -23,96 +39,388 @@ func (s *repoConfigService) Config() *Config {
 	return s.config
 }
 
+// Save writes the keys that differ from what the current scope inherits to
+// that scope's file.
 func (s *repoConfigService) Save() error {
 	if s.config == nil {
 		s.config = DefaultConfig()
 	}
 
-	if err := ensureConfigDirExists(s.configPath); err != nil {
+	if err := s.config.Validate(); err != nil {
+		return err
+	}
+	cfg, err := s.savedValues()
+	if err != nil {
 		return err
 	}
 
-	data, err := yaml.Marshal(s.config)
+	index := s.targetIndex()
+	target := s.layers[index]
+	below := mergeLayers(s.effectiveLayers()[:index])
+	if s.scope == ScopePersonal {
+		if err := s.checkLockedKeys(cfg, below); err != nil {
+			return err
+		}
+	}
+
+	values, err := diffLayer(cfg, target, below)
 	if err != nil {
 		return err
 	}
+	target.values = values
 
-	return os.WriteFile(s.configPath, data, 0644)
+	if err := writeLayer(target); err != nil {
+		return err
+	}
+	s.layers[index] = target
+	return nil
 }
 
 func (s *repoConfigService) IsOnboarded() bool {
-	_, err := os.Stat(s.configPath)
-	return err == nil
+	// A team config, or a global one such as the migrated pre-layering file,
+	// is enough to get started without the personal prompts
+	for _, path := range []string{s.configPath, s.sharedPath, s.globalPath} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func NewService(repoRoot string) (Service, error) {
-	configPath, err := getGlobalConfigPath()
+	return newRepoConfigService(repoRoot, false)
+}
+
+// NewOnboardingService ignores any existing repository layer so onboarding
+// starts from the defaults, the shared file and the global file.
+func NewOnboardingService(repoRoot string) (Service, error) {
+	return newRepoConfigService(repoRoot, true)
+}
+
+func newRepoConfigService(repoRoot string, onboarding bool) (Service, error) {
+	globalPath, err := getGlobalConfigPath()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get global config path: %w", err)
 	}
-
-	service := &repoConfigService{
-		repoRoot:   repoRoot,
-		configPath: configPath,
+	configPath, err := getRepoConfigPath(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository config path: %w", err)
+	}
+	legacyPath, err := getLegacyConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legacy config path: %w", err)
 	}
 
 	if err := ensureConfigDirExists(configPath); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := service.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+	if err := migrateLegacyConfig(legacyPath, globalPath); err != nil {
+		return nil, err
+	}
+
+	env, err := envOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	service := &repoConfigService{
+		repoRoot:   repoRoot,
+		configPath: configPath,
+		globalPath: globalPath,
+		sharedPath: filepath.Join(repoRoot, SharedConfigFile),
+		overrides:  []layer{env},
+		scope:      ScopePersonal,
+		onboarding: onboarding,
+	}
+
+	if err := service.load(); err != nil {
 		return nil, err
 	}
 
 	return service, nil
 }
 
-func NewOnboardingService(repoRoot string) (Service, error) {
-	configPath, err := getGlobalConfigPath()
+func (s *repoConfigService) load() error {
+	defaults, err := configToMap(DefaultConfig())
 	if err != nil {
-		return nil, fmt.Errorf("failed to determine config path: %w", err)
+		return err
 	}
+	s.layers = []layer{{name: LayerDefaults, values: defaults}}
 
-	if err := ensureConfigDirExists(configPath); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	shared, err := readLayer(LayerShared, s.sharedPath)
+	if err != nil {
+		return err
 	}
+	s.layers = append(s.layers, shared)
 
-	return &repoConfigService{
-		repoRoot:   repoRoot,
-		configPath: configPath,
-		onboarding: true,
-	}, nil
+	global, err := readLayer(LayerGlobal, s.globalPath)
+	if err != nil {
+		return err
+	}
+	s.layers = append(s.layers, global)
+
+	repo := layer{name: LayerRepo, path: s.configPath, values: make(map[string]interface{})}
+	if !s.onboarding {
+		if repo, err = readLayer(LayerRepo, s.configPath); err != nil {
+			return err
+		}
+	}
+	s.layers = append(s.layers, repo)
+
+	return s.resolve()
 }
 
-func (s *repoConfigService) load() error {
-	if s.onboarding {
-		s.config = DefaultConfig()
-		return nil
+// resolve rebuilds Config() from the layers visible in the current scope and
+// the overrides.
+func (s *repoConfigService) resolve() error {
+	files := s.effectiveLayers()[:s.targetIndex()+1]
+	name, profile, err := s.selectProfile(files, s.overrides)
+	if err != nil {
+		return err
+	}
+
+	layers := append(append(files, profile), s.overrides...)
+	config, err := mapToConfig(mergeLayers(layers))
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	resolved, err := configToMap(config)
+	if err != nil {
+		return err
 	}
 
-	data, err := os.ReadFile(s.configPath)
+	s.config = config
+	s.resolved = resolved
+	s.activeProfile = profile
+	s.activeProfileName = name
+	return nil
+}
+
+// Override applies per-invocation values, such as command-line flags, on top
+// of the files and the environment. The values are never saved.
+func (s *repoConfigService) Override(values map[string]string) error {
+	flags, err := overrideLayer(LayerFlags, values, nil)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			s.config = DefaultConfig()
-			return nil
+		return err
+	}
+
+	overrides := make([]layer, 0, len(s.overrides)+1)
+	for _, o := range s.overrides {
+		if o.name != LayerFlags {
+			overrides = append(overrides, o)
 		}
-		return fmt.Errorf("failed to read config file: %w", err)
 	}
+	s.overrides = append(overrides, flags)
+	return s.resolve()
+}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+// effectiveLayers returns the layers with locked keys removed from the personal ones.
+func (s *repoConfigService) effectiveLayers() []layer {
+	locked := make(map[string]bool)
+	for _, key := range s.LockedKeys() {
+		locked[key] = true
 	}
 
-	s.config = &config
+	effective := make([]layer, len(s.layers))
+	for i, l := range s.layers {
+		if l.name == LayerGlobal || l.name == LayerRepo {
+			l = l.without(locked)
+		}
+		effective[i] = l
+	}
+	return effective
+}
+
+func (s *repoConfigService) targetIndex() int {
+	name := LayerRepo
+	if s.scope == ScopeShared {
+		name = LayerShared
+	}
+	for i, l := range s.layers {
+		if l.name == name {
+			return i
+		}
+	}
+	return len(s.layers) - 1
+}
+
+// LockedKeys lists the keys the shared file sets without marking them overridable.
+func (s *repoConfigService) LockedKeys() []string {
+	var locked []string
+	for _, l := range s.layers {
+		if l.name != LayerShared {
+			continue
+		}
+		overridable := make(map[string]bool)
+		for _, key := range l.overridable {
+			overridable[key] = true
+		}
+		for key := range l.values {
+			if !overridable[key] {
+				locked = append(locked, key)
+			}
+		}
+	}
+	sort.Strings(locked)
+	return locked
+}
+
+// checkLockedKeys refuses to save a personal change to a key the team locked.
+func (s *repoConfigService) checkLockedKeys(cfg *Config, below map[string]interface{}) error {
+	current, err := configToMap(cfg)
+	if err != nil {
+		return err
+	}
+	inherited, err := normalizeValues(below)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range s.LockedKeys() {
+		if !reflect.DeepEqual(current[key], inherited[key]) {
+			return fmt.Errorf("%s is set by %s and is not overridable", key, SharedConfigFile)
+		}
+	}
 	return nil
 }
 
+func (s *repoConfigService) IsLocked(key string) bool {
+	if s.scope == ScopeShared {
+		return false
+	}
+	for _, locked := range s.LockedKeys() {
+		if locked == key {
+			return true
+		}
+	}
+	return false
+}
+
+// SetScope selects the file Save and ConfigPath use. Config() then shows the
+// settings as seen from that scope, so editing the shared file never picks up
+// personal overrides.
+func (s *repoConfigService) SetScope(scope Scope) error {
+	if scope != ScopePersonal && scope != ScopeShared {
+		return fmt.Errorf("unknown config scope '%s'", scope)
+	}
+	s.scope = scope
+	return s.resolve()
+}
+
 func (s *repoConfigService) Update(cfg *Config) error {
 	s.config = cfg
 	return s.Save()
 }
 
 func (s *repoConfigService) ConfigPath() string {
+	if s.scope == ScopeShared {
+		return s.sharedPath
+	}
 	return s.configPath
 }
+
+// Origin reports which layer supplies key as seen from the current scope.
+// Keys no file sets come from the built-in defaults.
+func (s *repoConfigService) Origin(key string) Origin {
+	l, ok := s.originLayer(key)
+	switch {
+	case !ok:
+		return Origin{Layer: LayerDefaults}
+	case l.name == LayerEnv:
+		if k, err := LookupKey(key); err == nil {
+			return Origin{Layer: LayerEnv, Path: k.EnvVar()}
+		}
+	case l.name == LayerProfile:
+		return Origin{Layer: LayerProfile, Path: s.activeProfileName}
+	}
+	return Origin{Layer: l.name, Path: l.path}
+}
+
+func (s *repoConfigService) originLayer(key string) (layer, bool) {
+	layers := append(append(s.effectiveLayers()[:s.targetIndex()+1], s.activeProfile), s.overrides...)
+	for i := len(layers) - 1; i >= 0; i-- {
+		if _, ok := layers[i].values[key]; ok {
+			return layers[i], true
+		}
+	}
+	return layer{}, false
+}
+
+// Unset removes key from the current scope's file so the value is inherited again.
+func (s *repoConfigService) Unset(key string) error {
+	if _, err := LookupKey(key); err != nil {
+		return err
+	}
+
+	index := s.targetIndex()
+	target := s.layers[index]
+	if _, ok := target.values[key]; !ok {
+		return nil
+	}
+	target = target.without(map[string]bool{key: true})
+
+	if err := writeLayer(target); err != nil {
+		return err
+	}
+	s.layers[index] = target
+	return s.resolve()
+}
+
+// Replace validates data as the complete contents of the current scope's file
+// and writes it. Nothing is written when any key is unknown or invalid.
+func (s *repoConfigService) Replace(data []byte) error {
+	index := s.targetIndex()
+	target, err := parseLayer(s.layers[index].name, s.layers[index].path, data)
+	if err != nil {
+		return err
+	}
+	layers := append([]layer(nil), s.layers...)
+	layers[index] = target
+	replaced := &repoConfigService{layers: layers, overrides: s.overrides, profile: s.profile, scope: s.scope}
+	if err := replaced.resolve(); err != nil {
+		return err
+	}
+	if err := replaced.config.Validate(); err != nil {
+		return err
+	}
+	if s.scope == ScopePersonal {
+		for _, key := range s.LockedKeys() {
+			if _, ok := target.values[key]; ok {
+				return fmt.Errorf("%s is set by %s and is not overridable", key, SharedConfigFile)
+			}
+		}
+	}
+
+	// Write what was given rather than re-marshalling it, so comments survive
+	if err := ensureConfigDirExists(target.path); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", target.path, err)
+	}
+	s.layers = layers
+	s.config = replaced.config
+	s.resolved = replaced.resolved
+	s.activeProfile = replaced.activeProfile
+	s.activeProfileName = replaced.activeProfileName
+	return nil
+}
+
+// Validate lints the resolved configuration against the repository's branches
+// and points each finding at the file that sets the offending key. Files that
+// fail strict decoding never get this far: loading them already fails.
+func (s *repoConfigService) Validate(branchNames []string) []Issue {
+	issues := Lint(s.Config(), branchNames)
+	for i := range issues {
+		l, ok := s.originLayer(issues[i].Key)
+		if !ok {
+			continue
+		}
+		issues[i].Path = l.path
+		if pos, ok := l.positions[issues[i].Key]; ok {
+			issues[i].Line, issues[i].Column = pos.line, pos.column
+		}
+	}
+	return issues
+}
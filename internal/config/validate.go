@@ -0,0 +1,412 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is one problem found in a config file. Line and Column are 1-based
+// and zero when the problem has no single location.
+type Issue struct {
+	Path    string
+	Line    int
+	Column  int
+	Key     string
+	Message string
+	Warning bool
+}
+
+func (i Issue) String() string {
+	var location string
+	switch {
+	case i.Path != "" && i.Line > 0 && i.Column > 0:
+		location = fmt.Sprintf("%s:%d:%d: ", i.Path, i.Line, i.Column)
+	case i.Path != "" && i.Line > 0:
+		location = fmt.Sprintf("%s:%d: ", i.Path, i.Line)
+	case i.Path != "":
+		location = i.Path + ": "
+	}
+	if i.Key != "" {
+		return location + i.Key + ": " + i.Message
+	}
+	return location + i.Message
+}
+
+// ValidationError rejects a config file, listing every problem found in it.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+	}
+	return fmt.Sprintf("%d problem(s):\n  %s", len(lines), strings.Join(lines, "\n  "))
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+var (
+	yamlLinePrefix  = regexp.MustCompile(`^(yaml: )?line \d+: `)
+	yamlSyntaxError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+)
+
+// decodeLayer strictly decodes a config file: every key must be known and
+// every value must have the field's type and pass the same checks as the
+// prompts. The overridable list is only accepted in the shared file.
+func decodeLayer(name, path string, data []byte) (layer, []Issue) {
+	l := layer{name: name, path: path, values: make(map[string]interface{}), positions: make(map[string]position)}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		issue := Issue{Path: path, Message: err.Error()}
+		if match := yamlSyntaxError.FindStringSubmatch(err.Error()); match != nil {
+			issue.Line, _ = strconv.Atoi(match[1])
+			issue.Message = match[2]
+		}
+		return l, []Issue{issue}
+	}
+	if len(doc.Content) == 0 {
+		return l, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return l, []Issue{{Path: path, Line: root.Line, Column: root.Column, Message: "expected a mapping of config keys"}}
+	}
+
+	d := &layerDecoder{path: path}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		key := keyNode.Value
+		if _, seen := l.positions[key]; seen {
+			d.issue(keyNode, key, "duplicate key")
+			continue
+		}
+		l.positions[key] = position{line: keyNode.Line, column: keyNode.Column}
+
+		switch key {
+		case overridableKey:
+			if name != LayerShared {
+				d.issue(keyNode, key, "only allowed in %s", SharedConfigFile)
+				continue
+			}
+			l.overridable = d.decodeKeyList(key, valueNode)
+		case profilesKey:
+			l.profiles = d.decodeProfiles(valueNode)
+		default:
+			if value, ok := d.decodeValue(key, key, keyNode, valueNode); ok {
+				l.values[key] = value
+			}
+		}
+	}
+
+	return l, d.issues
+}
+
+type layerDecoder struct {
+	path   string
+	issues []Issue
+}
+
+func (d *layerDecoder) issue(node *yaml.Node, key, format string, args ...interface{}) {
+	d.issues = append(d.issues, Issue{Path: d.path, Line: node.Line, Column: node.Column, Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *layerDecoder) decodeKeyList(key string, node *yaml.Node) []string {
+	var names []string
+	if err := node.Decode(&names); err != nil {
+		d.issue(node, key, "must be a list of keys")
+		return nil
+	}
+	for i, name := range names {
+		if _, err := LookupKey(name); err != nil {
+			d.issue(node.Content[i], key, "%v", err)
+		}
+	}
+	return names
+}
+
+// decodeValue checks one config key; label is how issues name it.
+func (d *layerDecoder) decodeValue(key, label string, keyNode, valueNode *yaml.Node) (interface{}, bool) {
+	if _, err := LookupKey(key); err != nil {
+		d.issue(keyNode, label, "%v", err)
+		return nil, false
+	}
+
+	var single Config
+	if err := decodeStrict(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{keyNode, valueNode}}, &single); err != nil {
+		d.issue(valueNode, label, "%s", typeErrorMessage(err))
+		return nil, false
+	}
+	for _, problem := range checkValue(key, &single, valueNode) {
+		d.issue(problem.node, label, "%s", problem.message)
+	}
+
+	var value interface{}
+	if err := valueNode.Decode(&value); err != nil {
+		d.issue(valueNode, label, "%v", err)
+		return nil, false
+	}
+	return value, true
+}
+
+func (d *layerDecoder) decodeProfiles(node *yaml.Node) map[string]map[string]interface{} {
+	if node.Kind != yaml.MappingNode {
+		d.issue(node, profilesKey, "must map profile names to settings")
+		return nil
+	}
+
+	profiles := make(map[string]map[string]interface{})
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, settingsNode := node.Content[i], node.Content[i+1]
+		name := nameNode.Value
+		label := profilesKey + "." + name
+		if err := ValidateProfileName(name); err != nil {
+			d.issue(nameNode, label, "%v", err)
+			continue
+		}
+		if _, seen := profiles[name]; seen {
+			d.issue(nameNode, label, "duplicate profile")
+			continue
+		}
+		if settingsNode.Kind != yaml.MappingNode {
+			d.issue(settingsNode, label, "must be a mapping of config keys")
+			continue
+		}
+
+		values := make(map[string]interface{})
+		for j := 0; j+1 < len(settingsNode.Content); j += 2 {
+			keyNode, valueNode := settingsNode.Content[j], settingsNode.Content[j+1]
+			if keyNode.Value == defaultProfileKey {
+				d.issue(keyNode, label+"."+keyNode.Value, "cannot be set inside a profile")
+				continue
+			}
+			if value, ok := d.decodeValue(keyNode.Value, label+"."+keyNode.Value, keyNode, valueNode); ok {
+				values[keyNode.Value] = value
+			}
+		}
+		profiles[name] = values
+	}
+	return profiles
+}
+
+func decodeStrict(node *yaml.Node, cfg *Config) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	return decoder.Decode(cfg)
+}
+
+// typeErrorMessage drops yaml's line prefix, which refers to the re-encoded
+// single key rather than the original file.
+func typeErrorMessage(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		return yamlLinePrefix.ReplaceAllString(typeErr.Errors[0], "")
+	}
+	return yamlLinePrefix.ReplaceAllString(err.Error(), "")
+}
+
+type valueProblem struct {
+	node    *yaml.Node
+	message string
+}
+
+// checkValue validates one decoded key, pointing at list elements where it can.
+func checkValue(key string, cfg *Config, node *yaml.Node) []valueProblem {
+	var problems []valueProblem
+	switch key {
+	case "protectedRegex", "includeRegex":
+		patterns := cfg.ProtectedRegex
+		if key == "includeRegex" {
+			patterns = cfg.IncludeRegex
+		}
+		for i, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				at := node
+				if node.Kind == yaml.SequenceNode && i < len(node.Content) {
+					at = node.Content[i]
+				}
+				problems = append(problems, valueProblem{at, fmt.Sprintf("invalid regex pattern '%s': %v", pattern, err)})
+			}
+		}
+	case "remoteName":
+		if err := ValidateRemoteName(cfg.RemoteName); err != nil && node.Tag != "!!null" {
+			problems = append(problems, valueProblem{node, err.Error()})
+		}
+	case "remotes":
+		if err := ValidateRemotes(cfg.Remotes); err != nil {
+			problems = append(problems, valueProblem{node, err.Error()})
+		}
+	case defaultProfileKey:
+		if err := ValidateProfileName(cfg.DefaultProfile); err != nil && node.Tag != "!!null" {
+			problems = append(problems, valueProblem{node, err.Error()})
+		}
+	case "maxAge":
+		if cfg.MaxAge <= 0 {
+			problems = append(problems, valueProblem{node, "must be greater than zero; a zero maxAge treats every merged branch as stale"})
+		}
+	case "goneMaxAge":
+		if cfg.GoneMaxAge < 0 {
+			problems = append(problems, valueProblem{node, "must not be negative"})
+		}
+	case "allRemoteMaxAge":
+		if cfg.AllRemoteMaxAge < 0 {
+			problems = append(problems, valueProblem{node, "must not be negative"})
+		}
+	case "fetchWarnAge":
+		if cfg.FetchWarnAge < 0 {
+			problems = append(problems, valueProblem{node, "must not be negative"})
+		}
+	case "jobs":
+		if cfg.Jobs < 0 {
+			problems = append(problems, valueProblem{node, "must not be negative"})
+		}
+	case "gitTimeout":
+		if cfg.GitTimeout < 0 {
+			problems = append(problems, valueProblem{node, "must not be negative"})
+		}
+	}
+	return problems
+}
+
+// globPattern matches regexes that were most likely written as globs, such as
+// "release/*", which in a regex means "release" followed by any number of slashes.
+var globPattern = regexp.MustCompile(`/\*`)
+
+// Lint reports likely mistakes in cfg that are valid but probably unintended.
+// branchNames are the repository's branches; checks that need them are skipped
+// when it is nil.
+func Lint(cfg *Config, branchNames []string) []Issue {
+	var issues []Issue
+	warn := func(key, format string, args ...interface{}) {
+		issues = append(issues, Issue{Key: key, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{{"protectedRegex", cfg.ProtectedRegex}, {"includeRegex", cfg.IncludeRegex}} {
+		for _, pattern := range list.patterns {
+			if globPattern.MatchString(pattern) {
+				warn(list.key, "'%s' looks like a glob; as a regex it matches '%s' followed by any number of slashes (did you mean '%s'?)",
+					pattern, strings.SplitN(pattern, "/*", 2)[0], globToRegexHint(pattern))
+			}
+		}
+	}
+
+	protected := make(map[string]bool)
+	for _, pattern := range cfg.ProtectedRegex {
+		protected[pattern] = true
+	}
+	for _, pattern := range cfg.IncludeRegex {
+		if protected[pattern] {
+			warn("includeRegex", "'%s' is also a protected pattern, so the branches it includes are never cleaned", pattern)
+		}
+	}
+
+	if len(cfg.Remotes) > 0 {
+		var push, mergeSource bool
+		for _, remote := range cfg.Remotes {
+			push = push || remote.Has(RolePush)
+			mergeSource = mergeSource || remote.Has(RoleMergeSource)
+		}
+		if !push {
+			warn("remotes", "no remote has the %s role, so remote branches are never cleaned", RolePush)
+		}
+		if !mergeSource {
+			warn("remotes", "no remote has the %s role, so merges are only detected in local base branches", RoleMergeSource)
+		}
+	}
+
+	if branchNames == nil {
+		return issues
+	}
+
+	issues = append(issues, lintIncludeOverlap(cfg, branchNames)...)
+
+	existing := make(map[string]bool)
+	for _, name := range branchNames {
+		existing[name] = true
+	}
+	var missing []string
+	for _, base := range cfg.BaseBranches {
+		if !existing[base] {
+			missing = append(missing, base)
+		}
+	}
+	switch {
+	case len(cfg.BaseBranches) == 0:
+		issues = append(issues, Issue{Key: "baseBranches", Message: "no base branches configured, so no branch can be merged"})
+	case len(missing) == len(cfg.BaseBranches):
+		issues = append(issues, Issue{Key: "baseBranches", Message: fmt.Sprintf("none of the base branches exist (%s)", strings.Join(missing, ", "))})
+	case len(missing) > 0:
+		warn("baseBranches", "not found in this repository: %s", strings.Join(missing, ", "))
+	}
+
+	return issues
+}
+
+// lintIncludeOverlap warns about include patterns that select protected
+// branches. Catch-all patterns (matching every branch) are expected to overlap.
+func lintIncludeOverlap(cfg *Config, branchNames []string) []Issue {
+	var issues []Issue
+	for _, include := range cfg.IncludeRegex {
+		includeRe, err := regexp.Compile(include)
+		if err != nil {
+			continue
+		}
+
+		var matched int
+		overlap := make(map[string][]string)
+		for _, name := range branchNames {
+			if !includeRe.MatchString(name) {
+				continue
+			}
+			matched++
+			for _, protect := range cfg.ProtectedRegex {
+				if ok, err := regexp.MatchString(protect, name); err == nil && ok {
+					overlap[protect] = append(overlap[protect], name)
+				}
+			}
+		}
+		if matched == len(branchNames) {
+			continue
+		}
+
+		protects := make([]string, 0, len(overlap))
+		for protect := range overlap {
+			protects = append(protects, protect)
+		}
+		sort.Strings(protects)
+		for _, protect := range protects {
+			issues = append(issues, Issue{
+				Key:     "includeRegex",
+				Message: fmt.Sprintf("'%s' overlaps protectedRegex '%s' (e.g. %s); protected branches are never cleaned", include, protect, overlap[protect][0]),
+				Warning: true,
+			})
+		}
+	}
+	return issues
+}
+
+func globToRegexHint(pattern string) string {
+	hint := strings.ReplaceAll(pattern, "/*", "/")
+	if !strings.HasPrefix(hint, "^") {
+		hint = "^" + hint
+	}
+	return hint
+}
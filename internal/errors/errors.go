@@ -15,6 +15,8 @@ const (
 	ExitConfig ExitCode = 2
 	// git-related error
 	ExitGit ExitCode = 3
+	// stopped by Ctrl-C, following the shell's 128+SIGINT convention
+	ExitInterrupted ExitCode = 130
 )
 
 func FatalError(code ExitCode, format string, args ...interface{}) {
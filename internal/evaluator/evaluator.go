@@ -0,0 +1,236 @@
+package evaluator
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/output"
+)
+
+// Kind identifies a step of the qualification pipeline.
+type Kind string
+
+const (
+	KindCurrent    Kind = "current"
+	KindAge        Kind = "age"
+	KindLocalOnly  Kind = "local-only"
+	KindRemoteOnly Kind = "remote-only"
+	KindInclude    Kind = "include"
+	KindProtected  Kind = "protected"
+	KindMerged     Kind = "merged"
+)
+
+// Result is the outcome of a single check.
+type Result string
+
+const (
+	ResultPass Result = "pass"
+	ResultFail Result = "fail"
+	// ResultIgnored marks include patterns that did not match when another one did
+	ResultIgnored Result = "ignored"
+)
+
+// Check is one rule applied to a branch together with the value it compared.
+type Check struct {
+	Kind Kind
+	// Pattern is the include or protected regex the check tried
+	Pattern string
+	Value   string
+	Result  Result
+}
+
+// Label names the check the way explain prints it, e.g. "include ^feature/".
+func (c Check) Label() string {
+	switch c.Kind {
+	case KindCurrent:
+		return "current branch"
+	case KindAge:
+		return "age"
+	case KindLocalOnly:
+		return "--local-only"
+	case KindRemoteOnly:
+		return "--remote-only"
+	case KindMerged:
+		return "merged"
+	}
+	return string(c.Kind) + " " + c.Pattern
+}
+
+// Decision is the result of running a branch through the checks every
+// qualification rule (merged into a base, upstream gone, no local copy) applies.
+type Decision struct {
+	// Rule is what selected the branch, e.g. "merged into main"
+	Rule      string
+	Qualifies bool
+	Checks    []Check
+}
+
+// Reason summarises why a branch that does not qualify was skipped.
+func (d Decision) Reason() string {
+	for _, check := range d.Checks {
+		if check.Result != ResultFail {
+			continue
+		}
+		switch check.Kind {
+		case KindCurrent:
+			return "current branch"
+		case KindAge:
+			return "too recent (" + check.Value + ")"
+		case KindLocalOnly:
+			return "remote branch with --local-only"
+		case KindRemoteOnly:
+			return "local branch with --remote-only"
+		case KindInclude:
+			return "no include pattern matches"
+		case KindProtected:
+			return "protected by " + check.Pattern
+		case KindMerged:
+			return "not merged (use --force)"
+		}
+	}
+	return ""
+}
+
+// Options are the command line filters clean applies on top of the config.
+type Options struct {
+	LocalOnly  bool
+	RemoteOnly bool
+	// CleanGone also considers local branches whose upstream was deleted
+	CleanGone bool
+	// CleanAllRemote also considers remote branches without a local copy
+	CleanAllRemote bool
+	// Force lets CleanAllRemote clean remote branches that are not merged
+	Force bool
+	// Now is the time ages are measured against, time.Now() if zero
+	Now time.Time
+}
+
+// Evaluator decides which branches clean qualifies, the same way for clean,
+// list and explain.
+type Evaluator struct {
+	cfg           *config.Config
+	branchService git.BranchService
+	opts          Options
+}
+
+func New(cfg *config.Config, branchService git.BranchService, opts Options) *Evaluator {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	return &Evaluator{cfg: cfg, branchService: branchService, opts: opts}
+}
+
+// Evaluate runs every check against branch, selected by rule, with maxAge as
+// the age threshold. Unlike clean's verbose output it does not stop at the
+// first failure, so a Decision lists everything that applies to the branch.
+func (e *Evaluator) Evaluate(branch *git.Branch, rule string, maxAge time.Duration) Decision {
+	var checks []Check
+
+	current := Check{Kind: KindCurrent, Value: "not checked out", Result: ResultPass}
+	if branch.IsCurrent {
+		current.Value, current.Result = "checked out", ResultFail
+	}
+	checks = append(checks, current)
+
+	age := e.opts.Now.Sub(branch.LastCommitAt)
+	ageCheck := Check{Kind: KindAge, Value: fmt.Sprintf("%s old, minimum %s", output.Age(age), output.Age(maxAge)), Result: ResultPass}
+	if age < maxAge {
+		ageCheck.Result = ResultFail
+	}
+	checks = append(checks, ageCheck)
+
+	branchType := "local branch"
+	if branch.IsRemote {
+		branchType = "remote branch"
+	}
+	if e.opts.LocalOnly {
+		checks = append(checks, Check{Kind: KindLocalOnly, Value: branchType, Result: passIf(!branch.IsRemote)})
+	}
+	if e.opts.RemoteOnly {
+		checks = append(checks, Check{Kind: KindRemoteOnly, Value: branchType, Result: passIf(branch.IsRemote)})
+	}
+
+	includeMatched := false
+	var includes []Check
+	for _, pattern := range e.cfg.IncludeRegex {
+		matched := matches(pattern, branch.Name)
+		includeMatched = includeMatched || matched
+		includes = append(includes, Check{Kind: KindInclude, Pattern: pattern, Value: matchValue(matched), Result: passIf(matched)})
+	}
+	if len(includes) == 0 {
+		includes = append(includes, Check{Kind: KindInclude, Pattern: "(none configured)", Value: "nothing is included", Result: ResultFail})
+	}
+	for i := range includes {
+		if includeMatched && includes[i].Result == ResultFail {
+			includes[i].Result = ResultIgnored
+		}
+	}
+	checks = append(checks, includes...)
+
+	for _, pattern := range e.cfg.ProtectedRegex {
+		matched := e.branchService.IsProtectedBranch(branch, []string{pattern})
+		checks = append(checks, Check{Kind: KindProtected, Pattern: pattern, Value: matchValue(matched), Result: passIf(!matched)})
+	}
+
+	decision := Decision{Rule: rule, Qualifies: true, Checks: checks}
+	for _, check := range checks {
+		if check.Result == ResultFail {
+			decision.Qualifies = false
+		}
+	}
+	return decision
+}
+
+// EvaluateNoLocalCopy is Evaluate for the rule for remote branches without a
+// local copy, with allRemoteMaxAge as the threshold. Those are usually someone
+// else's work, so unless Force is set they must also be merged into a base.
+func (e *Evaluator) EvaluateNoLocalCopy(branch *git.Branch, merged bool) Decision {
+	decision := e.Evaluate(branch, "no local copy", time.Duration(e.cfg.AllRemoteMaxAge))
+	check := Check{Kind: KindMerged, Value: "not merged", Result: ResultFail}
+	switch {
+	case merged:
+		check.Value, check.Result = "merged", ResultPass
+	case e.opts.Force:
+		check.Value, check.Result = "not merged, --force", ResultPass
+	}
+	decision.Checks = append(decision.Checks, check)
+	decision.Qualifies = decision.Qualifies && check.Result == ResultPass
+	return decision
+}
+
+// HasNoLocalCopy reports whether branch is a remote branch, other than a base
+// branch, without a local branch of the same name.
+func (e *Evaluator) HasNoLocalCopy(branch *git.Branch) bool {
+	if !branch.IsRemote || branch.HasLocal {
+		return false
+	}
+	for _, base := range e.cfg.BaseBranches {
+		if branch.Name == base {
+			return false
+		}
+	}
+	return true
+}
+
+func passIf(ok bool) Result {
+	if ok {
+		return ResultPass
+	}
+	return ResultFail
+}
+
+func matches(pattern, name string) bool {
+	// Patterns are validated when the config is loaded
+	matched, err := regexp.MatchString(pattern, name)
+	return err == nil && matched
+}
+
+func matchValue(matched bool) string {
+	if matched {
+		return "matches"
+	}
+	return "does not match"
+}
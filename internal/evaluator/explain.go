@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+)
+
+// BaseResult is a branch's merge status against one base branch.
+type BaseResult struct {
+	Base   string
+	Exists bool
+	// Err is set when the base could not be checked
+	Err         error
+	Merged      bool
+	MergeMethod git.MergeMethod
+	// Partial is set when only CommitsUpstream of CommitsUnique commits landed
+	Partial         bool
+	CommitsUnique   int
+	CommitsUpstream int
+	// Decision is only set when the branch is merged into the base
+	Decision *Decision
+}
+
+// Explanation traces one branch through every rule clean applies, in order.
+type Explanation struct {
+	Branch git.Branch
+	Bases  []BaseResult
+	// Gone is set when the gone rule applies to the branch
+	Gone *Decision
+	// AllRemote is set when the rule for remote branches without a local copy applies
+	AllRemote *Decision
+	// Qualifying is the first decision that qualified the branch, if any
+	Qualifying *Decision
+}
+
+// FindBranch looks name up among the local branches, then the remote ones.
+// Remote branches can also be given as <remote>/<name>, which is how to pick
+// the remote one when a local branch has the same name. Every branch of a push
+// remote is found, with or without a local copy.
+func (e *Evaluator) FindBranch(name string) (git.Branch, error) {
+	branches, err := e.branchService.GetBranchesWithTrackedRemotes()
+	if err != nil {
+		return git.Branch{}, err
+	}
+	remoteBranches, err := e.branchService.GetRemoteBranches()
+	if err != nil {
+		return git.Branch{}, err
+	}
+	branches = append(branches, remoteBranches...)
+
+	for _, branch := range branches {
+		if !branch.IsRemote && branch.Name == name {
+			return branch, nil
+		}
+	}
+	for _, branch := range branches {
+		if branch.IsRemote && (branch.Name == name || branch.Remote+"/"+branch.Name == name) {
+			return branch, nil
+		}
+	}
+	return git.Branch{}, fmt.Errorf("branch '%s' not found", name)
+}
+
+// Explain runs branch through the same pipeline as clean: its merge status
+// against every base branch, each followed by the filters, then the gone rule
+// and the rule for remote branches without a local copy.
+func (e *Evaluator) Explain(branch git.Branch) *Explanation {
+	explanation := &Explanation{Branch: branch}
+
+	for _, base := range e.cfg.BaseBranches {
+		result := BaseResult{Base: base}
+		result.Exists, result.Err = e.branchService.BranchExists(base)
+		if result.Err != nil || !result.Exists {
+			explanation.Bases = append(explanation.Bases, result)
+			continue
+		}
+
+		merged, partial, err := e.branchService.GetMergeStatus(base)
+		if err != nil {
+			result.Err = err
+			explanation.Bases = append(explanation.Bases, result)
+			continue
+		}
+		if match, ok := findIn(merged, &branch); ok {
+			result.Merged, result.MergeMethod = true, match.MergeMethod
+			decision := e.Evaluate(match, "merged into "+base, time.Duration(e.cfg.MaxAge))
+			result.Decision = &decision
+			if decision.Qualifies && explanation.Qualifying == nil {
+				explanation.Qualifying = result.Decision
+			}
+		} else if match, ok := findIn(partial, &branch); ok {
+			result.Partial = true
+			result.CommitsUnique, result.CommitsUpstream = match.CommitsUnique, match.CommitsUpstream
+		}
+		explanation.Bases = append(explanation.Bases, result)
+	}
+
+	if e.opts.CleanGone && branch.UpstreamGone && !branch.IsRemote {
+		decision := e.Evaluate(&branch, "upstream gone", time.Duration(e.cfg.GoneMaxAge))
+		explanation.Gone = &decision
+		if decision.Qualifies && explanation.Qualifying == nil {
+			explanation.Qualifying = explanation.Gone
+		}
+	}
+
+	if e.opts.CleanAllRemote && e.HasNoLocalCopy(&branch) {
+		merged := false
+		for _, result := range explanation.Bases {
+			merged = merged || result.Merged
+		}
+		decision := e.EvaluateNoLocalCopy(&branch, merged)
+		explanation.AllRemote = &decision
+		if decision.Qualifies && explanation.Qualifying == nil {
+			explanation.Qualifying = explanation.AllRemote
+		}
+	}
+	return explanation
+}
+
+func findIn(branches []git.Branch, branch *git.Branch) (*git.Branch, bool) {
+	for i := range branches {
+		if branches[i].Name == branch.Name && branches[i].IsRemote == branch.IsRemote {
+			return &branches[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,210 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveRefPrefix is the hidden namespace archived branches are moved to.
+// Refs outside refs/heads and refs/remotes never show up in `git branch -a`.
+const ArchiveRefPrefix = "refs/clean-git/archive/"
+
+const archiveDateLayout = "2006-01-02"
+
+type ArchivedRef struct {
+	Ref        string
+	Name       string
+	SHA        string
+	ArchivedOn time.Time
+	// Remote is where the archive ref is stored; empty for local archive refs
+	Remote string
+	// BranchRemote is the remote the archived branch was on; empty for local branches
+	BranchRemote string
+}
+
+// BranchName is the archived branch as <remote>/<name> for remote branches.
+func (a ArchivedRef) BranchName() string {
+	if a.BranchRemote != "" {
+		return a.BranchRemote + "/" + a.Name
+	}
+	return a.Name
+}
+
+// ArchiveRefFor builds refs/clean-git/archive/<date>/<name> for a branch archived on date.
+func ArchiveRefFor(date time.Time, branchName string) string {
+	return ArchiveRefPrefix + date.Format(archiveDateLayout) + "/" + branchName
+}
+
+// ArchiveRefForBranch is ArchiveRefFor with local branches under
+// heads/<name> and remote branches under remotes/<remote>/<name>, so a local
+// branch and its remote copy archived on the same day do not compete for one
+// ref, and a local branch named like remotes/... is not read back as remote.
+func ArchiveRefForBranch(date time.Time, branch *Branch) string {
+	if branch.IsRemote {
+		return ArchiveRefFor(date, "remotes/"+remoteOrDefault(branch.Remote, "")+"/"+branch.Name)
+	}
+	return ArchiveRefFor(date, "heads/"+branch.Name)
+}
+
+func parseArchivedRefs(refs map[string]string, remote string) []ArchivedRef {
+	var archived []ArchivedRef
+	for ref, sha := range refs {
+		rest := strings.TrimPrefix(ref, ArchiveRefPrefix)
+		if rest == ref {
+			continue
+		}
+		date, name, found := strings.Cut(rest, "/")
+		if !found || name == "" {
+			continue
+		}
+		archivedOn, err := time.ParseInLocation(archiveDateLayout, date, time.Local)
+		if err != nil {
+			continue
+		}
+		// Local archives made before the heads/ segment existed have none
+		branchRemote := ""
+		if local, isLocal := strings.CutPrefix(name, "heads/"); isLocal {
+			if name = local; name == "" {
+				continue
+			}
+		} else if rest, isRemote := strings.CutPrefix(name, "remotes/"); isRemote {
+			if branchRemote, name, found = strings.Cut(rest, "/"); !found || name == "" {
+				continue
+			}
+		}
+		archived = append(archived, ArchivedRef{
+			Ref:          ref,
+			Name:         name,
+			SHA:          sha,
+			ArchivedOn:   archivedOn,
+			Remote:       remote,
+			BranchRemote: branchRemote,
+		})
+	}
+
+	sort.Slice(archived, func(i, j int) bool {
+		if !archived[i].ArchivedOn.Equal(archived[j].ArchivedOn) {
+			return archived[i].ArchivedOn.Before(archived[j].ArchivedOn)
+		}
+		return archived[i].BranchName() < archived[j].BranchName()
+	})
+	return archived
+}
+
+func (s *DefaultBranchService) ArchiveBranch(branch *Branch, archiveRef string, toRemote bool) error {
+	if branch.IsRemote {
+		branch.Remote = remoteOrDefault(branch.Remote, s.RemoteName)
+		if err := checkOwnedRemote(branch, s.Remotes); err != nil {
+			return err
+		}
+	}
+	if err := s.checkLease(branch); err != nil {
+		return err
+	}
+	sha, err := s.ResolveBranchSHA(branch)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.Client.resolveRef(archiveRef)
+	if err == nil && existing != sha {
+		return fmt.Errorf("archive ref %s already exists at %s", archiveRef, existing)
+	}
+	if err != nil {
+		if err := s.Client.updateRef(archiveRef, sha); err != nil {
+			return err
+		}
+	}
+
+	if branch.IsRemote && toRemote {
+		if err := s.Client.pushRef(remoteOrDefault(branch.Remote, s.RemoteName), sha, archiveRef); err != nil {
+			return err
+		}
+	}
+
+	// The archive ref preserves every commit, so the safe-delete checks do not apply
+	return s.ForceDeleteBranch(branch)
+}
+
+func (s *DefaultBranchService) ListArchivedBranches(remote string) ([]ArchivedRef, error) {
+	if remote != "" {
+		refs, err := s.Client.listRemoteRefs(remote, ArchiveRefPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return parseArchivedRefs(refs, remote), nil
+	}
+
+	refs, err := s.Client.listRefs(ArchiveRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return parseArchivedRefs(refs, ""), nil
+}
+
+func (s *DefaultBranchService) DeleteArchivedBranch(archived ArchivedRef) error {
+	if archived.Remote != "" {
+		return s.Client.deleteRemoteRef(archived.Remote, archived.Ref)
+	}
+	return s.Client.deleteRef(archived.Ref)
+}
+
+func (s *TestableBranchService) ArchiveBranch(branch *Branch, archiveRef string, toRemote bool) error {
+	if branch.IsRemote {
+		branch.Remote = remoteOrDefault(branch.Remote, s.RemoteName)
+		if err := checkOwnedRemote(branch, s.Remotes); err != nil {
+			return err
+		}
+	}
+	if err := s.checkLease(branch); err != nil {
+		return err
+	}
+	sha, err := s.ResolveBranchSHA(branch)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.client.ResolveRef(archiveRef)
+	if err == nil && existing != sha {
+		return fmt.Errorf("archive ref %s already exists at %s", archiveRef, existing)
+	}
+	if err != nil {
+		if err := s.client.UpdateRef(archiveRef, sha); err != nil {
+			return err
+		}
+	}
+
+	if branch.IsRemote && toRemote {
+		if err := s.client.PushRef(remoteOrDefault(branch.Remote, s.RemoteName), sha, archiveRef); err != nil {
+			return err
+		}
+	}
+
+	// The archive ref preserves every commit, so the safe-delete checks do not apply
+	return s.ForceDeleteBranch(branch)
+}
+
+func (s *TestableBranchService) ListArchivedBranches(remote string) ([]ArchivedRef, error) {
+	if remote != "" {
+		refs, err := s.client.ListRemoteRefs(remote, ArchiveRefPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return parseArchivedRefs(refs, remote), nil
+	}
+
+	refs, err := s.client.ListRefs(ArchiveRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return parseArchivedRefs(refs, ""), nil
+}
+
+func (s *TestableBranchService) DeleteArchivedBranch(archived ArchivedRef) error {
+	if archived.Remote != "" {
+		return s.client.DeleteRemoteRef(archived.Remote, archived.Ref)
+	}
+	return s.client.DeleteRef(archived.Ref)
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// branchMetadataFormat collects everything createBranchFromName needs for every
+// branch in a single for-each-ref call. Fields are NUL separated because author
+// names may contain any printable character.
+const branchMetadataFormat = "%(refname)%00%(symref)%00%(objectname:short)%00%(committerdate:iso)%00" +
+	"%(authorname)%00%(authoremail)%00%(upstream:short)%00%(upstream:track)%00%(HEAD)%00%(contents:subject)"
+
+const branchMetadataFields = 10
+
+// getBranchMetadata lists branch refs matching patterns (all local and remote
+// branches when none are given) in branchMetadataFormat, one per line.
+func (c *defaultGitClient) getBranchMetadata(patterns ...string) (string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"refs/heads", "refs/remotes"}
+	}
+	args := append([]string{"for-each-ref", "--format=" + branchMetadataFormat}, patterns...)
+	output, err := c.run(c.ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to list branch metadata: %w", err)
+	}
+	return output, nil
+}
+
+// parseBranchMetadata builds branches keyed the way `git branch --all` names
+// them ("feature/x", "remotes/origin/feature/x"). Symbolic refs and branches of
+// remotes that are not configured are left out so callers fall back to the
+// per-branch path.
+func parseBranchMetadata(output string, remotes []Remote) (map[string]Branch, error) {
+	branches := make(map[string]Branch)
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\x00")
+		if len(fields) != branchMetadataFields {
+			return nil, fmt.Errorf("unexpected branch metadata format: %q", line)
+		}
+		refName, symRef := fields[0], fields[1]
+		if symRef != "" {
+			continue
+		}
+
+		var key, name, remote string
+		switch {
+		case strings.HasPrefix(refName, "refs/heads/"):
+			name = strings.TrimPrefix(refName, "refs/heads/")
+			key = name
+		case strings.HasPrefix(refName, "refs/remotes/"):
+			var ok bool
+			if remote, name, ok = splitRemoteBranch(strings.TrimPrefix(refName, "refs/"), remotes); !ok {
+				continue
+			}
+			key = "remotes/" + remote + "/" + name
+		default:
+			continue
+		}
+
+		commitDate, err := time.Parse("2006-01-02 15:04:05 -0700", fields[3])
+		if err != nil {
+			commitDate = time.Time{}
+		}
+
+		branch := Branch{
+			Name:           name,
+			IsCurrent:      remote == "" && fields[8] == "*",
+			IsRemote:       remote != "",
+			LastCommitAt:   commitDate,
+			LastCommitSHA:  fields[2],
+			AuthorUserName: fields[4],
+			AuthorEmail:    strings.TrimSuffix(strings.TrimPrefix(fields[5], "<"), ">"),
+			Remote:         remote,
+			Subject:        fields[9],
+		}
+		if remote == "" {
+			applyUpstreamInfo(&branch, fields[6], fields[7])
+			branch.HasUnpushedCommits = branch.Ahead > 0
+		}
+		branches[key] = branch
+	}
+
+	for key, branch := range branches {
+		if branch.IsRemote {
+			_, branch.HasLocal = branches[branch.Name]
+			branches[key] = branch
+		}
+	}
+	return branches, nil
+}
+
+// branchRefPattern turns a `git branch --all` style name into the ref it names.
+func branchRefPattern(branchName string) string {
+	if strings.HasPrefix(branchName, "remotes/") {
+		return "refs/" + branchName
+	}
+	return "refs/heads/" + branchName
+}
+
+// branchSnapshot returns nil when the batch call fails; lookups then fall
+// back to createBranchFromName.
+func (s *DefaultBranchService) branchSnapshot(patterns ...string) map[string]Branch {
+	output, err := s.Client.getBranchMetadata(patterns...)
+	if err != nil {
+		return nil
+	}
+	snapshot, err := parseBranchMetadata(output, s.Remotes)
+	if err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+func (s *DefaultBranchService) branchFromSnapshot(snapshot map[string]Branch, branchName string) (*Branch, error) {
+	if branch, ok := snapshot[branchName]; ok {
+		return &branch, nil
+	}
+	return s.createBranchFromName(branchName)
+}
+
+func (s *TestableBranchService) branchSnapshot(patterns ...string) map[string]Branch {
+	output, err := s.client.GetBranchMetadata(patterns...)
+	if err != nil {
+		return nil
+	}
+	snapshot, err := parseBranchMetadata(output, s.Remotes)
+	if err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+func (s *TestableBranchService) branchFromSnapshot(snapshot map[string]Branch, branchName string) (*Branch, error) {
+	if branch, ok := snapshot[branchName]; ok {
+		return &branch, nil
+	}
+	return s.createBranchFromName(branchName)
+}
@@ -2,15 +2,60 @@ package git
 
 import "time"
 
+// MergeMethod records how a branch's work reached a base branch.
+type MergeMethod string
+
+const (
+	MergeMethodNone MergeMethod = ""
+	// MergeMethodMerge means the branch tip is an ancestor of the base (merge commit or fast-forward)
+	MergeMethodMerge MergeMethod = "merge"
+	// MergeMethodSquash means the branch's combined diff was applied to the base as a single commit
+	MergeMethodSquash MergeMethod = "squash"
+	// MergeMethodRebase means every commit unique to the branch has a patch-equivalent commit in the base
+	MergeMethodRebase MergeMethod = "rebase"
+)
+
 type Branch struct {
 	Name               string
 	IsCurrent          bool
 	IsRemote           bool
 	IsMerged           bool
+	MergeMethod        MergeMethod
 	LastCommitAt       time.Time
 	LastCommitSHA      string
 	AuthorUserName     string
 	AuthorEmail        string
 	HasUnpushedCommits bool
 	Remote             string
+
+	// Upstream is the short name of the configured upstream (e.g. origin/feature/x).
+	// UpstreamGone is set when that upstream no longer exists; Ahead and Behind
+	// count commits relative to it and are zero when it is gone.
+	Upstream     string
+	UpstreamGone bool
+	Ahead        int
+	Behind       int
+
+	// CommitsUnique counts commits not reachable from the base and CommitsUpstream
+	// how many of them have a patch-equivalent there; only set for partial merges
+	CommitsUnique   int
+	CommitsUpstream int
+
+	// Subject is the first line of the tip commit's message
+	Subject string
+
+	// Remotes lists the configured remotes a branch of this name exists on;
+	// only set by GetBranchesWithTrackedRemotes and GetRemoteBranches
+	Remotes []string
+	// HasLocal is set on remote branches with a local branch of the same name
+	HasLocal bool
+
+	// Divergence counts commits against each base branch and UniqueCommits
+	// those in none of them; both are only set by BranchService.AddDivergence
+	Divergence    []Divergence
+	UniqueCommits int
+
+	// ExpectedSHA, when set, is the full tip the branch must still point at for
+	// it to be deleted or archived; remote deletions push with a lease on it
+	ExpectedSHA string
 }
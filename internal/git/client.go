@@ -1,44 +1,124 @@
 package git
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // gitClient handles raw git command execution (internal interface)
 type gitClient interface {
-	run(args ...string) (string, error)
+	run(ctx context.Context, args ...string) (string, error)
 	getCurrentBranchName() (string, error)
 	getMergedBranchNames(baseBranch string) ([]string, error)
 	getAllBranchNames() ([]string, error)
 	getBranchCommitInfo(branchName string) (string, error)
+	getBranchLog(ref string, limit int) (string, error)
 	deleteLocalBranch(branchName string) error
+	forceDeleteLocalBranch(branchName string) error
+	deleteLocalBranchAt(branchName, sha string, force bool) error
+	upstreamContains(branchName string) (contains bool, hasUpstream bool, err error)
 	deleteRemoteBranch(remote, branchName string) error
+	deleteRemoteBranchWithLease(remote, branchName, sha string) error
+	getAheadBehind(baseRef string) (string, error)
+	countAheadBehind(ref, baseRef string) (ahead int, behind int, err error)
+	countUniqueCommits(ref string, baseRefs []string) (int, error)
 	hasUnpushedCommits(branchName string) (bool, error)
 	getCurrentUserName() (string, error)
 	getCurrentUserEmail() (string, error)
-	branchExists(branchName string) (bool, error)
+	branchExists(branchName string, remotes []string) (bool, error)
+	resolveRef(ref string) (string, error)
+	createLocalBranch(branchName, sha string) error
+	pushBranch(remote, branchName, sha string) error
+	updateRef(ref, sha string) error
+	deleteRef(ref string) error
+	pushRef(remote, sha, ref string) error
+	deleteRemoteRef(remote, ref string) error
+	listRefs(prefix string) (map[string]string, error)
+	listRemoteRefs(remote, prefix string) (map[string]string, error)
+	isSquashMerged(branchRef, baseRef string) (bool, error)
+	cherryCounts(branchRef, baseRef string) (upstream int, total int, err error)
+	getUpstreamInfo(branchName string) (upstream string, track string, err error)
+	getBranchMetadata(patterns ...string) (string, error)
+	fetchPrune(remote string) error
+	gitPath(name string) (string, error)
+	gitCommonDir() (string, error)
 }
 
-type defaultGitClient struct{}
+// networkCommands talk to a remote and may need credentials.
+var networkCommands = map[string]bool{"push": true, "fetch": true, "ls-remote": true}
 
-func newGitClient() gitClient {
-	return &defaultGitClient{}
+// defaultGitClient runs every command under ctx, each limited to timeout
+// (no limit when zero).
+type defaultGitClient struct {
+	ctx     context.Context
+	timeout time.Duration
+	// prompt retries network commands that failed authentication in the
+	// foreground, where git and ssh can ask for credentials
+	prompt   bool
+	promptMu sync.Mutex // held by those retries so the prompts do not interleave
 }
 
-func (c *defaultGitClient) run(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+func newGitClient(ctx context.Context, timeout time.Duration, prompt bool) gitClient {
+	return &defaultGitClient{ctx: contextOrBackground(ctx), timeout: timeout, prompt: prompt}
+}
+
+func (c *defaultGitClient) run(ctx context.Context, args ...string) (string, error) {
+	output, err := c.runOnce(ctx, false, args...)
+	if err != nil && c.prompt && networkCommands[args[0]] && errors.Is(err, ErrAuthFailed) && ctx.Err() == nil {
+		c.promptMu.Lock()
+		defer c.promptMu.Unlock()
+		output, err = c.runOnce(ctx, true, args...)
+	}
+	return output, err
+}
+
+// runOnce runs git detached and without prompts, so commands can run in
+// parallel and Ctrl-C leaves them to finish, or in the foreground when it
+// may need to ask for credentials.
+func (c *defaultGitClient) runOnce(ctx context.Context, foreground bool, args ...string) (string, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if !foreground {
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		detach(cmd)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	start := time.Now()
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("git command failed: %w", err)
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return "", fmt.Errorf("git %s timed out after %s: %w", args[0], c.timeout, ctx.Err())
+		case context.Canceled:
+			return "", fmt.Errorf("git %s was interrupted: %w", args[0], ctx.Err())
+		}
+		commandErr := &CommandError{Args: args, ExitCode: -1, Stderr: stderr.String(), Duration: time.Since(start), Err: err}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			commandErr.ExitCode = exitErr.ExitCode()
+		}
+		commandErr.Kind = classifyStderr(commandErr.Stderr)
+		return "", commandErr
 	}
 	return string(output), nil
 }
 
 func (c *defaultGitClient) getCurrentBranchName() (string, error) {
-	output, err := c.run("branch", "--show-current")
+	output, err := c.run(c.ctx, "branch", "--show-current")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
@@ -46,7 +126,7 @@ func (c *defaultGitClient) getCurrentBranchName() (string, error) {
 }
 
 func (c *defaultGitClient) getMergedBranchNames(baseBranch string) ([]string, error) {
-	output, err := c.run("branch", "--merged", baseBranch)
+	output, err := c.run(c.ctx, "branch", "--all", "--merged", baseBranch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get merged branches for '%s': %w", baseBranch, err)
 	}
@@ -56,7 +136,7 @@ func (c *defaultGitClient) getMergedBranchNames(baseBranch string) ([]string, er
 
 	for _, line := range lines {
 		branch := strings.TrimSpace(strings.TrimPrefix(line, "*"))
-		if branch != "" && branch != baseBranch {
+		if branch != "" && branch != baseBranch && !strings.Contains(branch, " -> ") {
 			branches = append(branches, branch)
 		}
 	}
@@ -65,7 +145,7 @@ func (c *defaultGitClient) getMergedBranchNames(baseBranch string) ([]string, er
 }
 
 func (c *defaultGitClient) getAllBranchNames() ([]string, error) {
-	output, err := c.run("branch", "--all")
+	output, err := c.run(c.ctx, "branch", "--all")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all branches: %w", err)
 	}
@@ -75,7 +155,7 @@ func (c *defaultGitClient) getAllBranchNames() ([]string, error) {
 
 	for _, line := range lines {
 		branch := strings.TrimSpace(strings.TrimPrefix(line, "*"))
-		if branch != "" && branch != "origin/HEAD" {
+		if branch != "" && !strings.Contains(branch, " -> ") {
 			branches = append(branches, branch)
 		}
 	}
@@ -84,35 +164,136 @@ func (c *defaultGitClient) getAllBranchNames() ([]string, error) {
 }
 
 func (c *defaultGitClient) getBranchCommitInfo(branchName string) (string, error) {
-	output, err := c.run("log", "-1", "--format=%ci|%an|%ae|%h", branchName)
+	output, err := c.run(c.ctx, "log", "-1", "--format=%ci|%an|%ae|%h|%s", branchName)
 	if err != nil {
 		return "", fmt.Errorf("failed to get branch commit info for %s: %w", branchName, err)
 	}
 	return strings.TrimSpace(output), nil
 }
 
+func (c *defaultGitClient) getBranchLog(ref string, limit int) (string, error) {
+	output, err := c.run(c.ctx, "log", "-n", strconv.Itoa(limit), "--date=short", "--format=%h %ad %an: %s", ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get log for %s: %w", ref, err)
+	}
+	return strings.TrimRight(output, "\n"), nil
+}
+
 func (c *defaultGitClient) deleteLocalBranch(branchName string) error {
-	_, err := c.run("branch", "-d", branchName)
+	_, err := c.run(c.ctx, "branch", "-d", branchName)
+	if err != nil {
+		return fmt.Errorf("failed to delete local branch %s: %w", branchName, err)
+	}
+	return nil
+}
+
+func (c *defaultGitClient) forceDeleteLocalBranch(branchName string) error {
+	_, err := c.run(c.ctx, "branch", "-D", branchName)
+	if err != nil {
+		return fmt.Errorf("failed to force delete local branch %s: %w", branchName, err)
+	}
+	return nil
+}
+
+// upstreamContains reports whether the branch tip is reachable from its upstream.
+// hasUpstream is false when no upstream is configured or it no longer resolves.
+func (c *defaultGitClient) upstreamContains(branchName string) (bool, bool, error) {
+	upstream := branchName + "@{upstream}"
+	if _, err := c.run(c.ctx, "rev-parse", "--verify", "--quiet", upstream); err != nil {
+		return false, false, nil
+	}
+
+	_, err := c.run(c.ctx, "merge-base", "--is-ancestor", "refs/heads/"+branchName, upstream)
+	if err == nil {
+		return true, true, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, true, nil
+	}
+	return false, true, fmt.Errorf("failed to compare %s with its upstream: %w", branchName, err)
+}
+
+// deleteLocalBranchAt deletes branchName only while it still points at sha.
+// The checks git branch -d makes come first: the branch must not be checked
+// out in any worktree and, unless force is set, must be merged into its
+// upstream or, without one, into HEAD. The compare and the delete are then a
+// single update-ref, so a commit made in between is never deleted.
+func (c *defaultGitClient) deleteLocalBranchAt(branchName, sha string, force bool) error {
+	ref := "refs/heads/" + branchName
+	worktree, err := c.worktreeWith(ref)
 	if err != nil {
-		// Try force delete if regular delete fails
-		_, forceErr := c.run("branch", "-D", branchName)
-		if forceErr != nil {
-			return fmt.Errorf("failed to delete local branch %s: %w", branchName, err)
+		return fmt.Errorf("failed to delete local branch %s: %w", branchName, err)
+	}
+	if worktree != "" {
+		return fmt.Errorf("failed to delete local branch %s: %w at %s", branchName, ErrCheckedOutInWorktree, worktree)
+	}
+
+	if !force {
+		target := branchName + "@{upstream}"
+		if _, err := c.run(c.ctx, "rev-parse", "--verify", "--quiet", target); err != nil {
+			target = "HEAD"
+		}
+		_, err := c.run(c.ctx, "merge-base", "--is-ancestor", sha, target)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return fmt.Errorf("failed to delete local branch %s: %w", branchName, ErrNotFullyMerged)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to compare %s with %s: %w", branchName, target, err)
 		}
 	}
+
+	if _, err := c.run(c.ctx, "update-ref", "-d", ref, sha); err != nil {
+		return fmt.Errorf("failed to delete local branch %s: %w", branchName, err)
+	}
+	// git branch -d also drops the branch's upstream settings; a branch
+	// without any makes this fail, which is fine
+	c.run(c.ctx, "config", "--remove-section", "branch."+branchName)
 	return nil
 }
 
+// worktreeWith returns the path of the worktree that has ref checked out, if any.
+func (c *defaultGitClient) worktreeWith(ref string) (string, error) {
+	output, err := c.run(c.ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	var path string
+	for _, line := range strings.Split(output, "\n") {
+		if worktree, ok := strings.CutPrefix(line, "worktree "); ok {
+			path = worktree
+		} else if line == "branch "+ref {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
 func (c *defaultGitClient) deleteRemoteBranch(remote, branchName string) error {
-	_, err := c.run("push", remote, "--delete", branchName)
+	_, err := c.run(c.ctx, "push", remote, "--delete", branchName)
 	if err != nil {
 		return fmt.Errorf("failed to delete remote branch %s/%s: %w", remote, branchName, err)
 	}
 	return nil
 }
 
+// deleteRemoteBranchWithLease only deletes the branch if the remote still has
+// it at sha, checked by the remote itself so a concurrent push is never lost.
+func (c *defaultGitClient) deleteRemoteBranchWithLease(remote, branchName, sha string) error {
+	ref := "refs/heads/" + branchName
+	_, err := c.run(c.ctx, "push", "--force-with-lease="+ref+":"+sha, remote, "--delete", ref)
+	if err != nil {
+		if errors.Is(err, ErrRemoteRejected) && strings.Contains(err.Error(), "stale info") {
+			return fmt.Errorf("failed to delete remote branch %s/%s: its tip on the remote moved from %s: %w", remote, branchName, sha, err)
+		}
+		return fmt.Errorf("failed to delete remote branch %s/%s: %w", remote, branchName, err)
+	}
+	return nil
+}
+
 func (c *defaultGitClient) hasUnpushedCommits(branchName string) (bool, error) {
-	output, err := c.run("rev-list", "--count", branchName+"@{upstream}.."+branchName)
+	output, err := c.run(c.ctx, "rev-list", "--count", branchName+"@{upstream}.."+branchName)
 	if err != nil {
 		// If there's no upstream, assume no unpushed commits
 		return false, nil
@@ -128,7 +309,7 @@ func (c *defaultGitClient) hasUnpushedCommits(branchName string) (bool, error) {
 
 // GetCurrentUserName retrieves the git user.name configuration
 func (c *defaultGitClient) getCurrentUserName() (string, error) {
-	output, err := c.run("config", "user.name")
+	output, err := c.run(c.ctx, "config", "user.name")
 	if err != nil {
 		return "", err
 	}
@@ -140,7 +321,7 @@ func (c *defaultGitClient) getCurrentUserName() (string, error) {
 }
 
 func (c *defaultGitClient) getCurrentUserEmail() (string, error) {
-	output, err := c.run("config", "user.email")
+	output, err := c.run(c.ctx, "config", "user.email")
 	if err != nil {
 		return "", err
 	}
@@ -151,24 +332,107 @@ func (c *defaultGitClient) getCurrentUserEmail() (string, error) {
 	return email, nil
 }
 
-func (c *defaultGitClient) branchExists(branchName string) (bool, error) {
-	_, err := c.run("show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
+// branchExists looks for branchName locally, on each of remotes and as any
+// other ref name.
+func (c *defaultGitClient) branchExists(branchName string, remotes []string) (bool, error) {
+	_, err := c.run(c.ctx, "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
 	if err == nil {
 		return true, nil
 	}
 
-	remotes := []string{"origin", "upstream"}
 	for _, remote := range remotes {
-		_, err := c.run("show-ref", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branchName)
+		_, err := c.run(c.ctx, "show-ref", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branchName)
 		if err == nil {
 			return true, nil
 		}
 	}
 
-	_, err = c.run("rev-parse", "--verify", "--quiet", branchName)
+	_, err = c.run(c.ctx, "rev-parse", "--verify", "--quiet", branchName)
 	if err == nil {
 		return true, nil
 	}
 
 	return false, nil
 }
+
+func (c *defaultGitClient) resolveRef(ref string) (string, error) {
+	output, err := c.run(c.ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (c *defaultGitClient) createLocalBranch(branchName, sha string) error {
+	_, err := c.run(c.ctx, "branch", branchName, sha)
+	if err != nil {
+		return fmt.Errorf("failed to create local branch %s at %s: %w", branchName, sha, err)
+	}
+	return nil
+}
+
+func (c *defaultGitClient) pushBranch(remote, branchName, sha string) error {
+	return c.pushRef(remote, sha, "refs/heads/"+branchName)
+}
+
+func (c *defaultGitClient) updateRef(ref, sha string) error {
+	_, err := c.run(c.ctx, "update-ref", ref, sha)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", ref, err)
+	}
+	return nil
+}
+
+func (c *defaultGitClient) deleteRef(ref string) error {
+	_, err := c.run(c.ctx, "update-ref", "-d", ref)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", ref, err)
+	}
+	return nil
+}
+
+func (c *defaultGitClient) pushRef(remote, sha, ref string) error {
+	_, err := c.run(c.ctx, "push", remote, sha+":"+ref)
+	if err != nil {
+		return fmt.Errorf("failed to push %s to %s %s: %w", sha, remote, ref, err)
+	}
+	return nil
+}
+
+func (c *defaultGitClient) deleteRemoteRef(remote, ref string) error {
+	_, err := c.run(c.ctx, "push", remote, "--delete", ref)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s on %s: %w", ref, remote, err)
+	}
+	return nil
+}
+
+// listRefs returns every local ref under prefix mapped to the object it points at
+func (c *defaultGitClient) listRefs(prefix string) (map[string]string, error) {
+	output, err := c.run(c.ctx, "for-each-ref", "--format=%(objectname) %(refname)", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs under %s: %w", prefix, err)
+	}
+	return parseRefList(output), nil
+}
+
+// listRemoteRefs is the ls-remote counterpart of listRefs
+func (c *defaultGitClient) listRemoteRefs(remote, prefix string) (map[string]string, error) {
+	output, err := c.run(c.ctx, "ls-remote", remote, prefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs under %s on %s: %w", prefix, remote, err)
+	}
+	return parseRefList(output), nil
+}
+
+func parseRefList(output string) map[string]string {
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		refs[fields[1]] = fields[0]
+	}
+	return refs
+}
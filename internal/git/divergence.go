@@ -0,0 +1,176 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abey/clean-git/internal/pool"
+)
+
+// Divergence counts the commits a branch and a base branch do not share.
+type Divergence struct {
+	Base string
+	// Ahead counts commits of the branch missing from Base, Behind the reverse
+	Ahead  int
+	Behind int
+}
+
+// getAheadBehind counts every branch against baseRef in a single for-each-ref
+// call. %(ahead-behind) needs git 2.41; older versions fail and callers fall
+// back to countAheadBehind.
+func (c *defaultGitClient) getAheadBehind(baseRef string) (string, error) {
+	output, err := c.run(c.ctx, "for-each-ref", "--format=%(refname)%00%(ahead-behind:"+baseRef+")", "refs/heads", "refs/remotes")
+	if err != nil {
+		return "", fmt.Errorf("failed to count commits against %s: %w", baseRef, err)
+	}
+	return output, nil
+}
+
+func (c *defaultGitClient) countAheadBehind(ref, baseRef string) (int, int, error) {
+	output, err := c.run(c.ctx, "rev-list", "--left-right", "--count", ref+"..."+baseRef)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare %s with %s: %w", ref, baseRef, err)
+	}
+	return parseCounts(output, "\t")
+}
+
+// countUniqueCommits counts the commits of ref that are in none of baseRefs.
+func (c *defaultGitClient) countUniqueCommits(ref string, baseRefs []string) (int, error) {
+	args := append([]string{"rev-list", "--count", ref, "--not"}, baseRefs...)
+	output, err := c.run(c.ctx, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unique commits of %s: %w", ref, err)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected rev-list output: %q", output)
+	}
+	return count, nil
+}
+
+// parseCounts reads the "ahead<sep>behind" pairs rev-list --left-right and
+// %(ahead-behind) print.
+func parseCounts(counts, sep string) (int, int, error) {
+	fields := strings.Split(strings.TrimSpace(counts), sep)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected ahead/behind counts: %q", counts)
+	}
+	ahead, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected ahead/behind counts: %q", counts)
+	}
+	behind, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected ahead/behind counts: %q", counts)
+	}
+	return ahead, behind, nil
+}
+
+// parseAheadBehind maps each ref getAheadBehind listed to its counts.
+func parseAheadBehind(output string) (map[string][2]int, error) {
+	counts := make(map[string][2]int)
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected ahead-behind format: %q", line)
+		}
+		ahead, behind, err := parseCounts(fields[1], " ")
+		if err != nil {
+			return nil, err
+		}
+		counts[fields[0]] = [2]int{ahead, behind}
+	}
+	return counts, nil
+}
+
+// AddDivergence fills in Divergence and UniqueCommits of branches against
+// bases, which must exist. Each base is counted for all branches at once when
+// git supports it and per branch otherwise.
+func (s *DefaultBranchService) AddDivergence(branches []Branch, bases []string) error {
+	bulk := make(map[string]map[string][2]int)
+	for _, base := range bases {
+		if output, err := s.Client.getAheadBehind(base); err == nil {
+			if counts, err := parseAheadBehind(output); err == nil {
+				bulk[base] = counts
+			}
+		}
+	}
+
+	errs := make([]error, len(branches))
+	pool.Run(s.ctx, s.Jobs, len(branches), func(i int) {
+		branch := &branches[i]
+		ref := branchRef(branch, s.RemoteName)
+		branch.Divergence = make([]Divergence, 0, len(bases))
+		for _, base := range bases {
+			counts, ok := bulk[base][ref]
+			if !ok {
+				ahead, behind, err := s.Client.countAheadBehind(ref, base)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				counts = [2]int{ahead, behind}
+			}
+			branch.Divergence = append(branch.Divergence, Divergence{Base: base, Ahead: counts[0], Behind: counts[1]})
+		}
+
+		switch len(bases) {
+		case 0:
+		case 1:
+			branch.UniqueCommits = branch.Divergence[0].Ahead
+		default:
+			branch.UniqueCommits, errs[i] = s.Client.countUniqueCommits(ref, bases)
+		}
+	})
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+func (s *TestableBranchService) AddDivergence(branches []Branch, bases []string) error {
+	bulk := make(map[string]map[string][2]int)
+	for _, base := range bases {
+		if output, err := s.client.GetAheadBehind(base); err == nil {
+			if counts, err := parseAheadBehind(output); err == nil {
+				bulk[base] = counts
+			}
+		}
+	}
+
+	errs := make([]error, len(branches))
+	pool.Run(s.ctx, s.Jobs, len(branches), func(i int) {
+		branch := &branches[i]
+		ref := branchRef(branch, s.RemoteName)
+		branch.Divergence = make([]Divergence, 0, len(bases))
+		for _, base := range bases {
+			counts, ok := bulk[base][ref]
+			if !ok {
+				ahead, behind, err := s.client.CountAheadBehind(ref, base)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				counts = [2]int{ahead, behind}
+			}
+			branch.Divergence = append(branch.Divergence, Divergence{Base: base, Ahead: counts[0], Behind: counts[1]})
+		}
+
+		switch len(bases) {
+		case 0:
+		case 1:
+			branch.UniqueCommits = branch.Divergence[0].Ahead
+		default:
+			branch.UniqueCommits, errs[i] = s.client.CountUniqueCommits(ref, bases)
+		}
+	})
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for the git failures callers react to. A *CommandError
+// matches one of them with errors.Is when git's stderr says so.
+var (
+	// ErrBranchNotFound is a branch that does not exist, locally or on the remote
+	ErrBranchNotFound = errors.New("branch not found")
+	// ErrNotFullyMerged is git branch -d refusing a branch it cannot see merged
+	ErrNotFullyMerged = errors.New("branch is not fully merged")
+	// ErrCheckedOutInWorktree is a branch checked out in another worktree
+	ErrCheckedOutInWorktree = errors.New("branch is checked out in a worktree")
+	// ErrRemoteRejected is a push the remote refused, e.g. because a lease no longer held
+	ErrRemoteRejected = errors.New("remote rejected the push")
+	// ErrProtectedByRemote is a push refused because the remote protects the branch
+	ErrProtectedByRemote = errors.New("branch is protected by the remote")
+	// ErrAuthFailed is a remote that could not be authenticated with
+	ErrAuthFailed = errors.New("authentication with the remote failed")
+)
+
+// CommandError is a git command that exited with an error. It unwraps to the
+// sentinel error its stderr was classified as, if any, and to the error
+// exec returned.
+type CommandError struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+	Duration time.Duration
+	// Kind is the sentinel error stderr was classified as, nil if none matched
+	Kind error
+	Err  error
+}
+
+func (e *CommandError) Error() string {
+	message := fmt.Sprintf("git %s exited with status %d", strings.Join(e.Args, " "), e.ExitCode)
+	if detail := e.detail(); detail != "" {
+		message += ": " + detail
+	}
+	return message
+}
+
+func (e *CommandError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// detail is the most telling line of stderr: a rejected ref of a push, else
+// the first error or fatal line, else the first line that is not blank.
+func (e *CommandError) detail() string {
+	var errorLine, first string
+	for _, line := range strings.Split(e.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "! "):
+			return strings.Join(strings.Fields(line[2:]), " ")
+		case errorLine == "" && (strings.HasPrefix(line, "error: ") || strings.HasPrefix(line, "fatal: ")):
+			errorLine = line[len("error: "):]
+		}
+		if first == "" {
+			first = line
+		}
+	}
+	if errorLine != "" {
+		return errorLine
+	}
+	return first
+}
+
+// stderrKinds maps what git and common hosting services print to the
+// sentinel errors, checked in order: a protected branch is also reported as
+// a rejected push, so protection comes first.
+var stderrKinds = []struct {
+	kind      error
+	fragments []string
+}{
+	{ErrAuthFailed, []string{
+		"authentication failed",
+		"permission denied (publickey",
+		"could not read username",
+		"could not read password",
+		"terminal prompts disabled",
+		"the requested url returned error: 401",
+		"the requested url returned error: 403",
+	}},
+	{ErrProtectedByRemote, []string{
+		"protected branch",
+		"gh006",
+		"deletion prohibited",
+		"deletion of the current branch prohibited",
+	}},
+	{ErrCheckedOutInWorktree, []string{
+		"checked out at",
+		"used by worktree at",
+	}},
+	{ErrNotFullyMerged, []string{
+		"not fully merged",
+	}},
+	{ErrBranchNotFound, []string{
+		"' not found",
+		"remote ref does not exist",
+	}},
+	{ErrRemoteRejected, []string{
+		"[rejected]",
+		"[remote rejected]",
+		"failed to push some refs",
+	}},
+}
+
+// classifyStderr returns the sentinel error stderr describes, or nil.
+func classifyStderr(stderr string) error {
+	lower := strings.ToLower(stderr)
+	for _, candidate := range stderrKinds {
+		for _, fragment := range candidate.fragments {
+			if strings.Contains(lower, fragment) {
+				return candidate.kind
+			}
+		}
+	}
+	return nil
+}
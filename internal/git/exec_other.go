@@ -0,0 +1,8 @@
+//go:build !unix
+
+package git
+
+import "os/exec"
+
+// detach is a no-op where Ctrl-C is not delivered to a process group.
+func detach(cmd *exec.Cmd) {}
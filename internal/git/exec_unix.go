@@ -0,0 +1,21 @@
+//go:build unix
+
+package git
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach moves cmd into a session of its own so the SIGINT a terminal sends
+// on Ctrl-C only reaches clean-git, which lets running commands finish.
+// Without a controlling terminal ssh fails at once instead of stopping on a
+// passphrase prompt it cannot read. When the command is cancelled the whole
+// group is killed, including the hooks, credential helpers and ssh processes
+// git started.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,154 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fetchPrune fetches remote and drops the remote-tracking branches it no longer has.
+func (c *defaultGitClient) fetchPrune(remote string) error {
+	_, err := c.run(c.ctx, "fetch", "--prune", remote)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", remote, err)
+	}
+	return nil
+}
+
+// gitPath resolves name inside the git directory. In a linked worktree that
+// is .git/worktrees/<name>, which has a FETCH_HEAD of its own.
+func (c *defaultGitClient) gitPath(name string) (string, error) {
+	output, err := c.run(c.ctx, "rev-parse", "--path-format=absolute", "--git-path", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate %s: %w", name, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// gitCommonDir is the git directory shared by every worktree of the repository.
+func (c *defaultGitClient) gitCommonDir() (string, error) {
+	output, err := c.run(c.ctx, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the git directory: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// remoteRefPrefix is where the remote-tracking branches of remote live.
+func remoteRefPrefix(remote string) string {
+	return "refs/remotes/" + remote + "/"
+}
+
+// prunedRefs returns the short names (origin/feature/x) of the refs in before
+// that are missing from after, sorted.
+func prunedRefs(before, after map[string]string) []string {
+	var pruned []string
+	for ref := range before {
+		if _, ok := after[ref]; !ok {
+			pruned = append(pruned, strings.TrimPrefix(ref, "refs/remotes/"))
+		}
+	}
+	sort.Strings(pruned)
+	return pruned
+}
+
+// fetchHeadTime is the newest modification time of the FETCH_HEADs at paths,
+// which git rewrites on every fetch; the zero time when none exists. A fetch
+// writes the FETCH_HEAD of the worktree it ran in, so a linked worktree
+// checks its own and the main repository's.
+func fetchHeadTime(paths ...string) (time.Time, error) {
+	var newest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
+
+// FetchRemotes runs `git fetch --prune` for every configured remote and
+// returns the remote-tracking branches that were pruned. A remote that fails
+// to fetch does not stop the others; the errors are returned together.
+func (s *DefaultBranchService) FetchRemotes() ([]string, error) {
+	var pruned []string
+	var errs []error
+	for _, remote := range s.Remotes {
+		before, err := s.Client.listRefs(remoteRefPrefix(remote.Name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.Client.fetchPrune(remote.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		after, err := s.Client.listRefs(remoteRefPrefix(remote.Name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pruned = append(pruned, prunedRefs(before, after)...)
+	}
+	return pruned, errors.Join(errs...)
+}
+
+// LastFetch returns when the repository was last fetched, or the zero time
+// if it never was.
+func (s *DefaultBranchService) LastFetch() (time.Time, error) {
+	path, err := s.Client.gitPath("FETCH_HEAD")
+	if err != nil {
+		return time.Time{}, err
+	}
+	commonDir, err := s.Client.gitCommonDir()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fetchHeadTime(path, filepath.Join(commonDir, "FETCH_HEAD"))
+}
+
+func (s *TestableBranchService) FetchRemotes() ([]string, error) {
+	var pruned []string
+	var errs []error
+	for _, remote := range s.Remotes {
+		before, err := s.client.ListRefs(remoteRefPrefix(remote.Name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.client.FetchPrune(remote.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		after, err := s.client.ListRefs(remoteRefPrefix(remote.Name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pruned = append(pruned, prunedRefs(before, after)...)
+	}
+	return pruned, errors.Join(errs...)
+}
+
+func (s *TestableBranchService) LastFetch() (time.Time, error) {
+	path, err := s.client.GitPath("FETCH_HEAD")
+	if err != nil {
+		return time.Time{}, err
+	}
+	commonDir, err := s.client.GitCommonDir()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fetchHeadTime(path, filepath.Join(commonDir, "FETCH_HEAD"))
+}
@@ -0,0 +1,164 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abey/clean-git/internal/pool"
+)
+
+// cherryCounts compares the commits unique to branchRef with baseRef by
+// patch-id (via `git cherry`). total is the number of commits not reachable
+// from baseRef and upstream how many of those already have an equivalent there.
+func (c *defaultGitClient) cherryCounts(branchRef, baseRef string) (int, int, error) {
+	output, err := c.run(c.ctx, "cherry", baseRef, branchRef)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare %s with %s: %w", branchRef, baseRef, err)
+	}
+	return parseCherryCounts(output)
+}
+
+func parseCherryCounts(output string) (int, int, error) {
+	var upstream, total int
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '-':
+			upstream++
+		case '+':
+		default:
+			return 0, 0, fmt.Errorf("unexpected git cherry output: %s", line)
+		}
+		total++
+	}
+	return upstream, total, nil
+}
+
+type patchEquivalence struct {
+	name     string
+	upstream int
+	total    int
+}
+
+func (p patchEquivalence) fullyUpstream() bool {
+	return p.total > 0 && p.upstream == p.total
+}
+
+func (p patchEquivalence) partiallyUpstream() bool {
+	return p.upstream > 0 && p.upstream < p.total
+}
+
+// bestPatchEquivalence keeps whichever base (local or remote) has more of the branch's commits.
+func bestPatchEquivalence(current, candidate patchEquivalence, found bool) patchEquivalence {
+	if !found || candidate.upstream > current.upstream {
+		return candidate
+	}
+	return current
+}
+
+func (s *DefaultBranchService) findPatchEquivalents(mergedNames []string, baseBranch string, remoteBases []string) ([]patchEquivalence, error) {
+	allNames, err := s.Client.getAllBranchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebase detection only looks at local branches
+	candidates := squashCandidates(allNames, mergedNames, baseBranch, nil)
+	bests := make([]patchEquivalence, len(candidates))
+	found := make([]bool, len(candidates))
+	pool.Run(s.ctx, s.Jobs, len(candidates), func(i int) {
+		for _, base := range append([]string{baseBranch}, remoteBases...) {
+			// A base that cannot be compared (e.g. missing remote branch) is skipped
+			upstream, total, err := s.Client.cherryCounts("refs/heads/"+candidates[i], base)
+			if err != nil {
+				continue
+			}
+			bests[i] = bestPatchEquivalence(bests[i], patchEquivalence{name: candidates[i], upstream: upstream, total: total}, found[i])
+			found[i] = true
+		}
+	})
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []patchEquivalence
+	for i := range candidates {
+		if found[i] {
+			results = append(results, bests[i])
+		}
+	}
+	return results, nil
+}
+
+func (s *TestableBranchService) findPatchEquivalents(mergedNames []string, baseBranch string, remoteBases []string) ([]patchEquivalence, error) {
+	allNames, err := s.client.GetAllBranchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebase detection only looks at local branches
+	candidates := squashCandidates(allNames, mergedNames, baseBranch, nil)
+	bests := make([]patchEquivalence, len(candidates))
+	found := make([]bool, len(candidates))
+	pool.Run(s.ctx, s.Jobs, len(candidates), func(i int) {
+		for _, base := range append([]string{baseBranch}, remoteBases...) {
+			upstream, total, err := s.client.CherryCounts("refs/heads/"+candidates[i], base)
+			if err != nil {
+				continue
+			}
+			bests[i] = bestPatchEquivalence(bests[i], patchEquivalence{name: candidates[i], upstream: upstream, total: total}, found[i])
+			found[i] = true
+		}
+	})
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []patchEquivalence
+	for i := range candidates {
+		if found[i] {
+			results = append(results, bests[i])
+		}
+	}
+	return results, nil
+}
+
+// partialBranches returns the branches of equivalents where some, but not
+// all, unique commits are upstream, with their commit counts.
+func partialBranches(snapshot map[string]Branch, equivalents []patchEquivalence, lookup func(map[string]Branch, string) (*Branch, error)) []Branch {
+	var branches []Branch
+	for _, equivalence := range equivalents {
+		if !equivalence.partiallyUpstream() {
+			continue
+		}
+		branch, err := lookup(snapshot, equivalence.name)
+		if err != nil {
+			continue
+		}
+		branch.CommitsUpstream = equivalence.upstream
+		branch.CommitsUnique = equivalence.total
+		branches = append(branches, *branch)
+	}
+	return branches
+}
+
+// GetPartiallyMergedBranches returns local branches where some, but not all,
+// unique commits already have a patch-equivalent commit in baseBranch.
+func (s *DefaultBranchService) GetPartiallyMergedBranches(baseBranch string) ([]Branch, error) {
+	equivalents, err := s.findPatchEquivalents(nil, baseBranch, remoteBasesFor(baseBranch, s.Remotes))
+	if err != nil {
+		return nil, err
+	}
+	return partialBranches(s.branchSnapshot(), equivalents, s.branchFromSnapshot), nil
+}
+
+func (s *TestableBranchService) GetPartiallyMergedBranches(baseBranch string) ([]Branch, error) {
+	equivalents, err := s.findPatchEquivalents(nil, baseBranch, remoteBasesFor(baseBranch, s.Remotes))
+	if err != nil {
+		return nil, err
+	}
+	return partialBranches(s.branchSnapshot(), equivalents, s.branchFromSnapshot), nil
+}
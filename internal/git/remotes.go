@@ -0,0 +1,198 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Remote is a configured remote and what clean-git may do with it.
+type Remote struct {
+	Name string
+	// Push marks a remote we own: its branches are listed and may be deleted
+	Push bool
+	// MergeSource marks a remote whose base branches count for merge status
+	MergeSource bool
+}
+
+// ErrRemoteNotOwned is a deletion or archive aimed at a remote that is not
+// configured as a push remote.
+var ErrRemoteNotOwned = errors.New("remote is not a push remote")
+
+// remotesOrDefault returns remotes, or remoteName in both roles when none are configured.
+func remotesOrDefault(remotes []Remote, remoteName string) []Remote {
+	if len(remotes) > 0 {
+		return remotes
+	}
+	return []Remote{{Name: remoteOrDefault("", remoteName), Push: true, MergeSource: true}}
+}
+
+// firstPushRemote is the remote branches default to when none is given.
+func firstPushRemote(remotes []Remote) string {
+	for _, remote := range remotes {
+		if remote.Push {
+			return remote.Name
+		}
+	}
+	return ""
+}
+
+func remoteNames(remotes []Remote, keep func(Remote) bool) []string {
+	var names []string
+	for _, remote := range remotes {
+		if keep(remote) {
+			names = append(names, remote.Name)
+		}
+	}
+	return names
+}
+
+func isMergeSourceRemote(remote Remote) bool { return remote.MergeSource }
+func isAnyRemote(Remote) bool                { return true }
+
+// findRemote returns the configured remote called name.
+func findRemote(remotes []Remote, name string) (Remote, bool) {
+	for _, remote := range remotes {
+		if remote.Name == name {
+			return remote, true
+		}
+	}
+	return Remote{}, false
+}
+
+// splitRemoteBranch splits a `git branch --all` style name such as
+// "remotes/upstream/feature/x" into its remote and branch, for configured
+// remotes only. The longest configured remote name that prefixes the rest
+// wins, since git allows "/" in remote names and "team/fork/x" could be
+// branch "fork/x" of remote "team" or branch "x" of remote "team/fork".
+func splitRemoteBranch(name string, remotes []Remote) (remote, branch string, ok bool) {
+	rest, isRemote := strings.CutPrefix(name, "remotes/")
+	if !isRemote {
+		return "", "", false
+	}
+	for _, candidate := range remotes {
+		after, matches := strings.CutPrefix(rest, candidate.Name+"/")
+		if !matches || after == "" || len(candidate.Name) <= len(remote) {
+			continue
+		}
+		remote, branch, ok = candidate.Name, after, true
+	}
+	return remote, branch, ok
+}
+
+// remoteBasesFor returns the remote-tracking names of baseBranch on every
+// merge-source remote (e.g. origin/main, upstream/main).
+func remoteBasesFor(baseBranch string, remotes []Remote) []string {
+	var bases []string
+	for _, name := range remoteNames(remotes, isMergeSourceRemote) {
+		bases = append(bases, name+"/"+baseBranch)
+	}
+	return bases
+}
+
+// ownedMergedNames drops branches clean-git must not touch from merged: the
+// base itself on any remote, and branches of remotes that are not push
+// remotes or not configured at all.
+func ownedMergedNames(merged []string, baseBranch string, remotes []Remote) []string {
+	var owned []string
+	for _, name := range merged {
+		if strings.Contains(name, " -> ") {
+			continue
+		}
+		if strings.HasPrefix(name, "remotes/") {
+			remote, branch, ok := splitRemoteBranch(name, remotes)
+			if !ok || branch == baseBranch || branch == "HEAD" {
+				continue
+			}
+			if owner, _ := findRemote(remotes, remote); !owner.Push {
+				continue
+			}
+		}
+		owned = append(owned, name)
+	}
+	return owned
+}
+
+// unionNames appends the names of more that are not in names yet.
+func unionNames(names, more []string) []string {
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+	for _, name := range more {
+		if !existing[name] {
+			existing[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// pushRemoteBranchNames returns the `git branch --all` names of every branch
+// on a push remote.
+func pushRemoteBranchNames(allNames []string, remotes []Remote) []string {
+	var names []string
+	for _, name := range allNames {
+		remote, branch, ok := splitRemoteBranch(name, remotes)
+		if !ok || branch == "HEAD" || strings.Contains(name, " -> ") {
+			continue
+		}
+		if owner, _ := findRemote(remotes, remote); owner.Push {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// trackedBranchNames splits `git branch --all` names into local branches and
+// the branches of push remotes that have a local branch of the same name.
+func trackedBranchNames(allNames []string, remotes []Remote) (local, tracked []string) {
+	local = localBranchNames(allNames)
+	localSet := make(map[string]bool, len(local))
+	for _, name := range local {
+		localSet[name] = true
+	}
+	for _, name := range pushRemoteBranchNames(allNames, remotes) {
+		if _, branch, _ := splitRemoteBranch(name, remotes); localSet[branch] {
+			tracked = append(tracked, name)
+		}
+	}
+	return local, tracked
+}
+
+// branchRemotes maps each branch name to the configured remotes it exists on,
+// in the order the remotes are configured.
+func branchRemotes(allNames []string, remotes []Remote) map[string][]string {
+	found := make(map[string]map[string]bool)
+	for _, name := range allNames {
+		remote, branch, ok := splitRemoteBranch(name, remotes)
+		if !ok || branch == "HEAD" {
+			continue
+		}
+		if found[branch] == nil {
+			found[branch] = make(map[string]bool)
+		}
+		found[branch][remote] = true
+	}
+
+	result := make(map[string][]string, len(found))
+	for branch, on := range found {
+		for _, remote := range remotes {
+			if on[remote.Name] {
+				result[branch] = append(result[branch], remote.Name)
+			}
+		}
+	}
+	return result
+}
+
+// checkOwnedRemote refuses remote branches on remotes we do not push to.
+func checkOwnedRemote(branch *Branch, remotes []Remote) error {
+	if !branch.IsRemote {
+		return nil
+	}
+	if remote, ok := findRemote(remotes, branch.Remote); ok && remote.Push {
+		return nil
+	}
+	return fmt.Errorf("refusing to change %s/%s: %w", branch.Remote, branch.Name, ErrRemoteNotOwned)
+}
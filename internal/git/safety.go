@@ -0,0 +1,170 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+)
+
+// UnsafeDeleteError is returned when a local branch may hold work that
+// would be lost by deleting it. Only ForceDeleteBranch overrides it.
+type UnsafeDeleteError struct {
+	Branch string
+	Reason string
+	Err    error
+}
+
+func (e *UnsafeDeleteError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("refusing to delete %s: %s: %v", e.Branch, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("refusing to delete %s: %s", e.Branch, e.Reason)
+}
+
+func (e *UnsafeDeleteError) Unwrap() error {
+	return e.Err
+}
+
+// StaleTipError is returned when a branch no longer points at the tip it was
+// selected with (Branch.ExpectedSHA), e.g. because someone pushed to it since.
+type StaleTipError struct {
+	Branch   string
+	Expected string
+	Actual   string
+}
+
+func (e *StaleTipError) Error() string {
+	return fmt.Sprintf("refusing to delete %s: tip moved from %s to %s", e.Branch, e.Expected, e.Actual)
+}
+
+// checkLease compares the branch with its ExpectedSHA. Remote branches are
+// compared through their remote-tracking ref here and again by the remote
+// when they are deleted.
+func (s *DefaultBranchService) checkLease(branch *Branch) error {
+	if branch.ExpectedSHA == "" {
+		return nil
+	}
+	sha, err := s.ResolveBranchSHA(branch)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", branch.Name, err)
+	}
+	if sha != branch.ExpectedSHA {
+		return &StaleTipError{Branch: branch.Name, Expected: branch.ExpectedSHA, Actual: sha}
+	}
+	return nil
+}
+
+// CheckDeleteSafety runs the checks that do not modify the repository, so
+// callers can report refusals up front (e.g. in dry-run mode).
+func (s *DefaultBranchService) CheckDeleteSafety(branch *Branch) error {
+	if branch.IsRemote {
+		return nil
+	}
+	if branch.HasUnpushedCommits {
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: "has commits not pushed to its upstream"}
+	}
+
+	contains, hasUpstream, err := s.Client.upstreamContains(branch.Name)
+	if err != nil {
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: "could not compare with its upstream", Err: err}
+	}
+	if hasUpstream && !contains {
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: "tip is not reachable from its upstream"}
+	}
+	return nil
+}
+
+// ForceDeleteBranch skips every safety check. Callers must only use it on
+// explicit user request or when the branch's commits are preserved elsewhere.
+func (s *DefaultBranchService) ForceDeleteBranch(branch *Branch) error {
+	if branch.IsRemote {
+		return s.DeleteBranch(branch)
+	}
+	if err := s.checkLease(branch); err != nil {
+		return err
+	}
+	return s.deleteLocalBranch(branch, true)
+}
+
+// deleteLocalBranch runs git branch -d, or -D with force. A leased branch is
+// deleted with a compare-and-delete instead, and a tip that moved after
+// checkLease is still reported as a *StaleTipError.
+func (s *DefaultBranchService) deleteLocalBranch(branch *Branch, force bool) error {
+	switch {
+	case branch.ExpectedSHA != "":
+		err := s.Client.deleteLocalBranchAt(branch.Name, branch.ExpectedSHA, force)
+		if err != nil {
+			if leaseErr := s.checkLease(branch); isStaleTip(leaseErr) {
+				return leaseErr
+			}
+		}
+		return err
+	case force:
+		return s.Client.forceDeleteLocalBranch(branch.Name)
+	default:
+		return s.Client.deleteLocalBranch(branch.Name)
+	}
+}
+
+func isStaleTip(err error) bool {
+	var staleErr *StaleTipError
+	return errors.As(err, &staleErr)
+}
+
+func (s *TestableBranchService) checkLease(branch *Branch) error {
+	if branch.ExpectedSHA == "" {
+		return nil
+	}
+	sha, err := s.ResolveBranchSHA(branch)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", branch.Name, err)
+	}
+	if sha != branch.ExpectedSHA {
+		return &StaleTipError{Branch: branch.Name, Expected: branch.ExpectedSHA, Actual: sha}
+	}
+	return nil
+}
+
+func (s *TestableBranchService) CheckDeleteSafety(branch *Branch) error {
+	if branch.IsRemote {
+		return nil
+	}
+	if branch.HasUnpushedCommits {
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: "has commits not pushed to its upstream"}
+	}
+
+	contains, hasUpstream, err := s.client.UpstreamContains(branch.Name)
+	if err != nil {
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: "could not compare with its upstream", Err: err}
+	}
+	if hasUpstream && !contains {
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: "tip is not reachable from its upstream"}
+	}
+	return nil
+}
+
+func (s *TestableBranchService) ForceDeleteBranch(branch *Branch) error {
+	if branch.IsRemote {
+		return s.DeleteBranch(branch)
+	}
+	if err := s.checkLease(branch); err != nil {
+		return err
+	}
+	return s.deleteLocalBranch(branch, true)
+}
+
+func (s *TestableBranchService) deleteLocalBranch(branch *Branch, force bool) error {
+	switch {
+	case branch.ExpectedSHA != "":
+		err := s.client.DeleteLocalBranchAt(branch.Name, branch.ExpectedSHA, force)
+		if err != nil {
+			if leaseErr := s.checkLease(branch); isStaleTip(leaseErr) {
+				return leaseErr
+			}
+		}
+		return err
+	case force:
+		return s.client.ForceDeleteLocalBranch(branch.Name)
+	default:
+		return s.client.DeleteLocalBranch(branch.Name)
+	}
+}
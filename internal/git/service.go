@@ -1,6 +1,8 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -10,11 +12,27 @@ import (
 type BranchService interface {
 	GetCurrentBranch() (*Branch, error)
 	GetMergedBranches(baseBranch string) ([]Branch, error)
+	GetPartiallyMergedBranches(baseBranch string) ([]Branch, error)
+	GetMergeStatus(baseBranch string) (merged, partial []Branch, err error)
+	GetGoneBranches() ([]Branch, error)
 	GetBranchesWithTrackedRemotes() ([]Branch, error)
+	GetRemoteBranches() ([]Branch, error)
 	GetBranchByName(branchName string) (*Branch, error)
+	GetBranchLog(branch *Branch, limit int) (string, error)
+	AddDivergence(branches []Branch, bases []string) error
 	DeleteBranch(branch *Branch) error
+	ForceDeleteBranch(branch *Branch) error
+	CheckDeleteSafety(branch *Branch) error
 	IsProtectedBranch(branch *Branch, patterns []string) bool
 	BranchExists(branchName string) (bool, error)
+	ResolveBranchSHA(branch *Branch) (string, error)
+	RestoreBranch(branchName, sha string) error
+	PushBranch(remote, branchName, sha string) error
+	ArchiveBranch(branch *Branch, archiveRef string, toRemote bool) error
+	ListArchivedBranches(remote string) ([]ArchivedRef, error)
+	DeleteArchivedBranch(archived ArchivedRef) error
+	FetchRemotes() (pruned []string, err error)
+	LastFetch() (time.Time, error)
 }
 
 type TestableGitClient interface {
@@ -22,34 +40,128 @@ type TestableGitClient interface {
 	GetMergedBranchNames(baseBranch string) ([]string, error)
 	GetAllBranchNames() ([]string, error)
 	GetBranchCommitInfo(branchName string) (string, error)
+	GetBranchLog(ref string, limit int) (string, error)
 	DeleteLocalBranch(branchName string) error
+	ForceDeleteLocalBranch(branchName string) error
+	DeleteLocalBranchAt(branchName, sha string, force bool) error
+	UpstreamContains(branchName string) (contains bool, hasUpstream bool, err error)
 	DeleteRemoteBranch(remote, branchName string) error
+	DeleteRemoteBranchWithLease(remote, branchName, sha string) error
+	GetAheadBehind(baseRef string) (string, error)
+	CountAheadBehind(ref, baseRef string) (ahead int, behind int, err error)
+	CountUniqueCommits(ref string, baseRefs []string) (int, error)
 	HasUnpushedCommits(branchName string) (bool, error)
-	BranchExists(branchName string) (bool, error)
+	BranchExists(branchName string, remotes []string) (bool, error)
+	ResolveRef(ref string) (string, error)
+	CreateLocalBranch(branchName, sha string) error
+	PushBranch(remote, branchName, sha string) error
+	UpdateRef(ref, sha string) error
+	DeleteRef(ref string) error
+	PushRef(remote, sha, ref string) error
+	DeleteRemoteRef(remote, ref string) error
+	ListRefs(prefix string) (map[string]string, error)
+	ListRemoteRefs(remote, prefix string) (map[string]string, error)
+	IsSquashMerged(branchRef, baseRef string) (bool, error)
+	CherryCounts(branchRef, baseRef string) (upstream int, total int, err error)
+	GetUpstreamInfo(branchName string) (upstream string, track string, err error)
+	GetBranchMetadata(patterns ...string) (string, error)
+	FetchPrune(remote string) error
+	GitPath(name string) (string, error)
+	GitCommonDir() (string, error)
 }
 
-type DefaultBranchService struct {
-	Client     gitClient
+// Options tunes how a BranchService classifies branches
+type Options struct {
 	RemoteName string
+	// Remotes lists the remotes and their roles; RemoteName in both roles when empty
+	Remotes []Remote
+	// DetectSquashMerges also reports branches whose combined diff is already in the base as merged
+	DetectSquashMerges bool
+	// DetectRebaseMerges also reports branches whose every commit has a patch-equivalent in the base as merged
+	DetectRebaseMerges bool
+	// Context stops git commands when cancelled; Timeout limits each one (no limit when zero)
+	Context context.Context
+	Timeout time.Duration
+	// Jobs is how many branches are compared with a base at the same time
+	Jobs int
+	// Prompt retries a push, fetch or ls-remote that failed authentication
+	// once in the foreground, so git and ssh can ask for credentials
+	Prompt bool
+}
+
+type DefaultBranchService struct {
+	Client             gitClient
+	RemoteName         string
+	Remotes            []Remote
+	DetectSquashMerges bool
+	DetectRebaseMerges bool
+	Jobs               int
+	ctx                context.Context
 }
 
 func NewBranchService(remoteName string) BranchService {
+	return NewBranchServiceWithOptions(Options{RemoteName: remoteName})
+}
+
+func NewBranchServiceWithOptions(opts Options) BranchService {
+	remotes := remotesOrDefault(opts.Remotes, opts.RemoteName)
 	return &DefaultBranchService{
-		Client:     newGitClient(),
-		RemoteName: remoteName,
+		Client:             newGitClient(opts.Context, opts.Timeout, opts.Prompt),
+		RemoteName:         remoteOrDefault(opts.RemoteName, firstPushRemote(remotes)),
+		Remotes:            remotes,
+		DetectSquashMerges: opts.DetectSquashMerges,
+		DetectRebaseMerges: opts.DetectRebaseMerges,
+		Jobs:               opts.Jobs,
+		ctx:                contextOrBackground(opts.Context),
 	}
 }
 
 func NewBranchServiceWithClient(client TestableGitClient, remoteName string) BranchService {
+	return NewBranchServiceWithClientOptions(client, Options{RemoteName: remoteName})
+}
+
+func NewBranchServiceWithClientOptions(client TestableGitClient, opts Options) BranchService {
+	remotes := remotesOrDefault(opts.Remotes, opts.RemoteName)
 	return &TestableBranchService{
-		client:     client,
-		RemoteName: remoteName,
+		client:             client,
+		RemoteName:         remoteOrDefault(opts.RemoteName, firstPushRemote(remotes)),
+		Remotes:            remotes,
+		DetectSquashMerges: opts.DetectSquashMerges,
+		DetectRebaseMerges: opts.DetectRebaseMerges,
+		Jobs:               opts.Jobs,
+		ctx:                contextOrBackground(opts.Context),
 	}
 }
 
 type TestableBranchService struct {
-	client     TestableGitClient
-	RemoteName string
+	client             TestableGitClient
+	RemoteName         string
+	Remotes            []Remote
+	DetectSquashMerges bool
+	DetectRebaseMerges bool
+	Jobs               int
+	ctx                context.Context
+}
+
+// safeDeleteReason explains why git branch -d refused a branch.
+func safeDeleteReason(err error) string {
+	if errors.Is(err, ErrNotFullyMerged) {
+		return "git does not see it merged"
+	}
+	return "safe delete failed"
+}
+
+// stoppedEarly reports whether err comes from a git command that was
+// interrupted or timed out, which says nothing about the branch itself.
+func stoppedEarly(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
 }
 
 func (s *DefaultBranchService) GetCurrentBranch() (*Branch, error) {
@@ -61,40 +173,72 @@ func (s *DefaultBranchService) GetCurrentBranch() (*Branch, error) {
 }
 
 func (s *DefaultBranchService) GetMergedBranches(baseBranch string) ([]Branch, error) {
+	merged, _, err := s.GetMergeStatus(baseBranch)
+	return merged, err
+}
+
+// GetMergeStatus returns the branches merged into baseBranch and, with rebase
+// detection on, the local branches only partially landed there. Each branch
+// is compared with the base by patch-id once for both.
+func (s *DefaultBranchService) GetMergeStatus(baseBranch string) ([]Branch, []Branch, error) {
 	branchNames, err := s.Client.getMergedBranchNames(baseBranch)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	remoteName := s.RemoteName
-	if remoteName == "" {
-		remoteName = "origin"
+	// The base on a merge-source remote may be ahead of the local one, e.g. in
+	// a fork whose main is only synced with upstream now and then
+	remoteBases := remoteBasesFor(baseBranch, s.Remotes)
+	for _, remoteBase := range remoteBases {
+		if remoteMerged, err := s.Client.getMergedBranchNames(remoteBase); err == nil {
+			branchNames = unionNames(branchNames, remoteMerged)
+		}
 	}
-	remoteBase := fmt.Sprintf("%s/%s", remoteName, baseBranch)
-	remoteMerged, err := s.Client.getMergedBranchNames(remoteBase)
-	if err == nil {
-		existing := make(map[string]bool)
-		for _, name := range branchNames {
-			existing[name] = true
+	branchNames = ownedMergedNames(branchNames, baseBranch, s.Remotes)
+
+	mergeMethods := make(map[string]MergeMethod)
+	for _, name := range branchNames {
+		mergeMethods[name] = MergeMethodMerge
+	}
+
+	var equivalents []patchEquivalence
+	if s.DetectRebaseMerges {
+		equivalents, err = s.findPatchEquivalents(branchNames, baseBranch, remoteBases)
+		if err != nil {
+			return nil, nil, err
 		}
-		for _, name := range remoteMerged {
-			if !existing[name] {
-				branchNames = append(branchNames, name)
+		for _, equivalence := range equivalents {
+			if equivalence.fullyUpstream() {
+				branchNames = append(branchNames, equivalence.name)
+				mergeMethods[equivalence.name] = MergeMethodRebase
 			}
 		}
 	}
 
+	if s.DetectSquashMerges {
+		squashed, err := s.findSquashMerged(branchNames, baseBranch, remoteBases)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range squashed {
+			branchNames = append(branchNames, name)
+			mergeMethods[name] = MergeMethodSquash
+		}
+	}
+
+	snapshot := s.branchSnapshot()
 	var branches []Branch
 	for _, name := range branchNames {
-		branch, err := s.GetBranchByName(name)
+		branch, err := s.branchFromSnapshot(snapshot, name)
 		if err != nil {
 			continue
 		}
 		branch.IsMerged = true
+		branch.MergeMethod = mergeMethods[name]
 		branches = append(branches, *branch)
 	}
 
-	return branches, nil
+	return branches, partialBranches(snapshot, equivalents, s.branchFromSnapshot), nil
 }
 
 func (s *DefaultBranchService) GetBranchesWithTrackedRemotes() ([]Branch, error) {
@@ -103,71 +247,98 @@ func (s *DefaultBranchService) GetBranchesWithTrackedRemotes() ([]Branch, error)
 		return nil, err
 	}
 
-	var localBranches []string
-	var remoteBranches []string
-	localBranchSet := make(map[string]bool)
+	localNames, trackedNames := trackedBranchNames(branchNames, s.Remotes)
+	remotesByBranch := branchRemotes(branchNames, s.Remotes)
 
-	remoteName := s.RemoteName
-	if remoteName == "" {
-		remoteName = "origin"
-	}
-
-	for _, name := range branchNames {
-		if name == "origin/HEAD" {
+	snapshot := s.branchSnapshot()
+	var branches []Branch
+	for _, name := range append(localNames, trackedNames...) {
+		branch, err := s.branchFromSnapshot(snapshot, name)
+		if err != nil {
 			continue
 		}
+		branch.Remotes = remotesByBranch[branch.Name]
+		// Tracked remote branches have a local branch by definition
+		branch.HasLocal = branch.IsRemote
+		branches = append(branches, *branch)
+	}
 
-		if strings.HasPrefix(name, "remotes/"+remoteName+"/") {
-			remoteBranches = append(remoteBranches, name)
-		} else {
-			localBranches = append(localBranches, name)
-			localBranchSet[name] = true
-		}
+	return branches, nil
+}
+
+// GetRemoteBranches returns every branch of the push remotes, including the
+// ones without a local branch of the same name (HasLocal is false).
+func (s *DefaultBranchService) GetRemoteBranches() ([]Branch, error) {
+	allNames, err := s.Client.getAllBranchNames()
+	if err != nil {
+		return nil, err
 	}
 
-	var branches []Branch
+	localSet := make(map[string]bool)
+	for _, name := range localBranchNames(allNames) {
+		localSet[name] = true
+	}
+	remotesByBranch := branchRemotes(allNames, s.Remotes)
 
-	for _, name := range localBranches {
-		branch, err := s.createBranchFromName(name)
+	snapshot := s.branchSnapshot()
+	var branches []Branch
+	for _, name := range pushRemoteBranchNames(allNames, s.Remotes) {
+		branch, err := s.branchFromSnapshot(snapshot, name)
 		if err != nil {
 			continue
 		}
+		branch.Remotes = remotesByBranch[branch.Name]
+		branch.HasLocal = localSet[branch.Name]
 		branches = append(branches, *branch)
 	}
 
-	for _, remoteName := range remoteBranches {
-		branchName := strings.TrimPrefix(remoteName, "remotes/"+s.RemoteName+"/")
-		if s.RemoteName == "" {
-			branchName = strings.TrimPrefix(remoteName, "remotes/origin/")
-		}
-		if localBranchSet[branchName] {
-			branch, err := s.createBranchFromName(remoteName)
-			if err != nil {
-				continue
-			}
-			branches = append(branches, *branch)
-		}
-	}
-
 	return branches, nil
 }
 
 func (s *DefaultBranchService) GetBranchByName(branchName string) (*Branch, error) {
-	return s.createBranchFromName(branchName)
+	return s.branchFromSnapshot(s.branchSnapshot(branchRefPattern(branchName)), branchName)
+}
+
+func (s *DefaultBranchService) GetBranchLog(branch *Branch, limit int) (string, error) {
+	return s.Client.getBranchLog(branchRef(branch, s.RemoteName), limit)
 }
 
+// DeleteBranch removes a branch only if the safety checks pass and git's safe
+// delete succeeds, or the branch is known to have landed as a squash or rebase
+// merge. Everything else, gone upstreams included, is refused with an
+// *UnsafeDeleteError and needs ForceDeleteBranch.
 func (s *DefaultBranchService) DeleteBranch(branch *Branch) error {
+	if err := s.checkLease(branch); err != nil {
+		return err
+	}
 	if branch.IsRemote {
-		if branch.Remote == "" {
-			if s.RemoteName != "" {
-				branch.Remote = s.RemoteName
-			} else {
-				branch.Remote = "origin" // Fallback to origin when no remote is configured
-			}
+		branch.Remote = remoteOrDefault(branch.Remote, s.RemoteName)
+		if err := checkOwnedRemote(branch, s.Remotes); err != nil {
+			return err
+		}
+		if branch.ExpectedSHA != "" {
+			return s.Client.deleteRemoteBranchWithLease(branch.Remote, branch.Name, branch.ExpectedSHA)
 		}
 		return s.Client.deleteRemoteBranch(branch.Remote, branch.Name)
 	}
-	return s.Client.deleteLocalBranch(branch.Name)
+
+	if err := s.CheckDeleteSafety(branch); err != nil {
+		return err
+	}
+	if err := s.deleteLocalBranch(branch, false); err != nil {
+		// Forcing would not help with these, and they say nothing about unmerged work
+		if stoppedEarly(err) || isStaleTip(err) || errors.Is(err, ErrCheckedOutInWorktree) || errors.Is(err, ErrBranchNotFound) {
+			return err
+		}
+		// git only knows ancestry; squash and rebase merges were verified by
+		// content instead. A gone upstream proves nothing about the branch's
+		// commits, so those branches need --force like any other.
+		if branch.MergeMethod == MergeMethodSquash || branch.MergeMethod == MergeMethodRebase {
+			return s.ForceDeleteBranch(branch)
+		}
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: safeDeleteReason(err), Err: err}
+	}
+	return nil
 }
 
 func (s *DefaultBranchService) IsProtectedBranch(branch *Branch, patterns []string) bool {
@@ -184,22 +355,25 @@ func (s *DefaultBranchService) IsProtectedBranch(branch *Branch, patterns []stri
 }
 
 func (s *DefaultBranchService) BranchExists(branchName string) (bool, error) {
-	return s.Client.branchExists(branchName)
+	return s.Client.branchExists(branchName, remoteNames(s.Remotes, isAnyRemote))
 }
 
-func (s *DefaultBranchService) createBranchFromName(branchName string) (*Branch, error) {
-	remoteName := "origin"
-	if s.RemoteName != "" {
-		remoteName = s.RemoteName
-	}
+func (s *DefaultBranchService) ResolveBranchSHA(branch *Branch) (string, error) {
+	return s.Client.resolveRef(branchRef(branch, s.RemoteName))
+}
 
-	isRemote := strings.HasPrefix(branchName, "remotes/"+remoteName+"/")
-	actualName := branchName
-	remote := ""
+func (s *DefaultBranchService) RestoreBranch(branchName, sha string) error {
+	return s.Client.createLocalBranch(branchName, sha)
+}
 
-	if isRemote {
-		actualName = strings.TrimPrefix(branchName, "remotes/"+remoteName+"/")
-		remote = remoteName
+func (s *DefaultBranchService) PushBranch(remote, branchName, sha string) error {
+	return s.Client.pushBranch(remote, branchName, sha)
+}
+
+func (s *DefaultBranchService) createBranchFromName(branchName string) (*Branch, error) {
+	remote, actualName, isRemote := splitRemoteBranch(branchName, s.Remotes)
+	if !isRemote {
+		actualName = branchName
 	}
 
 	branchNameForCommitInfo := actualName
@@ -212,10 +386,15 @@ func (s *DefaultBranchService) createBranchFromName(branchName string) (*Branch,
 		return nil, fmt.Errorf("failed to get commit info for branch %s: %w", branchNameForCommitInfo, err)
 	}
 
-	parts := strings.Split(commitInfo, "|")
-	if len(parts) != 4 {
+	// The subject comes last as it may itself contain "|"
+	parts := strings.SplitN(commitInfo, "|", 5)
+	if len(parts) < 4 {
 		return nil, fmt.Errorf("unexpected commit info format for branch %s", actualName)
 	}
+	subject := ""
+	if len(parts) == 5 {
+		subject = parts[4]
+	}
 
 	commitDate, err := time.Parse("2006-01-02 15:04:05 -0700", parts[0])
 	if err != nil {
@@ -240,10 +419,17 @@ func (s *DefaultBranchService) createBranchFromName(branchName string) (*Branch,
 		LastCommitSHA:      strings.TrimSpace(parts[3]),
 		AuthorUserName:     strings.TrimSpace(parts[1]),
 		AuthorEmail:        strings.TrimSpace(parts[2]),
+		Subject:            subject,
 		HasUnpushedCommits: hasUnpushed,
 		Remote:             remote,
 	}
 
+	if !isRemote {
+		if upstream, track, err := s.Client.getUpstreamInfo(actualName); err == nil {
+			applyUpstreamInfo(branch, upstream, track)
+		}
+	}
+
 	return branch, nil
 }
 
@@ -256,40 +442,69 @@ func (s *TestableBranchService) GetCurrentBranch() (*Branch, error) {
 }
 
 func (s *TestableBranchService) GetMergedBranches(baseBranch string) ([]Branch, error) {
+	merged, _, err := s.GetMergeStatus(baseBranch)
+	return merged, err
+}
+
+func (s *TestableBranchService) GetMergeStatus(baseBranch string) ([]Branch, []Branch, error) {
 	branchNames, err := s.client.GetMergedBranchNames(baseBranch)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	remoteName := s.RemoteName
-	if remoteName == "" {
-		remoteName = "origin"
+	// The base on a merge-source remote may be ahead of the local one, e.g. in
+	// a fork whose main is only synced with upstream now and then
+	remoteBases := remoteBasesFor(baseBranch, s.Remotes)
+	for _, remoteBase := range remoteBases {
+		if remoteMerged, err := s.client.GetMergedBranchNames(remoteBase); err == nil {
+			branchNames = unionNames(branchNames, remoteMerged)
+		}
 	}
-	remoteBase := fmt.Sprintf("%s/%s", remoteName, baseBranch)
-	remoteMerged, err := s.client.GetMergedBranchNames(remoteBase)
-	if err == nil {
-		existing := make(map[string]bool)
-		for _, name := range branchNames {
-			existing[name] = true
+	branchNames = ownedMergedNames(branchNames, baseBranch, s.Remotes)
+
+	mergeMethods := make(map[string]MergeMethod)
+	for _, name := range branchNames {
+		mergeMethods[name] = MergeMethodMerge
+	}
+
+	var equivalents []patchEquivalence
+	if s.DetectRebaseMerges {
+		equivalents, err = s.findPatchEquivalents(branchNames, baseBranch, remoteBases)
+		if err != nil {
+			return nil, nil, err
 		}
-		for _, name := range remoteMerged {
-			if !existing[name] {
-				branchNames = append(branchNames, name)
+		for _, equivalence := range equivalents {
+			if equivalence.fullyUpstream() {
+				branchNames = append(branchNames, equivalence.name)
+				mergeMethods[equivalence.name] = MergeMethodRebase
 			}
 		}
 	}
 
+	if s.DetectSquashMerges {
+		squashed, err := s.findSquashMerged(branchNames, baseBranch, remoteBases)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range squashed {
+			branchNames = append(branchNames, name)
+			mergeMethods[name] = MergeMethodSquash
+		}
+	}
+
+	snapshot := s.branchSnapshot()
 	var branches []Branch
 	for _, name := range branchNames {
-		branch, err := s.GetBranchByName(name)
+		branch, err := s.branchFromSnapshot(snapshot, name)
 		if err != nil {
 			continue
 		}
 		branch.IsMerged = true
+		branch.MergeMethod = mergeMethods[name]
 		branches = append(branches, *branch)
 	}
 
-	return branches, nil
+	return branches, partialBranches(snapshot, equivalents, s.branchFromSnapshot), nil
 }
 
 func (s *TestableBranchService) GetBranchesWithTrackedRemotes() ([]Branch, error) {
@@ -298,72 +513,96 @@ func (s *TestableBranchService) GetBranchesWithTrackedRemotes() ([]Branch, error
 		return nil, err
 	}
 
-	var localBranches []string
-	var remoteBranches []string
-	localBranchSet := make(map[string]bool)
-
-	remoteName := s.RemoteName
-	if remoteName == "" {
-		remoteName = "origin"
-	}
+	localNames, trackedNames := trackedBranchNames(branchNames, s.Remotes)
+	remotesByBranch := branchRemotes(branchNames, s.Remotes)
 
-	for _, name := range branchNames {
-		if name == "origin/HEAD" {
+	snapshot := s.branchSnapshot()
+	var branches []Branch
+	for _, name := range append(localNames, trackedNames...) {
+		branch, err := s.branchFromSnapshot(snapshot, name)
+		if err != nil {
 			continue
 		}
+		branch.Remotes = remotesByBranch[branch.Name]
+		// Tracked remote branches have a local branch by definition
+		branch.HasLocal = branch.IsRemote
+		branches = append(branches, *branch)
+	}
 
-		if strings.HasPrefix(name, "remotes/"+remoteName+"/") {
-			remoteBranches = append(remoteBranches, name)
-		} else {
-			localBranches = append(localBranches, name)
-			localBranchSet[name] = true
-		}
+	return branches, nil
+}
+
+func (s *TestableBranchService) GetRemoteBranches() ([]Branch, error) {
+	allNames, err := s.client.GetAllBranchNames()
+	if err != nil {
+		return nil, err
 	}
 
-	var branches []Branch
+	localSet := make(map[string]bool)
+	for _, name := range localBranchNames(allNames) {
+		localSet[name] = true
+	}
+	remotesByBranch := branchRemotes(allNames, s.Remotes)
 
-	for _, name := range localBranches {
-		branch, err := s.createBranchFromName(name)
+	snapshot := s.branchSnapshot()
+	var branches []Branch
+	for _, name := range pushRemoteBranchNames(allNames, s.Remotes) {
+		branch, err := s.branchFromSnapshot(snapshot, name)
 		if err != nil {
 			continue
 		}
+		branch.Remotes = remotesByBranch[branch.Name]
+		branch.HasLocal = localSet[branch.Name]
 		branches = append(branches, *branch)
 	}
 
-	for _, remoteName := range remoteBranches {
-		branchName := strings.TrimPrefix(remoteName, "remotes/"+s.RemoteName+"/")
-		if s.RemoteName == "" {
-			branchName = strings.TrimPrefix(remoteName, "remotes/origin/")
-		}
-
-		if localBranchSet[branchName] {
-			branch, err := s.createBranchFromName(remoteName)
-			if err != nil {
-				continue
-			}
-			branches = append(branches, *branch)
-		}
-	}
-
 	return branches, nil
 }
 
 func (s *TestableBranchService) GetBranchByName(branchName string) (*Branch, error) {
-	return s.createBranchFromName(branchName)
+	return s.branchFromSnapshot(s.branchSnapshot(branchRefPattern(branchName)), branchName)
 }
 
+func (s *TestableBranchService) GetBranchLog(branch *Branch, limit int) (string, error) {
+	return s.client.GetBranchLog(branchRef(branch, s.RemoteName), limit)
+}
+
+// DeleteBranch removes a branch only if the safety checks pass and git's safe
+// delete succeeds, or the branch is known to have landed as a squash or rebase
+// merge. Everything else, gone upstreams included, is refused with an
+// *UnsafeDeleteError and needs ForceDeleteBranch.
 func (s *TestableBranchService) DeleteBranch(branch *Branch) error {
+	if err := s.checkLease(branch); err != nil {
+		return err
+	}
 	if branch.IsRemote {
-		if branch.Remote == "" {
-			if s.RemoteName != "" {
-				branch.Remote = s.RemoteName
-			} else {
-				branch.Remote = "origin" // Fallback to origin when no remote is configured
-			}
+		branch.Remote = remoteOrDefault(branch.Remote, s.RemoteName)
+		if err := checkOwnedRemote(branch, s.Remotes); err != nil {
+			return err
+		}
+		if branch.ExpectedSHA != "" {
+			return s.client.DeleteRemoteBranchWithLease(branch.Remote, branch.Name, branch.ExpectedSHA)
 		}
 		return s.client.DeleteRemoteBranch(branch.Remote, branch.Name)
 	}
-	return s.client.DeleteLocalBranch(branch.Name)
+
+	if err := s.CheckDeleteSafety(branch); err != nil {
+		return err
+	}
+	if err := s.deleteLocalBranch(branch, false); err != nil {
+		// Forcing would not help with these, and they say nothing about unmerged work
+		if stoppedEarly(err) || isStaleTip(err) || errors.Is(err, ErrCheckedOutInWorktree) || errors.Is(err, ErrBranchNotFound) {
+			return err
+		}
+		// git only knows ancestry; squash and rebase merges were verified by
+		// content instead. A gone upstream proves nothing about the branch's
+		// commits, so those branches need --force like any other.
+		if branch.MergeMethod == MergeMethodSquash || branch.MergeMethod == MergeMethodRebase {
+			return s.ForceDeleteBranch(branch)
+		}
+		return &UnsafeDeleteError{Branch: branch.Name, Reason: safeDeleteReason(err), Err: err}
+	}
+	return nil
 }
 
 func (s *TestableBranchService) IsProtectedBranch(branch *Branch, patterns []string) bool {
@@ -380,22 +619,25 @@ func (s *TestableBranchService) IsProtectedBranch(branch *Branch, patterns []str
 }
 
 func (s *TestableBranchService) BranchExists(branchName string) (bool, error) {
-	return s.client.BranchExists(branchName)
+	return s.client.BranchExists(branchName, remoteNames(s.Remotes, isAnyRemote))
 }
 
-func (s *TestableBranchService) createBranchFromName(branchName string) (*Branch, error) {
-	remoteName := "origin"
-	if s.RemoteName != "" {
-		remoteName = s.RemoteName
-	}
+func (s *TestableBranchService) ResolveBranchSHA(branch *Branch) (string, error) {
+	return s.client.ResolveRef(branchRef(branch, s.RemoteName))
+}
 
-	isRemote := strings.HasPrefix(branchName, "remotes/"+remoteName+"/")
-	actualName := branchName
-	remote := ""
+func (s *TestableBranchService) RestoreBranch(branchName, sha string) error {
+	return s.client.CreateLocalBranch(branchName, sha)
+}
 
-	if isRemote {
-		actualName = strings.TrimPrefix(branchName, "remotes/"+remoteName+"/")
-		remote = remoteName
+func (s *TestableBranchService) PushBranch(remote, branchName, sha string) error {
+	return s.client.PushBranch(remote, branchName, sha)
+}
+
+func (s *TestableBranchService) createBranchFromName(branchName string) (*Branch, error) {
+	remote, actualName, isRemote := splitRemoteBranch(branchName, s.Remotes)
+	if !isRemote {
+		actualName = branchName
 	}
 
 	branchNameForCommitInfo := actualName
@@ -408,10 +650,15 @@ func (s *TestableBranchService) createBranchFromName(branchName string) (*Branch
 		return nil, fmt.Errorf("failed to get commit info for branch %s: %w", branchNameForCommitInfo, err)
 	}
 
-	parts := strings.Split(commitInfo, "|")
-	if len(parts) != 4 {
+	// The subject comes last as it may itself contain "|"
+	parts := strings.SplitN(commitInfo, "|", 5)
+	if len(parts) < 4 {
 		return nil, fmt.Errorf("unexpected commit info format for branch %s", actualName)
 	}
+	subject := ""
+	if len(parts) == 5 {
+		subject = parts[4]
+	}
 
 	commitDate, err := time.Parse("2006-01-02 15:04:05 -0700", parts[0])
 	if err != nil {
@@ -437,9 +684,34 @@ func (s *TestableBranchService) createBranchFromName(branchName string) (*Branch
 		LastCommitSHA:      strings.TrimSpace(parts[3]),
 		AuthorUserName:     strings.TrimSpace(parts[1]),
 		AuthorEmail:        strings.TrimSpace(parts[2]),
+		Subject:            subject,
 		HasUnpushedCommits: hasUnpushed,
 		Remote:             remote,
 	}
 
+	if !isRemote {
+		if upstream, track, err := s.client.GetUpstreamInfo(actualName); err == nil {
+			applyUpstreamInfo(branch, upstream, track)
+		}
+	}
+
 	return branch, nil
 }
+
+// branchRef returns the fully qualified ref a branch lives under.
+func branchRef(branch *Branch, defaultRemote string) string {
+	if !branch.IsRemote {
+		return "refs/heads/" + branch.Name
+	}
+	return "refs/remotes/" + remoteOrDefault(branch.Remote, defaultRemote) + "/" + branch.Name
+}
+
+func remoteOrDefault(remote, defaultRemote string) string {
+	if remote != "" {
+		return remote
+	}
+	if defaultRemote != "" {
+		return defaultRemote
+	}
+	return "origin"
+}
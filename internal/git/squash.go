@@ -0,0 +1,127 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abey/clean-git/internal/pool"
+)
+
+// isSquashMerged reports whether the combined diff of branchRef against its
+// merge-base with baseRef already exists in baseRef as a single commit. It
+// synthesizes that squash commit with commit-tree (the object is unreferenced
+// and left for gc) and asks `git cherry` whether an equivalent patch is upstream.
+func (c *defaultGitClient) isSquashMerged(branchRef, baseRef string) (bool, error) {
+	output, err := c.run(c.ctx, "merge-base", baseRef, branchRef)
+	if err != nil {
+		return false, fmt.Errorf("failed to find merge base of %s and %s: %w", branchRef, baseRef, err)
+	}
+	mergeBase := strings.TrimSpace(output)
+
+	output, err = c.run(c.ctx, "rev-parse", branchRef+"^{tree}", mergeBase+"^{tree}")
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve trees for %s: %w", branchRef, err)
+	}
+	trees := strings.Fields(output)
+	if len(trees) != 2 {
+		return false, fmt.Errorf("unexpected rev-parse output for %s", branchRef)
+	}
+	if trees[0] == trees[1] {
+		// No net changes, so there is nothing a squash merge could have applied
+		return false, nil
+	}
+
+	output, err = c.run(c.ctx, "-c", "user.name=clean-git", "-c", "user.email=clean-git@localhost",
+		"commit-tree", trees[0], "-p", mergeBase, "-m", "clean-git squash probe")
+	if err != nil {
+		return false, fmt.Errorf("failed to synthesize squash commit for %s: %w", branchRef, err)
+	}
+	squashCommit := strings.TrimSpace(output)
+
+	output, err = c.run(c.ctx, "cherry", baseRef, squashCommit)
+	if err != nil {
+		return false, fmt.Errorf("failed to compare %s with %s: %w", branchRef, baseRef, err)
+	}
+	return strings.HasPrefix(strings.TrimSpace(output), "-"), nil
+}
+
+// squashCandidates returns the local branches and the branches of push
+// remotes that are not already known to be merged and could therefore have
+// been squash merged into baseBranch.
+func squashCandidates(allNames, mergedNames []string, baseBranch string, remotes []Remote) []string {
+	merged := make(map[string]bool)
+	for _, name := range mergedNames {
+		merged[name] = true
+	}
+
+	var candidates []string
+	for _, name := range localBranchNames(allNames) {
+		if merged[name] || name == baseBranch {
+			continue
+		}
+		candidates = append(candidates, name)
+	}
+	for _, name := range pushRemoteBranchNames(allNames, remotes) {
+		if _, branch, _ := splitRemoteBranch(name, remotes); merged[name] || branch == baseBranch {
+			continue
+		}
+		candidates = append(candidates, name)
+	}
+	return candidates
+}
+
+func (s *DefaultBranchService) findSquashMerged(mergedNames []string, baseBranch string, remoteBases []string) ([]string, error) {
+	allNames, err := s.Client.getAllBranchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := squashCandidates(allNames, mergedNames, baseBranch, s.Remotes)
+	merged := make([]bool, len(candidates))
+	pool.Run(s.ctx, s.Jobs, len(candidates), func(i int) {
+		// A branch that cannot be compared (e.g. no common history) is simply not merged
+		for _, base := range append([]string{baseBranch}, remoteBases...) {
+			if ok, err := s.Client.isSquashMerged(branchRefPattern(candidates[i]), base); err == nil && ok {
+				merged[i] = true
+				return
+			}
+		}
+	})
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return selected(candidates, merged), nil
+}
+
+func (s *TestableBranchService) findSquashMerged(mergedNames []string, baseBranch string, remoteBases []string) ([]string, error) {
+	allNames, err := s.client.GetAllBranchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := squashCandidates(allNames, mergedNames, baseBranch, s.Remotes)
+	merged := make([]bool, len(candidates))
+	pool.Run(s.ctx, s.Jobs, len(candidates), func(i int) {
+		for _, base := range append([]string{baseBranch}, remoteBases...) {
+			if ok, err := s.client.IsSquashMerged(branchRefPattern(candidates[i]), base); err == nil && ok {
+				merged[i] = true
+				return
+			}
+		}
+	})
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return selected(candidates, merged), nil
+}
+
+// selected returns the names whose flag is set, keeping their order.
+func selected(names []string, flags []bool) []string {
+	var result []string
+	for i, name := range names {
+		if flags[i] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// getUpstreamInfo returns the short upstream name (from branch.<name>.merge)
+// and its tracking state as printed by %(upstream:track), e.g. "[gone]" or
+// "[ahead 1, behind 2]". Both are empty when no upstream is configured.
+func (c *defaultGitClient) getUpstreamInfo(branchName string) (string, string, error) {
+	output, err := c.run(c.ctx, "for-each-ref", "--format=%(upstream:short)|%(upstream:track)", "refs/heads/"+branchName)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get upstream for %s: %w", branchName, err)
+	}
+	upstream, track, _ := strings.Cut(strings.TrimSpace(output), "|")
+	return upstream, track, nil
+}
+
+// parseUpstreamTrack decodes %(upstream:track) output.
+func parseUpstreamTrack(track string) (gone bool, ahead int, behind int) {
+	track = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(track), "["), "]")
+	if track == "gone" {
+		return true, 0, 0
+	}
+	for _, part := range strings.Split(track, ",") {
+		field, value, found := strings.Cut(strings.TrimSpace(part), " ")
+		if !found {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch field {
+		case "ahead":
+			ahead = count
+		case "behind":
+			behind = count
+		}
+	}
+	return false, ahead, behind
+}
+
+func applyUpstreamInfo(branch *Branch, upstream, track string) {
+	branch.Upstream = upstream
+	branch.UpstreamGone, branch.Ahead, branch.Behind = parseUpstreamTrack(track)
+}
+
+// localBranchNames filters `git branch --all` output down to local branches.
+func localBranchNames(allNames []string) []string {
+	var names []string
+	for _, name := range allNames {
+		if strings.HasPrefix(name, "remotes/") || strings.HasPrefix(name, "(") || strings.Contains(name, " -> ") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// GetGoneBranches returns local branches whose configured upstream branch no
+// longer exists, typically because it was deleted after its pull request merged.
+func (s *DefaultBranchService) GetGoneBranches() ([]Branch, error) {
+	allNames, err := s.Client.getAllBranchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := s.branchSnapshot()
+	var branches []Branch
+	for _, name := range localBranchNames(allNames) {
+		branch, err := s.branchFromSnapshot(snapshot, name)
+		if err != nil {
+			continue
+		}
+		if branch.UpstreamGone {
+			branches = append(branches, *branch)
+		}
+	}
+	return branches, nil
+}
+
+func (s *TestableBranchService) GetGoneBranches() ([]Branch, error) {
+	allNames, err := s.client.GetAllBranchNames()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := s.branchSnapshot()
+	var branches []Branch
+	for _, name := range localBranchNames(allNames) {
+		branch, err := s.branchFromSnapshot(snapshot, name)
+		if err != nil {
+			continue
+		}
+		if branch.UpstreamGone {
+			branches = append(branches, *branch)
+		}
+	}
+	return branches, nil
+}
@@ -0,0 +1,165 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	journalDir  = "clean-git"
+	journalFile = "journal.jsonl"
+)
+
+// Entry records a single branch removed by a clean run.
+type Entry struct {
+	RunID       string    `json:"runId"`
+	Branch      string    `json:"branch"`
+	IsRemote    bool      `json:"isRemote"`
+	Remote      string    `json:"remote,omitempty"`
+	SHA         string    `json:"sha"`
+	Author      string    `json:"author,omitempty"`
+	AuthorEmail string    `json:"authorEmail,omitempty"`
+	ArchiveRef  string    `json:"archiveRef,omitempty"`
+	Forced      bool      `json:"forced,omitempty"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+// Journal is an append-only log of deleted branches, stored as JSON lines
+// inside the repository's git directory so it never shows up in the worktree.
+type Journal struct {
+	path string
+}
+
+func Open(gitDir string) *Journal {
+	return &Journal{path: filepath.Join(gitDir, journalDir, journalFile)}
+}
+
+// NewRunID names a clean run by its start time plus a random suffix, so two
+// runs started in the same second are still told apart by restore --run.
+func NewRunID(now time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return now.UTC().Format("20060102T150405.000000000Z")
+	}
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+func (j *Journal) Append(entry Entry) error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Entries returns every journal entry in the order it was written.
+func (j *Journal) Entries() ([]Entry, error) {
+	file, err := os.Open(j.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, nil
+}
+
+func (j *Journal) Run(runID string) ([]Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	return filterRun(entries, runID), nil
+}
+
+func (j *Journal) LastRun() ([]Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return filterRun(entries, entries[len(entries)-1].RunID), nil
+}
+
+// LatestForBranch returns the entries of the most recent run that deleted
+// the named branch, covering both its local and remote copies.
+func (j *Journal) LatestForBranch(branchName string) ([]Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	runID := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Branch == branchName {
+			runID = entries[i].RunID
+			break
+		}
+	}
+	if runID == "" {
+		return nil, nil
+	}
+
+	var matches []Entry
+	for _, entry := range entries {
+		if entry.RunID == runID && entry.Branch == branchName {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+func filterRun(entries []Entry, runID string) []Entry {
+	var run []Entry
+	for _, entry := range entries {
+		if entry.RunID == runID {
+			run = append(run, entry)
+		}
+	}
+	return run
+}
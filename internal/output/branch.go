@@ -0,0 +1,129 @@
+package output
+
+import (
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+)
+
+// Merge statuses of a branch relative to the configured base branches
+const (
+	MergeStatusMerged    = "merged"
+	MergeStatusPartial   = "partial"
+	MergeStatusNotMerged = "not merged"
+	// MergeStatusNoUnique is a branch not merged into any single base whose
+	// commits are nonetheless all in the bases
+	MergeStatusNoUnique = "no unique commits"
+)
+
+// Outcomes of a clean run for a single branch
+const (
+	OutcomeSkipped          = "skipped"
+	OutcomePlanned          = "planned"
+	OutcomeDeselected       = "deselected"
+	OutcomeWouldDelete      = "would-delete"
+	OutcomeWouldForceDelete = "would-force-delete"
+	OutcomeWouldRefuse      = "would-refuse"
+	OutcomeWouldArchive     = "would-archive"
+	OutcomeDeleted          = "deleted"
+	OutcomeForceDeleted     = "force-deleted"
+	OutcomeArchived         = "archived"
+	OutcomeRefused          = "refused"
+	OutcomeStale            = "stale"
+	OutcomeFailed           = "failed"
+	OutcomeInterrupted      = "interrupted"
+)
+
+// Branch is the record list and clean emit for every branch they report: the
+// git.Branch fields plus what clean-git derived from them.
+type Branch struct {
+	Name               string          `json:"name"`
+	Type               string          `json:"type"`
+	IsCurrent          bool            `json:"isCurrent"`
+	IsRemote           bool            `json:"isRemote"`
+	IsMerged           bool            `json:"isMerged"`
+	MergeMethod        git.MergeMethod `json:"mergeMethod,omitempty"`
+	LastCommitAt       time.Time       `json:"lastCommitAt"`
+	LastCommitSHA      string          `json:"lastCommitSha"`
+	Subject            string          `json:"subject"`
+	Author             string          `json:"author"`
+	AuthorEmail        string          `json:"authorEmail"`
+	HasUnpushedCommits bool            `json:"hasUnpushedCommits"`
+	Remote             string          `json:"remote,omitempty"`
+	Remotes            []string        `json:"remotes,omitempty"`
+	HasLocal           bool            `json:"hasLocal"`
+	Upstream           string          `json:"upstream,omitempty"`
+	UpstreamGone       bool            `json:"upstreamGone"`
+	Ahead              int             `json:"ahead"`
+	Behind             int             `json:"behind"`
+	CommitsUnique      int             `json:"commitsUnique,omitempty"`
+	CommitsUpstream    int             `json:"commitsUpstream,omitempty"`
+	// Divergence and UniqueCommits are only set by list
+	Divergence    []Divergence `json:"divergence,omitempty"`
+	UniqueCommits *int         `json:"uniqueCommits,omitempty"`
+
+	MergeStatus string `json:"mergeStatus"`
+	MergedInto  string `json:"mergedInto,omitempty"`
+	AgeSeconds  int64  `json:"ageSeconds"`
+	// Qualifies is the qualification decision and Reasons what it was based on:
+	// the rule a qualifying branch matched, or every check it failed
+	Qualifies bool     `json:"qualifies"`
+	Reasons   []string `json:"reasons"`
+
+	// Outcome, Error and ArchiveRef are only set by clean
+	Outcome    string `json:"outcome,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ArchiveRef string `json:"archiveRef,omitempty"`
+}
+
+// Divergence is how far a branch is ahead of and behind one base branch.
+type Divergence struct {
+	Base   string `json:"base"`
+	Ahead  int    `json:"ahead"`
+	Behind int    `json:"behind"`
+}
+
+// NewBranch copies branch into a record with its type and age filled in and
+// the merge status taken from the branch itself.
+func NewBranch(branch git.Branch, now time.Time) *Branch {
+	record := &Branch{
+		Name:               branch.Name,
+		Type:               "local",
+		IsCurrent:          branch.IsCurrent,
+		IsRemote:           branch.IsRemote,
+		IsMerged:           branch.IsMerged,
+		MergeMethod:        branch.MergeMethod,
+		LastCommitAt:       branch.LastCommitAt,
+		LastCommitSHA:      branch.LastCommitSHA,
+		Subject:            branch.Subject,
+		Author:             branch.AuthorUserName,
+		AuthorEmail:        branch.AuthorEmail,
+		HasUnpushedCommits: branch.HasUnpushedCommits,
+		Remote:             branch.Remote,
+		Remotes:            branch.Remotes,
+		HasLocal:           branch.HasLocal,
+		Upstream:           branch.Upstream,
+		UpstreamGone:       branch.UpstreamGone,
+		Ahead:              branch.Ahead,
+		Behind:             branch.Behind,
+		CommitsUnique:      branch.CommitsUnique,
+		CommitsUpstream:    branch.CommitsUpstream,
+		MergeStatus:        MergeStatusNotMerged,
+		AgeSeconds:         int64(now.Sub(branch.LastCommitAt).Seconds()),
+		Reasons:            []string{},
+	}
+	if branch.IsRemote {
+		record.Type = "remote"
+	}
+	if branch.Divergence != nil {
+		for _, divergence := range branch.Divergence {
+			record.Divergence = append(record.Divergence, Divergence{Base: divergence.Base, Ahead: divergence.Ahead, Behind: divergence.Behind})
+		}
+		unique := branch.UniqueCommits
+		record.UniqueCommits = &unique
+	}
+	if branch.IsMerged {
+		record.MergeStatus = MergeStatusMerged
+	}
+	return record
+}
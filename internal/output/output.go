@@ -0,0 +1,96 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Format selects how list and clean report branches.
+type Format string
+
+const (
+	// FormatText is the human-readable tables and messages
+	FormatText Format = "text"
+	// FormatJSON writes one JSON array holding every record
+	FormatJSON Format = "json"
+	// FormatNDJSON writes one JSON record per line as soon as it is known
+	FormatNDJSON Format = "ndjson"
+)
+
+func ParseFormat(input string) (Format, error) {
+	switch format := Format(input); format {
+	case FormatText, FormatJSON, FormatNDJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format '%s': expected text, json or ndjson", input)
+}
+
+// IsMachine reports whether the format is meant for scripts rather than people.
+func (f Format) IsMachine() bool {
+	return f == FormatJSON || f == FormatNDJSON || f == FormatTemplate
+}
+
+// Writer emits records in a machine-readable format. Nothing is written in
+// FormatText, so callers can write records unconditionally. The first error
+// stops further output and is returned by Close.
+type Writer struct {
+	format   Format
+	out      io.Writer
+	template *Template
+	records  []interface{}
+	err      error
+	closed   bool
+}
+
+func NewWriter(format Format, out io.Writer) *Writer {
+	return &Writer{format: format, out: out}
+}
+
+// NewTemplateWriter renders each record with tmpl as it is written.
+func NewTemplateWriter(tmpl *Template, out io.Writer) *Writer {
+	return &Writer{format: FormatTemplate, out: out, template: tmpl}
+}
+
+func (w *Writer) Format() Format {
+	return w.format
+}
+
+// Write streams the record in FormatNDJSON and FormatTemplate and keeps it for
+// Close in FormatJSON.
+func (w *Writer) Write(record interface{}) {
+	if w.err != nil {
+		return
+	}
+	switch w.format {
+	case FormatNDJSON:
+		w.err = newEncoder(w.out).Encode(record)
+	case FormatTemplate:
+		w.err = w.template.Execute(w.out, record)
+	case FormatJSON:
+		w.records = append(w.records, record)
+	}
+}
+
+// Close writes the JSON array, which is empty rather than null when there are
+// no records. Closing again writes nothing.
+func (w *Writer) Close() error {
+	if w.err != nil || w.format != FormatJSON || w.closed {
+		return w.err
+	}
+	w.closed = true
+	records := w.records
+	if records == nil {
+		records = []interface{}{}
+	}
+	encoder := newEncoder(w.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// newEncoder keeps reasons like "too recent (< 1h old)" readable
+func newEncoder(out io.Writer) *json.Encoder {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	return encoder
+}
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+)
+
+// FormatTemplate renders every record with a user template, see ParseTemplate
+const FormatTemplate Format = "template"
+
+// Age is a branch age. It prints like the list table, in whole days or hours.
+type Age time.Duration
+
+func (a Age) String() string {
+	d := time.Duration(a)
+	switch {
+	case d >= 24*time.Hour:
+		return config.Duration(d.Truncate(24 * time.Hour)).String()
+	case d >= time.Hour:
+		return config.Duration(d.Truncate(time.Hour)).String()
+	}
+	return "< 1h"
+}
+
+// Days is the age in whole days, for comparisons in templates
+func (a Age) Days() int {
+	return int(time.Duration(a) / (24 * time.Hour))
+}
+
+// View is the data --format templates see for each branch. Its fields are a
+// stable interface for users' scripts: rename or remove them only with care.
+type View struct {
+	Name         string
+	Type         string
+	IsCurrent    bool
+	IsRemote     bool
+	IsMerged     bool
+	MergeMethod  string
+	MergeStatus  string
+	MergedInto   string
+	SHA          string
+	Subject      string
+	Author       string
+	AuthorEmail  string
+	LastCommitAt time.Time
+	Age          Age
+	Remote       string
+	// Remotes lists the configured remotes a branch of this name exists on
+	Remotes []string
+	// HasLocal is set on remote branches with a local branch of the same name
+	HasLocal     bool
+	Upstream     string
+	UpstreamGone bool
+	Ahead        int
+	Behind       int
+	// Divergence lists the ahead/behind counts against each base branch and
+	// UniqueCommits how many commits are in none of them
+	Divergence    []Divergence
+	UniqueCommits int
+	Qualifies     bool
+	Reasons       []string
+}
+
+func NewView(record *Branch) View {
+	view := View{
+		Name:         record.Name,
+		Type:         record.Type,
+		IsCurrent:    record.IsCurrent,
+		IsRemote:     record.IsRemote,
+		IsMerged:     record.IsMerged,
+		MergeMethod:  string(record.MergeMethod),
+		MergeStatus:  record.MergeStatus,
+		MergedInto:   record.MergedInto,
+		SHA:          record.LastCommitSHA,
+		Subject:      record.Subject,
+		Author:       record.Author,
+		AuthorEmail:  record.AuthorEmail,
+		LastCommitAt: record.LastCommitAt,
+		Age:          Age(time.Duration(record.AgeSeconds) * time.Second),
+		Remote:       record.Remote,
+		Remotes:      record.Remotes,
+		HasLocal:     record.HasLocal,
+		Upstream:     record.Upstream,
+		UpstreamGone: record.UpstreamGone,
+		Ahead:        record.Ahead,
+		Behind:       record.Behind,
+		Divergence:   record.Divergence,
+		Qualifies:    record.Qualifies,
+		Reasons:      record.Reasons,
+	}
+	if record.UniqueCommits != nil {
+		view.UniqueCommits = *record.UniqueCommits
+	}
+	return view
+}
+
+var objectName = regexp.MustCompile(`^[0-9a-f]{8,40}$`)
+
+var templateFuncs = template.FuncMap{
+	// ago renders a time relative to now, e.g. "3d ago"
+	"ago": func(t time.Time) string {
+		return Age(time.Since(t)).String() + " ago"
+	},
+	// short abbreviates ref names and object names like git's :short
+	"short": func(name string) string {
+		if objectName.MatchString(name) {
+			return name[:7]
+		}
+		for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/tags/", "refs/"} {
+			if strings.HasPrefix(name, prefix) {
+				return strings.TrimPrefix(name, prefix)
+			}
+		}
+		return name
+	},
+	// join takes the separator first so it works in pipelines: {{.Reasons | join ", "}}
+	"join": func(sep string, items []string) string {
+		return strings.Join(items, sep)
+	},
+}
+
+// Template renders one line per branch from a --format template.
+type Template struct {
+	template *template.Template
+}
+
+var templateEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`)
+
+// ParseTemplate parses a --format template such as
+// '{{.Name}}\t{{.AuthorEmail}}\t{{.Age}}'. Like the arguments to printf, \t
+// and \n in it are escapes, and a newline is added after each branch unless
+// the template ends with one. The template is tried on a sample View so
+// misspelt fields are reported before any work is done.
+func ParseTemplate(text string) (*Template, error) {
+	text = templateEscapes.Replace(text)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	parsed, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %w", err)
+	}
+	if err := parsed.Execute(io.Discard, View{Reasons: []string{""}, Divergence: []Divergence{{}}}); err != nil {
+		return nil, fmt.Errorf("invalid format template: %w", err)
+	}
+	return &Template{template: parsed}, nil
+}
+
+// Execute renders record, which must be a *Branch. Nothing is written when
+// the template renders only the trailing newline, so {{if}} can filter.
+func (t *Template) Execute(out io.Writer, record interface{}) error {
+	branch, ok := record.(*Branch)
+	if !ok {
+		return fmt.Errorf("cannot format %T with a template", record)
+	}
+	var rendered bytes.Buffer
+	if err := t.template.Execute(&rendered, NewView(branch)); err != nil {
+		return err
+	}
+	if rendered.String() == "\n" {
+		return nil
+	}
+	_, err := out.Write(rendered.Bytes())
+	return err
+}
@@ -0,0 +1,145 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+)
+
+// Version is bumped whenever a plan written by an older clean-git could be
+// misread by a newer one.
+const Version = 1
+
+// Action is what apply does with every branch of a plan.
+type Action string
+
+const (
+	ActionDelete  Action = "delete"
+	ActionArchive Action = "archive"
+)
+
+// Plan is a reviewable list of the branches a clean run would remove, written
+// by `clean --plan` and carried out by `apply`.
+type Plan struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Repository is the root of the repository the plan was made in
+	Repository string `json:"repository"`
+	Action     Action `json:"action"`
+	// ArchiveRemote also keeps archive refs on the remote for remote branches
+	ArchiveRemote bool `json:"archiveRemote,omitempty"`
+	// Force deletes local branches refused by the safety checks
+	Force    bool     `json:"force,omitempty"`
+	Branches []Branch `json:"branches"`
+}
+
+// Branch is a branch to remove and the tip it must still point at.
+type Branch struct {
+	Name     string `json:"name"`
+	IsRemote bool   `json:"isRemote"`
+	Remote   string `json:"remote,omitempty"`
+	SHA      string `json:"sha"`
+	// Reason is the rule that selected the branch, e.g. "merged into main"
+	Reason       string    `json:"reason"`
+	Author       string    `json:"author,omitempty"`
+	AuthorEmail  string    `json:"authorEmail,omitempty"`
+	LastCommitAt time.Time `json:"lastCommitAt"`
+	// MergeMethod and UpstreamGone let apply delete squash-merged and gone
+	// branches that git branch -d refuses, exactly as clean would
+	MergeMethod  git.MergeMethod `json:"mergeMethod,omitempty"`
+	UpstreamGone bool            `json:"upstreamGone,omitempty"`
+}
+
+// NewBranch records branch, selected because of reason, at its full tip sha.
+func NewBranch(branch *git.Branch, sha, reason string) Branch {
+	return Branch{
+		Name:         branch.Name,
+		IsRemote:     branch.IsRemote,
+		Remote:       branch.Remote,
+		SHA:          sha,
+		Reason:       reason,
+		Author:       branch.AuthorUserName,
+		AuthorEmail:  branch.AuthorEmail,
+		LastCommitAt: branch.LastCommitAt,
+		MergeMethod:  branch.MergeMethod,
+		UpstreamGone: branch.UpstreamGone,
+	}
+}
+
+// GitBranch returns the branch to delete, leased to the planned tip so it is
+// refused if anyone moved it since.
+func (b Branch) GitBranch() *git.Branch {
+	return &git.Branch{
+		Name:           b.Name,
+		IsRemote:       b.IsRemote,
+		Remote:         b.Remote,
+		LastCommitAt:   b.LastCommitAt,
+		LastCommitSHA:  b.SHA,
+		AuthorUserName: b.Author,
+		AuthorEmail:    b.AuthorEmail,
+		MergeMethod:    b.MergeMethod,
+		UpstreamGone:   b.UpstreamGone,
+		ExpectedSHA:    b.SHA,
+	}
+}
+
+var fullSHA = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// Write saves the plan as indented JSON so it diffs and reviews well.
+func Write(path string, p *Plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan %s: %w", path, err)
+	}
+	return nil
+}
+
+// Read loads a plan and rejects anything apply could not carry out exactly,
+// such as abbreviated SHAs.
+func Read(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan %s: %w", path, err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Plan) validate() error {
+	if p.Version != Version {
+		return fmt.Errorf("unsupported plan version %d (expected %d)", p.Version, Version)
+	}
+	if p.Action != ActionDelete && p.Action != ActionArchive {
+		return fmt.Errorf("unknown action '%s'", p.Action)
+	}
+
+	seen := make(map[string]bool)
+	for i, branch := range p.Branches {
+		if branch.Name == "" {
+			return fmt.Errorf("branch %d has no name", i+1)
+		}
+		if !fullSHA.MatchString(branch.SHA) {
+			return fmt.Errorf("branch %s: '%s' is not a full commit SHA", branch.Name, branch.SHA)
+		}
+		key := fmt.Sprintf("%t/%s/%s", branch.IsRemote, branch.Remote, branch.Name)
+		if seen[key] {
+			return fmt.Errorf("branch %s is listed twice", branch.Name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Package pool runs independent jobs on a bounded number of goroutines.
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Run calls fn for every index in [0, n) on at most workers goroutines and
+// waits for them. Indexes are handed out in order; once ctx is done no further
+// calls start but those already running finish. It returns how many calls
+// were started, so indexes from the result on never ran.
+func Run(ctx context.Context, workers, n int, fn func(i int)) int {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	// Workers claim the next index themselves and check ctx in the same step,
+	// so nothing starts after cancellation and the started calls stay a prefix
+	var mu sync.Mutex
+	next := 0
+	claim := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= n || ctx.Err() != nil {
+			return 0, false
+		}
+		next++
+		return next - 1, true
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, ok := claim(); ok; i, ok = claim() {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+	return next
+}
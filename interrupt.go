@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/abey/clean-git/internal/errors"
+)
+
+// interruptHandler lets Ctrl-C stop clean-git between branches while they are
+// removed. The first Ctrl-C cancels stop, so no further branch is started and
+// running git commands finish; the second cancels abort, which kills them, and
+// a third exits. Outside such sections Ctrl-C exits at once.
+type interruptHandler struct {
+	mu       sync.Mutex
+	sections int
+	stop     context.Context
+	stopped  context.CancelFunc
+	abort    context.Context
+	aborted  context.CancelFunc
+}
+
+var interrupts = newInterruptHandler()
+
+func newInterruptHandler() *interruptHandler {
+	h := &interruptHandler{}
+	h.stop, h.stopped = context.WithCancel(context.Background())
+	h.abort, h.aborted = context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go h.watch(signals)
+	return h
+}
+
+func (h *interruptHandler) watch(signals <-chan os.Signal) {
+	for range signals {
+		h.mu.Lock()
+		switch {
+		case h.sections == 0 || h.abort.Err() != nil:
+			h.aborted()
+			fmt.Fprintln(os.Stderr)
+			errors.FatalError(errors.ExitInterrupted, "Interrupted")
+		case h.stop.Err() == nil:
+			h.stopped()
+			fmt.Fprintln(os.Stderr, "\nInterrupted: waiting for running git commands to finish (press Ctrl-C again to abort them)...")
+		default:
+			h.aborted()
+			fmt.Fprintln(os.Stderr, "\nAborting running git commands...")
+		}
+		h.mu.Unlock()
+	}
+}
+
+// begin marks the start of a section Ctrl-C stops gracefully; call the
+// returned function to end it.
+func (h *interruptHandler) begin() func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sections++
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.sections--
+	}
+}
+
+// interrupted reports whether Ctrl-C was pressed during a section.
+func (h *interruptHandler) interrupted() bool {
+	return h.stop.Err() != nil
+}
@@ -4,8 +4,8 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -13,7 +13,10 @@ import (
 
 	"github.com/abey/clean-git/internal/config"
 	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/evaluator"
 	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/output"
+	"github.com/abey/clean-git/internal/plan"
 )
 
 const (
@@ -29,6 +32,7 @@ var (
 	dryRun     = flag.Bool("dry-run", false, "Show what would be done without actually doing it")
 	verbose    = flag.Bool("verbose", false, "Enable verbose output")
 	configFlag = flag.Bool("config", false, "Show or update configuration")
+	profile    = flag.String("profile", "", "Apply the named config profile instead of the default one")
 )
 
 func main() {
@@ -36,9 +40,13 @@ func main() {
 		fmt.Fprintf(os.Stderr, "%s\n\n", Description)
 		fmt.Fprintf(os.Stderr, "Usage: %s [GLOBAL OPTIONS] COMMAND [SUBCOMMAND OPTIONS]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Subcommands:\n")
+		fmt.Fprintf(os.Stderr, "  apply     Carry out a plan written by 'clean --plan'\n")
+		fmt.Fprintf(os.Stderr, "  archive   List or purge archived branches\n")
 		fmt.Fprintf(os.Stderr, "  clean     Clean up stale and merged branches\n")
 		fmt.Fprintf(os.Stderr, "  config    Setup or update configuration\n")
+		fmt.Fprintf(os.Stderr, "  explain   Show why clean would or would not delete a branch\n")
 		fmt.Fprintf(os.Stderr, "  list      List all branches with merge status information\n")
+		fmt.Fprintf(os.Stderr, "  restore   Restore branches deleted by a previous clean run\n")
 		fmt.Fprintf(os.Stderr, "\nGlobal Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nRun '%s COMMAND -h' for subcommand options.\n", os.Args[0])
@@ -46,6 +54,9 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s --version\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --dry-run clean --local-only\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s config\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --profile sweep clean --remote-only\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s restore --last\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s explain feature/my-branch\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -70,6 +81,11 @@ func main() {
 	if err != nil {
 		errors.FatalError(errors.ExitConfig, "Failed to initialize configuration service: %v", err)
 	}
+	if *profile != "" {
+		if err := configService.UseProfile(*profile); err != nil {
+			errors.FatalError(errors.ExitConfig, "Failed to select profile: %v", err)
+		}
+	}
 
 	subcmd := flag.Arg(0)
 	if subcmd == "" {
@@ -97,11 +113,19 @@ func main() {
 
 	switch subcmd {
 	case "clean":
-		handleCleanCommand(flag.Args()[1:], configService)
+		handleCleanCommand(flag.Args()[1:], configService, repoRoot)
 	case "list":
 		handleListCommand(flag.Args()[1:], configService)
 	case "config":
 		handleConfigCommand(flag.Args()[1:], configService)
+	case "restore":
+		handleRestoreCommand(flag.Args()[1:], configService, repoRoot)
+	case "archive":
+		handleArchiveCommand(flag.Args()[1:], configService)
+	case "explain":
+		handleExplainCommand(flag.Args()[1:], configService)
+	case "apply":
+		handleApplyCommand(flag.Args()[1:], configService, repoRoot)
 	default:
 		fmt.Fprintf(os.Stderr, "Error: Unknown command '%s'\n\n", subcmd)
 		flag.Usage()
@@ -109,39 +133,103 @@ func main() {
 	}
 }
 
-func handleCleanCommand(args []string, configService config.Service) {
+func handleCleanCommand(args []string, configService config.Service, repoRoot string) {
 	cleanFlags := flag.NewFlagSet("clean", flag.ExitOnError)
 	localOnly := cleanFlags.Bool("local-only", false, "Only clean local branches")
 	remoteOnly := cleanFlags.Bool("remote-only", false, "Only clean remote branches")
+	archiveFlag := cleanFlags.Bool("archive", false, "Move branches under "+git.ArchiveRefPrefix+"<date>/ instead of deleting them")
+	archiveRemoteFlag := cleanFlags.Bool("archive-remote", false, "With --archive, also keep the archive ref on the remote for remote branches")
+	force := cleanFlags.Bool("force", false, "Force delete local branches refused by the safety checks (unpushed or unmerged work), and with --all-remote clean unmerged remote branches too")
+	goneFlag := cleanFlags.Bool("gone", false, "Also clean local branches whose upstream branch was deleted (see goneMaxAge)")
+	allRemoteFlag := cleanFlags.Bool("all-remote", false, "Also clean remote branches without a local copy once older than allRemoteMaxAge; unmerged ones only with --force")
+	fetchFlag := cleanFlags.Bool("fetch", false, "Fetch and prune the remotes before evaluating branches")
+	yes := cleanFlags.Bool("yes", false, "Skip the interactive review and proceed with every qualifying branch")
+	planFile := cleanFlags.String("plan", "", "Write the qualifying branches and their tips to this file for 'clean-git apply' instead of changing anything")
+	outputFlag := cleanFlags.String("output", string(output.FormatText), "Output format: text, json or ndjson (human-readable text then goes to stderr)")
+	overrides := addConfigOverrideFlags(cleanFlags)
 
 	cleanFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s clean [OPTIONS]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Clean up stale and merged branches.\n\n")
+		fmt.Fprintf(os.Stderr, "When run from a terminal, qualifying branches are shown for review before\n")
+		fmt.Fprintf(os.Stderr, "anything is deleted. Use --yes (or a non-terminal stdin) to skip the review.\n")
+		fmt.Fprintf(os.Stderr, "With --plan the branches are written to a file to review and run with 'clean-git apply'.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		cleanFlags.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nGlobal options like --dry-run, --verbose are also available.\n")
+		fmt.Fprintf(os.Stderr, "\nEvery config key can also be overridden with a %s* variable, e.g. %sMAX_AGE=14d.\n", config.EnvPrefix, config.EnvPrefix)
+		fmt.Fprintf(os.Stderr, "Global options like --dry-run, --verbose are also available.\n")
 	}
 
 	cleanFlags.Parse(args)
+	format := parseOutputFormat(*outputFlag)
 
 	if !configService.IsOnboarded() {
 		errors.FatalError(errors.ExitConfig, "Repository not configured. Run 'clean-git config' first")
 	}
+	if !overrides.apply(configService) {
+		return
+	}
+	records, out := newRecordWriter(format, nil)
+	defer closeRecordWriter(records)
 
 	cfg := configService.Config()
 	if cfg == nil {
-		errors.FatalError(errors.ExitConfig, "Failed to load configuration")
+		fatalWithRecords(records, errors.ExitConfig, "Failed to load configuration")
 	}
 
-	branchService := git.NewBranchService(cfg.RemoteName)
+	archive := *archiveFlag || cfg.Archive
+	archiveRemote := *archiveRemoteFlag || cfg.ArchiveRemote
+	cleanGone := *goneFlag || cfg.CleanGone
+	cleanAllRemote := *allRemoteFlag || cfg.CleanAllRemote
+
+	branchService := newBranchService(cfg)
 
 	var qualifyingBranches []*git.Branch
 	var totalProcessed int
 	var errors []string
 
+	now := time.Now()
+	refreshRemotes(out, branchService, cfg, *fetchFlag || cfg.Fetch, now)
+
+	branchEvaluator := evaluator.New(cfg, branchService, evaluator.Options{
+		LocalOnly:      *localOnly,
+		RemoteOnly:     *remoteOnly,
+		CleanGone:      cleanGone,
+		CleanAllRemote: cleanAllRemote,
+		Force:          *force,
+		Now:            now,
+	})
+
+	// A branch can qualify through several base branches or rules; keep the
+	// first and record why the others skipped it
+	decisions := make(map[string]*output.Branch)
+	var decided []*output.Branch
+	consider := func(branch git.Branch, mergedInto string, decision evaluator.Decision) {
+		key := branchKey(&branch)
+		record, ok := decisions[key]
+		if !ok {
+			record = output.NewBranch(branch, now)
+			decisions[key] = record
+			decided = append(decided, record)
+		}
+		if record.MergedInto == "" {
+			record.MergedInto = mergedInto
+		}
+		if record.Qualifies {
+			return
+		}
+		if !recordDecision(record, decision) {
+			if *verbose {
+				fmt.Fprintf(out, "Skipping branch %s: %s\n", branch.Name, record.Reasons[len(record.Reasons)-1])
+			}
+			return
+		}
+		qualifyingBranches = append(qualifyingBranches, &branch)
+	}
+
 	for _, baseBranch := range cfg.BaseBranches {
 		if *verbose {
-			fmt.Printf("Processing base branch: %s\n", baseBranch)
+			fmt.Fprintf(out, "Processing base branch: %s\n", baseBranch)
 		}
 
 		exists, err := branchService.BranchExists(baseBranch)
@@ -154,7 +242,7 @@ func handleCleanCommand(args []string, configService config.Service) {
 			continue
 		}
 		if !exists {
-			fmt.Printf("Base branch '%s' not found in this repository, skipping\n", baseBranch)
+			fmt.Fprintf(out, "Base branch '%s' not found in this repository, skipping\n", baseBranch)
 			continue
 		}
 
@@ -171,193 +259,283 @@ func handleCleanCommand(args []string, configService config.Service) {
 		totalProcessed += len(mergedBranches)
 
 		for _, branch := range mergedBranches {
-			if branch.IsCurrent {
-				if *verbose {
-					fmt.Printf("Skipping current branch: %s\n", branch.Name)
-				}
-				continue
-			}
-
-			age := time.Since(branch.LastCommitAt)
-			if age < cfg.MaxAge {
-				if *verbose {
-					fmt.Printf("Skipping branch %s: too recent (%s < %s)\n", branch.Name, formatDuration(age), formatDuration(cfg.MaxAge))
-				}
-				continue
-			}
+			consider(branch, baseBranch, branchEvaluator.Evaluate(&branch, "merged into "+baseBranch, time.Duration(cfg.MaxAge)))
+		}
+	}
 
-			if *localOnly && branch.IsRemote {
-				if *verbose {
-					fmt.Printf("Skipping remote branch %s: --local-only specified\n", branch.Name)
-				}
-				continue
-			}
-			if *remoteOnly && !branch.IsRemote {
-				if *verbose {
-					fmt.Printf("Skipping local branch %s: --remote-only specified\n", branch.Name)
-				}
-				continue
+	if cleanGone {
+		goneBranches, err := branchService.GetGoneBranches()
+		if err != nil {
+			errorMsg := fmt.Sprintf("Failed to get branches with a gone upstream: %v", err)
+			errors = append(errors, errorMsg)
+			if *verbose {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", errorMsg)
 			}
+		}
+		for _, branch := range goneBranches {
+			consider(branch, "", branchEvaluator.Evaluate(&branch, "upstream gone", time.Duration(cfg.GoneMaxAge)))
+		}
+	}
 
-			includeMatch := false
-			for _, pattern := range cfg.IncludeRegex {
-				matched, err := regexp.MatchString(pattern, branch.Name)
-				if err != nil {
-					if *verbose {
-						fmt.Printf("Warning: Invalid include regex pattern '%s': %v\n", pattern, err)
-					}
-					continue
-				}
-				if matched {
-					includeMatch = true
-					break
-				}
-			}
-			if !includeMatch {
-				if *verbose {
-					fmt.Printf("Skipping branch %s: no include pattern matches\n", branch.Name)
-				}
-				continue
+	if cleanAllRemote {
+		remoteBranches, err := branchService.GetRemoteBranches()
+		if err != nil {
+			errorMsg := fmt.Sprintf("Failed to get remote branches: %v", err)
+			errors = append(errors, errorMsg)
+			if *verbose {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", errorMsg)
 			}
-
-			if branchService.IsProtectedBranch(&branch, cfg.ProtectedRegex) {
-				if *verbose {
-					fmt.Printf("Skipping protected branch: %s\n", branch.Name)
-				}
+		}
+		for _, branch := range remoteBranches {
+			if !branchEvaluator.HasNoLocalCopy(&branch) {
 				continue
 			}
+			// The merged rule above already recorded every merged branch
+			record, seen := decisions[branchKey(&branch)]
+			merged := seen && record.MergedInto != ""
+			consider(branch, "", branchEvaluator.EvaluateNoLocalCopy(&branch, merged))
+		}
+	}
 
-			branchCopy := branch
-			qualifyingBranches = append(qualifyingBranches, &branchCopy)
+	for _, record := range decided {
+		if !record.Qualifies {
+			record.Outcome = output.OutcomeSkipped
+			records.Write(record)
 		}
 	}
 
 	if len(qualifyingBranches) == 0 {
-		fmt.Println("No branches qualify for deletion.")
+		fmt.Fprintln(out, "No branches qualify for deletion.")
 		if len(errors) > 0 {
-			fmt.Printf("\nEncountered %d error(s) during processing:\n", len(errors))
+			fmt.Fprintf(out, "\nEncountered %d error(s) during processing:\n", len(errors))
 			for _, err := range errors {
-				fmt.Printf("  - %s\n", err)
+				fmt.Fprintf(out, "  - %s\n", err)
 			}
 		}
 		return
 	}
 
-	fmt.Printf("\nFound %d branch(es) qualifying for deletion:\n", len(qualifyingBranches))
+	fmt.Fprintf(out, "\nFound %d branch(es) qualifying for deletion:\n", len(qualifyingBranches))
 	for _, branch := range qualifyingBranches {
 		branchType := "local"
 		if branch.IsRemote {
 			branchType = "remote"
 		}
 		age := time.Since(branch.LastCommitAt)
-		fmt.Printf("  - %s (%s): last commit %s ago by %s (%s)\n",
+		fmt.Fprintf(out, "  - %s (%s): last commit %s ago by %s (%s)\n",
 			branch.Name, branchType, formatDuration(age), branch.AuthorUserName, branch.LastCommitSHA)
 
 		if *verbose {
-			fmt.Printf("    Author email: %s\n", branch.AuthorEmail)
+			fmt.Fprintf(out, "    Author email: %s\n", branch.AuthorEmail)
+			if branch.MergeMethod != git.MergeMethodNone {
+				fmt.Fprintf(out, "    Merged via: %s\n", branch.MergeMethod)
+			}
+			if branch.Upstream != "" {
+				fmt.Fprintf(out, "    Upstream: %s%s\n", branch.Upstream, formatTracking(branch))
+			}
 			if !branch.IsRemote {
-				fmt.Printf("    Has unpushed commits: %v\n", branch.HasUnpushedCommits)
+				fmt.Fprintf(out, "    Has unpushed commits: %v\n", branch.HasUnpushedCommits)
 			}
 			if branch.Remote != "" {
-				fmt.Printf("    Remote: %s\n", branch.Remote)
+				fmt.Fprintf(out, "    Remote: %s\n", branch.Remote)
 			}
 		}
 	}
 
+	recordFor := func(branch *git.Branch) *output.Branch {
+		return decisions[branchKey(branch)]
+	}
+
+	if *planFile != "" {
+		cleanPlan := &plan.Plan{
+			Version:       plan.Version,
+			CreatedAt:     now.UTC(),
+			Repository:    repoRoot,
+			Action:        plan.ActionDelete,
+			ArchiveRemote: archiveRemote,
+			Force:         *force,
+		}
+		if archive {
+			cleanPlan.Action = plan.ActionArchive
+		}
+		writePlan(out, *planFile, cleanPlan, qualifyingBranches, branchService, recordFor, records)
+		return
+	}
+
 	if *dryRun {
-		fmt.Printf("\n[DRY RUN] Would delete %d branch(es). No actual deletions performed.\n", len(qualifyingBranches))
+		if archive {
+			fmt.Fprintf(out, "\n[DRY RUN] Would archive %d branch(es) under %s. No actual changes performed.\n", len(qualifyingBranches), git.ArchiveRefFor(time.Now(), ""))
+			for _, branch := range qualifyingBranches {
+				record := decisions[branchKey(branch)]
+				record.Outcome = output.OutcomeWouldArchive
+				record.ArchiveRef = git.ArchiveRefForBranch(now, branch)
+				records.Write(record)
+			}
+		} else {
+			fmt.Fprintf(out, "\n[DRY RUN] Would delete %d branch(es). No actual deletions performed.\n", len(qualifyingBranches))
+			for _, branch := range qualifyingBranches {
+				record := decisions[branchKey(branch)]
+				record.Outcome = output.OutcomeWouldDelete
+				if err := branchService.CheckDeleteSafety(branch); err != nil {
+					record.Error = err.Error()
+					if *force {
+						record.Outcome = output.OutcomeWouldForceDelete
+						fmt.Fprintf(out, "  ! %s would be force deleted: %v\n", branch.Name, err)
+					} else {
+						record.Outcome = output.OutcomeWouldRefuse
+						fmt.Fprintf(out, "  ! %s would be refused: %v\n", branch.Name, err)
+					}
+				}
+				records.Write(record)
+			}
+		}
 		if len(errors) > 0 {
-			fmt.Printf("\nEncountered %d error(s) during processing:\n", len(errors))
+			fmt.Fprintf(out, "\nEncountered %d error(s) during processing:\n", len(errors))
 			for _, err := range errors {
-				fmt.Printf("  - %s\n", err)
+				fmt.Fprintf(out, "  - %s\n", err)
 			}
 		}
 		return
 	}
 
-	fmt.Printf("\nDeleting %d branch(es)...\n", len(qualifyingBranches))
-	var successCount, failCount int
-	var deletionErrors []string
-
-	for _, branch := range qualifyingBranches {
-		branchType := "local"
-		if branch.IsRemote {
-			branchType = "remote"
+	reader := bufio.NewReader(os.Stdin)
+	interactive := !*yes && stdinIsTerminal()
+	if interactive {
+		selectedBranches, confirmed := reviewSelection(out, reader, qualifyingBranches, branchService)
+		selected := make(map[string]bool)
+		for _, branch := range selectedBranches {
+			selected[branchKey(branch)] = true
 		}
-
-		if err := branchService.DeleteBranch(branch); err != nil {
-			failCount++
-			errorMsg := fmt.Sprintf("Failed to delete %s branch %s: %v", branchType, branch.Name, err)
-			deletionErrors = append(deletionErrors, errorMsg)
-			fmt.Printf("  ✗ %s\n", errorMsg)
-		} else {
-			successCount++
-			fmt.Printf("  ✓ Deleted %s branch: %s\n", branchType, branch.Name)
+		for _, branch := range qualifyingBranches {
+			if !confirmed || !selected[branchKey(branch)] {
+				record := decisions[branchKey(branch)]
+				record.Outcome = output.OutcomeDeselected
+				records.Write(record)
+			}
+		}
+		if !confirmed {
+			fmt.Fprintln(out, "Aborted. No branches were changed.")
+			return
+		}
+		if len(selectedBranches) == 0 {
+			fmt.Fprintln(out, "No branches selected. No branches were changed.")
+			return
 		}
+		qualifyingBranches = selectedBranches
 	}
 
-	fmt.Printf("\n=== Deletion Summary ===\n")
-	fmt.Printf("Successfully deleted: %d branch(es)\n", successCount)
-	if failCount > 0 {
-		fmt.Printf("Failed to delete: %d branch(es)\n", failCount)
-		fmt.Println("\nDeletion errors:")
-		for _, err := range deletionErrors {
-			fmt.Printf("  - %s\n", err)
-		}
+	remover := newRemoval(branchService, repoRoot, now, records, out)
+	remover.archive, remover.archiveRemote, remover.force = archive, archiveRemote, *force
+	remover.jobs = cfg.Jobs
+	if !*force && interactive {
+		remover.reader = reader
 	}
+	remover.run(qualifyingBranches, recordFor)
+	remover.printSummary()
 
 	if len(errors) > 0 {
-		fmt.Printf("\nProcessing errors (%d):\n", len(errors))
+		fmt.Fprintf(out, "\nProcessing errors (%d):\n", len(errors))
 		for _, err := range errors {
-			fmt.Printf("  - %s\n", err)
+			fmt.Fprintf(out, "  - %s\n", err)
 		}
 	}
 
-	fmt.Printf("\nProcessed %d total merged branch(es) across %d base branch(es).\n", totalProcessed, len(cfg.BaseBranches))
+	fmt.Fprintf(out, "\nProcessed %d total merged branch(es) across %d base branch(es).\n", totalProcessed, len(cfg.BaseBranches))
+	remover.printRunID()
+	remover.exitIfInterrupted()
 }
 
 func handleListCommand(args []string, configService config.Service) {
 	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
 	localOnly := listFlags.Bool("local-only", false, "Only show local branches")
 	remoteOnly := listFlags.Bool("remote-only", false, "Only show remote branches")
+	allRemote := listFlags.Bool("all-remote", false, "Also show remote branches without a local copy, with their author and whether you have a local copy")
+	fetchFlag := listFlags.Bool("fetch", false, "Fetch and prune the remotes before listing branches")
+	outputFlag := listFlags.String("output", string(output.FormatText), "Output format: text, json or ndjson (human-readable text then goes to stderr)")
+	formatFlag := listFlags.String("format", "", "Print each branch with a Go template, e.g. '{{.Name}}\\t{{.AuthorEmail}}\\t{{.Age}}'")
+	overrides := addConfigOverrideFlags(listFlags)
 
 	listFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s list [OPTIONS]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "List all branches with status information.\n\n")
-		fmt.Fprintf(os.Stderr, "Shows branch name, current status, remote status, merge status, and last commit time.\n")
+		fmt.Fprintf(os.Stderr, "Shows branch name, current status, remote status, the configured remotes it exists\n")
+		fmt.Fprintf(os.Stderr, "on (REMOTES), merge status, last commit time, commits ahead/behind each base branch,\n")
+		fmt.Fprintf(os.Stderr, "commits in no base branch (UNIQUE) and the subject of the last commit. Unmerged\n")
+		fmt.Fprintf(os.Stderr, "branches without unique commits are shown as 'no unique commits'.\n")
+		fmt.Fprintf(os.Stderr, "With --all-remote, branches of the push remotes you never checked out are shown too,\n")
+		fmt.Fprintf(os.Stderr, "with their AUTHOR and a LOCAL COPY column; a remote branch's LAST UPDATE is the age\n")
+		fmt.Fprintf(os.Stderr, "of its tip on the remote.\n")
 		fmt.Fprintf(os.Stderr, "Branches are sorted by most recent commit first.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		listFlags.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nGlobal options like --verbose are also available.\n")
+		fmt.Fprintf(os.Stderr, "\nTemplate fields: Name, Type, IsCurrent, IsRemote, IsMerged, MergeMethod, MergeStatus,\n")
+		fmt.Fprintf(os.Stderr, "MergedInto, SHA, Subject, Author, AuthorEmail, LastCommitAt, Age, Remote, Remotes,\n")
+		fmt.Fprintf(os.Stderr, "HasLocal, Upstream, UpstreamGone, Ahead, Behind, Divergence (Base, Ahead, Behind\n")
+		fmt.Fprintf(os.Stderr, "per base branch), UniqueCommits, Qualifies and Reasons. Functions: ago TIME,\n")
+		fmt.Fprintf(os.Stderr, "short NAME, join SEP LIST.\n")
+		fmt.Fprintf(os.Stderr, "\nEvery config key can also be overridden with a %s* variable, e.g. %sMAX_AGE=14d.\n", config.EnvPrefix, config.EnvPrefix)
+		fmt.Fprintf(os.Stderr, "Global options like --verbose are also available.\n")
 	}
 
 	listFlags.Parse(args)
+	format := parseOutputFormat(*outputFlag)
+	var formatTemplate *output.Template
+	if *formatFlag != "" {
+		if format != output.FormatText {
+			errors.FatalError(errors.ExitGeneral, "--format cannot be combined with --output %s", format)
+		}
+		var err error
+		if formatTemplate, err = output.ParseTemplate(*formatFlag); err != nil {
+			errors.FatalError(errors.ExitGeneral, "%v", err)
+		}
+		format = output.FormatTemplate
+	}
 
 	if !configService.IsOnboarded() {
 		errors.FatalError(errors.ExitConfig, "Repository not configured. Run 'clean-git config' first")
 	}
+	if !overrides.apply(configService) {
+		return
+	}
+	records, out := newRecordWriter(format, formatTemplate)
+	defer closeRecordWriter(records)
 
 	cfg := configService.Config()
 	if cfg == nil {
-		errors.FatalError(errors.ExitConfig, "Failed to load configuration")
+		fatalWithRecords(records, errors.ExitConfig, "Failed to load configuration")
 	}
 
-	branchService := git.NewBranchService(cfg.RemoteName)
+	branchService := newBranchService(cfg)
+	refreshRemotes(out, branchService, cfg, *fetchFlag || cfg.Fetch, time.Now())
 
 	allBranches, err := branchService.GetBranchesWithTrackedRemotes()
 	if err != nil {
-		errors.FatalError(errors.ExitGit, "Failed to get branches: %v", err)
+		fatalWithRecords(records, errors.ExitGit, "Failed to get branches: %v", err)
+	}
+	if *allRemote {
+		remoteBranches, err := branchService.GetRemoteBranches()
+		if err != nil {
+			fatalWithRecords(records, errors.ExitGit, "Failed to get remote branches: %v", err)
+		}
+		for _, branch := range remoteBranches {
+			// The others are already there as tracked remote branches
+			if !branch.HasLocal {
+				allBranches = append(allBranches, branch)
+			}
+		}
 	}
 
 	if *verbose {
-		fmt.Printf("Found %d total branches\n", len(allBranches))
+		fmt.Fprintf(out, "Found %d total branches\n", len(allBranches))
 	}
 
-	mergedBranchMap := make(map[string]map[string]time.Time)
+	mergedBranchMap := make(map[string]map[string]git.Branch)
+	partialBranchMap := make(map[string]map[string]git.Branch)
+	var existingBases []string
 
 	for _, baseBranch := range cfg.BaseBranches {
 		if *verbose {
-			fmt.Printf("Checking merged branches for base: %s\n", baseBranch)
+			fmt.Fprintf(out, "Checking merged branches for base: %s\n", baseBranch)
 		}
 
 		exists, err := branchService.BranchExists(baseBranch)
@@ -369,12 +547,15 @@ func handleListCommand(args []string, configService config.Service) {
 		}
 		if !exists {
 			if *verbose {
-				fmt.Printf("Base branch '%s' not found, skipping\n", baseBranch)
+				fmt.Fprintf(out, "Base branch '%s' not found, skipping\n", baseBranch)
 			}
 			continue
 		}
+		existingBases = append(existingBases, baseBranch)
 
-		mergedBranches, err := branchService.GetMergedBranches(baseBranch)
+		// One patch-id comparison per branch serves both the merged and the
+		// partially merged status
+		mergedBranches, partialBranches, err := branchService.GetMergeStatus(baseBranch)
 		if err != nil {
 			if *verbose {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to get merged branches for %s: %v\n", baseBranch, err)
@@ -383,10 +564,19 @@ func handleListCommand(args []string, configService config.Service) {
 		}
 
 		if mergedBranchMap[baseBranch] == nil {
-			mergedBranchMap[baseBranch] = make(map[string]time.Time)
+			mergedBranchMap[baseBranch] = make(map[string]git.Branch)
 		}
 		for _, branch := range mergedBranches {
-			mergedBranchMap[baseBranch][branch.Name] = branch.LastCommitAt
+			mergedBranchMap[baseBranch][branch.Name] = branch
+		}
+
+		if cfg.DetectRebaseMerges {
+			if partialBranchMap[baseBranch] == nil {
+				partialBranchMap[baseBranch] = make(map[string]git.Branch)
+			}
+			for _, branch := range partialBranches {
+				partialBranchMap[baseBranch][branch.Name] = branch
+			}
 		}
 	}
 
@@ -406,29 +596,55 @@ func handleListCommand(args []string, configService config.Service) {
 	})
 
 	if len(filteredBranches) == 0 {
-		fmt.Println("No branches found.")
+		fmt.Fprintln(out, "No branches found.")
 		return
 	}
 
+	divergenceBases := existingBases
+	if err := branchService.AddDivergence(filteredBranches, existingBases); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to compare branches with the base branches: %v\n", err)
+		divergenceBases = nil
+		for i := range filteredBranches {
+			filteredBranches[i].Divergence = nil
+		}
+	}
+
 	maxNameLen := 0
 	maxTypeLen := 0
+	maxRemotesLen := 0
+	maxAuthorLen := 0
+	maxLocalCopyLen := 0
 	maxStatusLen := 0
 	maxAgeLen := 0
 	maxMergeAgeLen := 0
 	maxMergedIntoLen := 0
+	maxUniqueLen := len("UNIQUE")
+	maxDivergenceLens := make([]int, len(divergenceBases))
+	for i, base := range divergenceBases {
+		maxDivergenceLens[i] = len("AHEAD/BEHIND " + base)
+	}
 
 	type displayBranch struct {
 		branch      git.Branch
 		indicator   string
 		branchType  string
+		remotes     string
+		localCopy   string
 		mergeStatus string
+		isMerged    bool
 		ageStr      string
 		mergeAgeStr string
 		mergedInto  string
+		divergence  []string
+		unique      string
 	}
 
 	var displayBranches []displayBranch
 
+	// Decide like clean would, without clean's --local-only/--remote-only
+	now := time.Now()
+	branchEvaluator := evaluator.New(cfg, branchService, evaluator.Options{Now: now})
+
 	for _, branch := range filteredBranches {
 		indicator := " "
 		if branch.IsCurrent {
@@ -440,23 +656,58 @@ func handleListCommand(args []string, configService config.Service) {
 		}
 
 		mergeStatus := "not merged"
+		isMerged := false
 		var mergeTime time.Time
 		var mergedInto string
+		record := output.NewBranch(branch, now)
 
 		for baseBranch, mergedBranches := range mergedBranchMap {
-			if mergeTimeForBranch, isMerged := mergedBranches[branch.Name]; isMerged {
+			if mergedBranch, found := mergedBranches[branch.Name]; found {
 				mergeStatus = "merged"
-				mergeTime = mergeTimeForBranch
+				if mergedBranch.MergeMethod == git.MergeMethodSquash || mergedBranch.MergeMethod == git.MergeMethodRebase {
+					mergeStatus = fmt.Sprintf("merged (%s)", mergedBranch.MergeMethod)
+				}
+				isMerged = true
+				mergeTime = mergedBranch.LastCommitAt
 				mergedInto = baseBranch
+				record.IsMerged, record.MergeMethod = true, mergedBranch.MergeMethod
+				record.MergeStatus, record.MergedInto = output.MergeStatusMerged, baseBranch
 				break
 			}
 		}
+		if !isMerged && !branch.IsRemote {
+			for baseBranch, partialBranches := range partialBranchMap {
+				if partialBranch, found := partialBranches[branch.Name]; found {
+					mergeStatus = fmt.Sprintf("partial (%d of %d in %s)", partialBranch.CommitsUpstream, partialBranch.CommitsUnique, baseBranch)
+					record.MergeStatus = output.MergeStatusPartial
+					record.CommitsUnique, record.CommitsUpstream = partialBranch.CommitsUnique, partialBranch.CommitsUpstream
+					break
+				}
+			}
+		}
+
+		if !isMerged && record.MergeStatus == output.MergeStatusNotMerged && branch.Divergence != nil && branch.UniqueCommits == 0 {
+			mergeStatus = output.MergeStatusNoUnique
+			record.MergeStatus = output.MergeStatusNoUnique
+		}
+
+		qualifies := isMerged && recordDecision(record, branchEvaluator.Evaluate(&branch, "merged into "+mergedInto, time.Duration(cfg.MaxAge)))
+		if !qualifies && cfg.CleanGone && branch.UpstreamGone && !branch.IsRemote {
+			qualifies = recordDecision(record, branchEvaluator.Evaluate(&branch, "upstream gone", time.Duration(cfg.GoneMaxAge)))
+		}
+		if !qualifies && cfg.CleanAllRemote && branchEvaluator.HasNoLocalCopy(&branch) {
+			recordDecision(record, branchEvaluator.EvaluateNoLocalCopy(&branch, isMerged))
+		}
+		if !isMerged && !record.Qualifies {
+			record.Reasons = append([]string{"not merged into any base branch"}, record.Reasons...)
+		}
+		records.Write(record)
 
 		age := time.Since(branch.LastCommitAt)
 		ageStr := formatDuration(age) + " ago"
 
 		mergeAgeStr := ""
-		if mergeStatus == "merged" && !mergeTime.IsZero() {
+		if isMerged && !mergeTime.IsZero() {
 			mergeAge := time.Since(mergeTime)
 			mergeAgeStr = formatDuration(mergeAge) + " ago"
 		}
@@ -465,11 +716,21 @@ func handleListCommand(args []string, configService config.Service) {
 			branch:      branch,
 			indicator:   indicator,
 			branchType:  branchType,
+			remotes:     strings.Join(branch.Remotes, ","),
 			mergeStatus: mergeStatus,
+			isMerged:    isMerged,
 			ageStr:      ageStr,
 			mergeAgeStr: mergeAgeStr,
 			mergedInto:  mergedInto,
 		})
+		if branch.Divergence != nil {
+			db := &displayBranches[len(displayBranches)-1]
+			for i, divergence := range branch.Divergence {
+				db.divergence = append(db.divergence, fmt.Sprintf("+%d/-%d", divergence.Ahead, divergence.Behind))
+				maxDivergenceLens[i] = max(maxDivergenceLens[i], len(db.divergence[i]))
+			}
+			db.unique = strconv.Itoa(branch.UniqueCommits)
+		}
 
 		if len(branch.Name) > maxNameLen {
 			maxNameLen = len(branch.Name)
@@ -477,6 +738,20 @@ func handleListCommand(args []string, configService config.Service) {
 		if len(branchType) > maxTypeLen {
 			maxTypeLen = len(branchType)
 		}
+		if len(branch.Remotes) > 0 {
+			maxRemotesLen = max(maxRemotesLen, len("REMOTES"), len(displayBranches[len(displayBranches)-1].remotes))
+		}
+		if *allRemote {
+			db := &displayBranches[len(displayBranches)-1]
+			if branch.IsRemote {
+				db.localCopy = "no"
+				if branch.HasLocal {
+					db.localCopy = "yes"
+				}
+			}
+			maxAuthorLen = max(maxAuthorLen, len("AUTHOR"), len(branch.AuthorUserName))
+			maxLocalCopyLen = len("LOCAL COPY")
+		}
 		if len(mergeStatus) > maxStatusLen {
 			maxStatusLen = len(mergeStatus)
 		}
@@ -491,8 +766,19 @@ func handleListCommand(args []string, configService config.Service) {
 		}
 	}
 
+	if format.IsMachine() {
+		return
+	}
+
 	maxNameLen += 2
 	maxTypeLen += 2
+	if maxRemotesLen > 0 {
+		maxRemotesLen += 2
+	}
+	if maxAuthorLen > 0 {
+		maxAuthorLen += 2
+		maxLocalCopyLen += 2
+	}
 	maxStatusLen += 2
 	maxAgeLen += 2
 	if maxMergeAgeLen > 0 {
@@ -501,39 +787,63 @@ func handleListCommand(args []string, configService config.Service) {
 	if maxMergedIntoLen > 0 {
 		maxMergedIntoLen += 2
 	}
+	maxUniqueLen += 2
+	for i := range maxDivergenceLens {
+		maxDivergenceLens[i] += 2
+	}
 
-	fmt.Printf("\n=== Branch List (%d branches) ===\n", len(filteredBranches))
-	fmt.Printf("Sorted by most recent commit first\n\n")
+	fmt.Fprintf(out, "\n=== Branch List (%d branches) ===\n", len(filteredBranches))
+	fmt.Fprintf(out, "Sorted by most recent commit first\n\n")
 
-	fmt.Printf("  %-*s %-*s %-*s %-*s",
-		maxNameLen, "BRANCH",
-		maxTypeLen, "TYPE",
-		maxStatusLen, "STATUS",
-		maxAgeLen, "LAST UPDATE")
+	fmt.Fprintf(out, "  %-*s %-*s", maxNameLen, "BRANCH", maxTypeLen, "TYPE")
+	if maxRemotesLen > 0 {
+		fmt.Fprintf(out, " %-*s", maxRemotesLen, "REMOTES")
+	}
+	if maxAuthorLen > 0 {
+		fmt.Fprintf(out, " %-*s %-*s", maxLocalCopyLen, "LOCAL COPY", maxAuthorLen, "AUTHOR")
+	}
+	fmt.Fprintf(out, " %-*s %-*s", maxStatusLen, "STATUS", maxAgeLen, "LAST UPDATE")
 
 	if maxMergeAgeLen > 0 {
-		fmt.Printf(" %-*s %-*s", maxMergeAgeLen, "MERGED", maxMergedIntoLen, "INTO")
+		fmt.Fprintf(out, " %-*s %-*s", maxMergeAgeLen, "MERGED", maxMergedIntoLen, "INTO")
+	}
+	for i, base := range divergenceBases {
+		fmt.Fprintf(out, " %-*s", maxDivergenceLens[i], "AHEAD/BEHIND "+base)
 	}
-	fmt.Printf("\n")
+	if len(divergenceBases) > 0 {
+		fmt.Fprintf(out, " %-*s", maxUniqueLen, "UNIQUE")
+	}
+	fmt.Fprintf(out, " SUBJECT\n")
 
-	fmt.Printf("  %s %s %s %s",
-		strings.Repeat("-", maxNameLen),
-		strings.Repeat("-", maxTypeLen),
-		strings.Repeat("-", maxStatusLen),
-		strings.Repeat("-", maxAgeLen))
+	fmt.Fprintf(out, "  %s %s", strings.Repeat("-", maxNameLen), strings.Repeat("-", maxTypeLen))
+	if maxRemotesLen > 0 {
+		fmt.Fprintf(out, " %s", strings.Repeat("-", maxRemotesLen))
+	}
+	if maxAuthorLen > 0 {
+		fmt.Fprintf(out, " %s %s", strings.Repeat("-", maxLocalCopyLen), strings.Repeat("-", maxAuthorLen))
+	}
+	fmt.Fprintf(out, " %s %s", strings.Repeat("-", maxStatusLen), strings.Repeat("-", maxAgeLen))
 
 	if maxMergeAgeLen > 0 {
-		fmt.Printf(" %s %s", strings.Repeat("-", maxMergeAgeLen), strings.Repeat("-", maxMergedIntoLen))
+		fmt.Fprintf(out, " %s %s", strings.Repeat("-", maxMergeAgeLen), strings.Repeat("-", maxMergedIntoLen))
+	}
+	for i := range divergenceBases {
+		fmt.Fprintf(out, " %s", strings.Repeat("-", maxDivergenceLens[i]))
 	}
-	fmt.Printf("\n")
+	if len(divergenceBases) > 0 {
+		fmt.Fprintf(out, " %s", strings.Repeat("-", maxUniqueLen))
+	}
+	fmt.Fprintf(out, " %s\n", strings.Repeat("-", len("SUBJECT")))
 
 	for _, db := range displayBranches {
-		fmt.Printf("%s %-*s %-*s %-*s %-*s",
-			db.indicator,
-			maxNameLen, db.branch.Name,
-			maxTypeLen, db.branchType,
-			maxStatusLen, db.mergeStatus,
-			maxAgeLen, db.ageStr)
+		fmt.Fprintf(out, "%s %-*s %-*s", db.indicator, maxNameLen, db.branch.Name, maxTypeLen, db.branchType)
+		if maxRemotesLen > 0 {
+			fmt.Fprintf(out, " %-*s", maxRemotesLen, db.remotes)
+		}
+		if maxAuthorLen > 0 {
+			fmt.Fprintf(out, " %-*s %-*s", maxLocalCopyLen, db.localCopy, maxAuthorLen, db.branch.AuthorUserName)
+		}
+		fmt.Fprintf(out, " %-*s %-*s", maxStatusLen, db.mergeStatus, maxAgeLen, db.ageStr)
 
 		if maxMergeAgeLen > 0 {
 			mergeInfo := ""
@@ -542,16 +852,28 @@ func handleListCommand(args []string, configService config.Service) {
 				mergeInfo = db.mergeAgeStr
 				intoInfo = db.mergedInto
 			}
-			fmt.Printf(" %-*s %-*s", maxMergeAgeLen, mergeInfo, maxMergedIntoLen, intoInfo)
+			fmt.Fprintf(out, " %-*s %-*s", maxMergeAgeLen, mergeInfo, maxMergedIntoLen, intoInfo)
 		}
-
-		fmt.Printf("\n")
+		for i := range divergenceBases {
+			cell := ""
+			if i < len(db.divergence) {
+				cell = db.divergence[i]
+			}
+			fmt.Fprintf(out, " %-*s", maxDivergenceLens[i], cell)
+		}
+		if len(divergenceBases) > 0 {
+			fmt.Fprintf(out, " %-*s", maxUniqueLen, db.unique)
+		}
+		fmt.Fprintf(out, " %s\n", truncateSubject(db.branch.Subject, 60))
 
 		if *verbose {
-			fmt.Printf("    Author: %s (%s)\n", db.branch.AuthorUserName, db.branch.AuthorEmail)
-			fmt.Printf("    SHA: %s\n", db.branch.LastCommitSHA)
+			fmt.Fprintf(out, "    Author: %s (%s)\n", db.branch.AuthorUserName, db.branch.AuthorEmail)
+			fmt.Fprintf(out, "    SHA: %s\n", db.branch.LastCommitSHA)
 			if db.branch.Remote != "" {
-				fmt.Printf("    Remote: %s\n", db.branch.Remote)
+				fmt.Fprintf(out, "    Remote: %s\n", db.branch.Remote)
+			}
+			if db.branch.Upstream != "" {
+				fmt.Fprintf(out, "    Upstream: %s%s\n", db.branch.Upstream, formatTracking(&db.branch))
 			}
 		}
 	}
@@ -570,34 +892,66 @@ func handleListCommand(args []string, configService config.Service) {
 		if db.branch.IsCurrent {
 			currentBranchName = db.branch.Name
 		}
-		if db.mergeStatus == "merged" {
+		if db.isMerged {
 			mergedCount++
 		}
 	}
 
-	fmt.Printf("\n=== Summary ===\n")
-	fmt.Printf("Total branches: %d\n", len(filteredBranches))
-	fmt.Printf("Local: %d, Remote: %d\n", localCount, remoteCount)
-	fmt.Printf("Merged: %d, Not merged: %d\n", mergedCount, len(filteredBranches)-mergedCount)
+	fmt.Fprintf(out, "\n=== Summary ===\n")
+	fmt.Fprintf(out, "Total branches: %d\n", len(filteredBranches))
+	fmt.Fprintf(out, "Local: %d, Remote: %d\n", localCount, remoteCount)
+	fmt.Fprintf(out, "Merged: %d, Not merged: %d\n", mergedCount, len(filteredBranches)-mergedCount)
 	if currentBranchName != "" {
-		fmt.Printf("Current branch: %s\n", currentBranchName)
+		fmt.Fprintf(out, "Current branch: %s\n", currentBranchName)
 	}
 }
 
 // ad-hoc config flow
 func handleConfigCommand(args []string, configService config.Service) {
 	configFlags := flag.NewFlagSet("config", flag.ExitOnError)
+	shared := configFlags.Bool("shared", false, "Write the team configuration ("+config.SharedConfigFile+" at the repository root)")
+	personal := configFlags.Bool("personal", false, "Write your personal configuration for this repository (default)")
 
 	configFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s config [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s config [OPTIONS] [SUBCOMMAND]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Set up or update clean-git configuration for this repository.\n\n")
-		fmt.Fprintf(os.Stderr, "This command will guide you through configuring clean-git\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
+		fmt.Fprintf(os.Stderr, "Without a subcommand this command will guide you through configuring clean-git.\n\n")
+		fmt.Fprintf(os.Stderr, "Subcommands:\n")
+		fmt.Fprintf(os.Stderr, "  get KEY                 Print the value of KEY\n")
+		fmt.Fprintf(os.Stderr, "  set KEY VALUE           Set KEY, with the same validation as the prompts\n")
+		fmt.Fprintf(os.Stderr, "  unset KEY               Remove KEY so its value is inherited again\n")
+		fmt.Fprintf(os.Stderr, "  list [--show-origin]    Print every key and its value\n")
+		fmt.Fprintf(os.Stderr, "  list-profiles           Show each profile and the settings it changes\n")
+		fmt.Fprintf(os.Stderr, "  validate                Check the configuration and lint it for likely mistakes\n")
+		fmt.Fprintf(os.Stderr, "  edit                    Open the config file in $EDITOR and validate it before saving\n\n")
+		fmt.Fprintf(os.Stderr, "Keys:\n")
+		for _, key := range config.Keys() {
+			fmt.Fprintf(os.Stderr, "  %-20s %s\n", key.Name, key.Description)
+		}
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		configFlags.PrintDefaults()
 	}
 
 	configFlags.Parse(args)
 
+	if *shared && *personal {
+		errors.FatalError(errors.ExitGeneral, "--shared and --personal are mutually exclusive")
+	}
+	if *shared {
+		if err := configService.SetScope(config.ScopeShared); err != nil {
+			errors.FatalError(errors.ExitConfig, "Failed to select shared configuration: %v", err)
+		}
+	}
+
+	if configFlags.NArg() > 0 {
+		handleConfigSubcommand(configFlags.Arg(0), configFlags.Args()[1:], configService, configFlags.Usage)
+		return
+	}
+
+	if *shared {
+		fmt.Printf("Editing the team configuration in %s (commit it to share it).\n", config.SharedConfigFile)
+	}
+
 	if err := runInteractiveConfiguration(configService); err != nil {
 		errors.FatalError(errors.ExitConfig, "Configuration failed: %v", err)
 	}
@@ -605,130 +959,201 @@ func handleConfigCommand(args []string, configService config.Service) {
 	fmt.Println("\nConfiguration updated successfully!")
 }
 
-func parseMaxAge(input string, defaultDuration time.Duration) (time.Duration, error) {
-	if input == "" {
-		return defaultDuration, nil
+// showLockedSetting prints a setting the team config locks instead of prompting for it.
+func showLockedSetting(configService config.Service, key, label, value string) bool {
+	if !configService.IsLocked(key) {
+		return false
 	}
+	fmt.Printf("%s: %s (set by %s)\n", label, value, config.SharedConfigFile)
+	return true
+}
 
-	days, err := strconv.Atoi(input)
-	if err != nil {
-		return 0, fmt.Errorf("invalid number '%s': expected integer number of days", input)
-	}
-	if days < 0 {
-		return 0, fmt.Errorf("days must be positive, got %d", days)
+func parseMaxAge(input string, defaultDuration config.Duration) (config.Duration, error) {
+	if input == "" {
+		return defaultDuration, nil
 	}
-	return time.Duration(days) * 24 * time.Hour, nil
+	return config.ParseDuration(input)
 }
 
 func parseCommaSeparatedList(input string, defaultList []string, validateRegex bool) ([]string, error) {
 	if input == "" {
 		return defaultList, nil
 	}
+	return config.ParseList(input, validateRegex)
+}
 
-	parts := strings.Split(input, ",")
-	result := make([]string, 0, len(parts))
+// truncateSubject shortens a commit subject to at most limit characters.
+func truncateSubject(subject string, limit int) string {
+	runes := []rune(subject)
+	if len(runes) <= limit {
+		return subject
+	}
+	return string(runes[:limit-1]) + "…"
+}
 
-	for _, part := range parts {
-		trimmed := strings.TrimSpace(part)
-		if trimmed == "" {
-			continue
-		}
+// formatTracking renders a branch's upstream state the way `git branch -vv` does.
+func formatTracking(branch *git.Branch) string {
+	switch {
+	case branch.UpstreamGone:
+		return " [gone]"
+	case branch.Ahead > 0 && branch.Behind > 0:
+		return fmt.Sprintf(" [ahead %d, behind %d]", branch.Ahead, branch.Behind)
+	case branch.Ahead > 0:
+		return fmt.Sprintf(" [ahead %d]", branch.Ahead)
+	case branch.Behind > 0:
+		return fmt.Sprintf(" [behind %d]", branch.Behind)
+	}
+	return ""
+}
 
-		if validateRegex {
-			if _, err := regexp.Compile(trimmed); err != nil {
-				return nil, fmt.Errorf("invalid regex pattern '%s': %w", trimmed, err)
-			}
+// recordDecision stores decision in record and reports whether the branch
+// qualified. A skipped branch keeps the reasons of every rule that selected it.
+func recordDecision(record *output.Branch, decision evaluator.Decision) bool {
+	if decision.Qualifies {
+		record.Qualifies = true
+		record.Reasons = []string{decision.Rule}
+		return true
+	}
+	reason := decision.Rule + " but " + decision.Reason()
+	for _, existing := range record.Reasons {
+		if existing == reason {
+			return false
 		}
+	}
+	record.Reasons = append(record.Reasons, reason)
+	return false
+}
+
+// branchKey tells local and remote branches of the same name apart
+func branchKey(branch *git.Branch) string {
+	return fmt.Sprintf("%t/%s", branch.IsRemote, branch.Name)
+}
 
-		result = append(result, trimmed)
+func newBranchService(cfg *config.Config) git.BranchService {
+	remoteName := cfg.RemoteName
+	if len(cfg.Remotes) > 0 {
+		// Branches without a remote belong to the first push remote instead
+		remoteName = ""
 	}
+	return git.NewBranchServiceWithOptions(git.Options{
+		RemoteName:         remoteName,
+		Remotes:            gitRemotes(cfg.EffectiveRemotes()),
+		DetectSquashMerges: cfg.DetectSquashMerges,
+		DetectRebaseMerges: cfg.DetectRebaseMerges,
+		Context:            interrupts.abort,
+		Timeout:            time.Duration(cfg.GitTimeout),
+		Jobs:               cfg.Jobs,
+		Prompt:             stdinIsTerminal(),
+	})
+}
 
-	if len(result) == 0 {
-		return nil, fmt.Errorf("at least one value is required")
+func gitRemotes(remotes []config.Remote) []git.Remote {
+	result := make([]git.Remote, len(remotes))
+	for i, remote := range remotes {
+		result[i] = git.Remote{
+			Name:        remote.Name,
+			Push:        remote.Has(config.RolePush),
+			MergeSource: remote.Has(config.RoleMergeSource),
+		}
 	}
+	return result
+}
 
-	return result, nil
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
+func confirmForceDelete(w io.Writer, reader *bufio.Reader, unsafeErr *git.UnsafeDeleteError) bool {
+	fmt.Fprintf(w, "  ? %s %s. Force delete anyway? (y/N): ", unsafeErr.Branch, unsafeErr.Reason)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(strings.ToLower(input))
+	return input == "y" || input == "yes"
+}
+
+// formatDuration renders an age in the notation config durations use, in
+// whole days, or whole hours for anything younger.
 func formatDuration(d time.Duration) string {
-	days := int(d.Hours()) / 24
-	if days > 0 {
-		return fmt.Sprintf("%d days", days)
-	}
-	hours := int(d.Hours())
-	if hours > 0 {
-		return fmt.Sprintf("%d hours", hours)
-	}
-	return "< 1 hour"
+	return output.Age(d).String()
 }
 
 func runInteractiveConfiguration(configService config.Service) error {
 	reader := bufio.NewReader(os.Stdin)
 	currentConfig := configService.Config()
-	newConfig := &config.Config{}
+	// Start from the current settings so options without a prompt are preserved
+	updatedConfig := *currentConfig
+	newConfig := &updatedConfig
 
 	fmt.Println("=== Clean-Git Configuration Setup ===")
 	fmt.Println("Let's configure clean-git for your repository.")
 
-	fmt.Printf("Base branches (branches to keep, comma-separated) [%s]: ", strings.Join(currentConfig.BaseBranches, ","))
-	fmt.Println("  Press Enter to keep defaults or type comma-separated list to override")
-	baseBranchesInput, _ := reader.ReadString('\n')
-	baseBranchesInput = strings.TrimSpace(baseBranchesInput)
-
 	var err error
-	newConfig.BaseBranches, err = parseCommaSeparatedList(baseBranchesInput, currentConfig.BaseBranches, false)
-	if err != nil {
-		return fmt.Errorf("invalid base branches input: %w", err)
+	if !showLockedSetting(configService, "baseBranches", "Base branches", strings.Join(currentConfig.BaseBranches, ", ")) {
+		fmt.Printf("Base branches (branches to keep, comma-separated) [%s]: ", strings.Join(currentConfig.BaseBranches, ","))
+		fmt.Println("  Press Enter to keep defaults or type comma-separated list to override")
+		baseBranchesInput, _ := reader.ReadString('\n')
+		baseBranchesInput = strings.TrimSpace(baseBranchesInput)
+
+		newConfig.BaseBranches, err = parseCommaSeparatedList(baseBranchesInput, currentConfig.BaseBranches, false)
+		if err != nil {
+			return fmt.Errorf("invalid base branches input: %w", err)
+		}
 	}
 
-	currentMaxAgeFormatted := formatDuration(currentConfig.MaxAge)
-	fmt.Printf("Maximum age for stale branches [%s]: ", currentMaxAgeFormatted)
-	fmt.Println("  Enter number of days (e.g., 30)")
-	maxAgeInput, _ := reader.ReadString('\n')
-	maxAgeInput = strings.TrimSpace(maxAgeInput)
+	if !showLockedSetting(configService, "maxAge", "Max age", currentConfig.MaxAge.String()) {
+		fmt.Printf("Maximum age for stale branches [%s]: ", currentConfig.MaxAge)
+		fmt.Println("  Enter a duration (e.g., 90d, 2w, 6mo, 1y); a plain number means days")
+		maxAgeInput, _ := reader.ReadString('\n')
+		maxAgeInput = strings.TrimSpace(maxAgeInput)
 
-	newConfig.MaxAge, err = parseMaxAge(maxAgeInput, currentConfig.MaxAge)
-	if err != nil {
-		return fmt.Errorf("invalid max age input: %w", err)
+		newConfig.MaxAge, err = parseMaxAge(maxAgeInput, currentConfig.MaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid max age input: %w", err)
+		}
 	}
 
-	fmt.Printf("Protected branch patterns (regex, comma-separated) [%s]: ", strings.Join(currentConfig.ProtectedRegex, ","))
-	fmt.Println("  Default patterns: release/*, hotfix/* - Press Enter to keep or edit")
-	protectedInput, _ := reader.ReadString('\n')
-	protectedInput = strings.TrimSpace(protectedInput)
+	if !showLockedSetting(configService, "protectedRegex", "Protected patterns", strings.Join(currentConfig.ProtectedRegex, ", ")) {
+		fmt.Printf("Protected branch patterns (regex, comma-separated) [%s]: ", strings.Join(currentConfig.ProtectedRegex, ","))
+		fmt.Println("  Default patterns: release/*, hotfix/* - Press Enter to keep or edit")
+		protectedInput, _ := reader.ReadString('\n')
+		protectedInput = strings.TrimSpace(protectedInput)
 
-	newConfig.ProtectedRegex, err = parseCommaSeparatedList(protectedInput, currentConfig.ProtectedRegex, true)
-	if err != nil {
-		return fmt.Errorf("invalid protected regex patterns: %w", err)
+		newConfig.ProtectedRegex, err = parseCommaSeparatedList(protectedInput, currentConfig.ProtectedRegex, true)
+		if err != nil {
+			return fmt.Errorf("invalid protected regex patterns: %w", err)
+		}
 	}
 
-	fmt.Printf("Include branch patterns (regex, comma-separated) [%s]: ", strings.Join(currentConfig.IncludeRegex, ","))
-	fmt.Println("  Default pattern: .* (matches all) - Press Enter to keep or edit")
-	includeInput, _ := reader.ReadString('\n')
-	includeInput = strings.TrimSpace(includeInput)
+	if !showLockedSetting(configService, "includeRegex", "Include patterns", strings.Join(currentConfig.IncludeRegex, ", ")) {
+		fmt.Printf("Include branch patterns (regex, comma-separated) [%s]: ", strings.Join(currentConfig.IncludeRegex, ","))
+		fmt.Println("  Default pattern: .* (matches all) - Press Enter to keep or edit")
+		includeInput, _ := reader.ReadString('\n')
+		includeInput = strings.TrimSpace(includeInput)
 
-	newConfig.IncludeRegex, err = parseCommaSeparatedList(includeInput, currentConfig.IncludeRegex, true)
-	if err != nil {
-		return fmt.Errorf("invalid include regex patterns: %w", err)
+		newConfig.IncludeRegex, err = parseCommaSeparatedList(includeInput, currentConfig.IncludeRegex, true)
+		if err != nil {
+			return fmt.Errorf("invalid include regex patterns: %w", err)
+		}
 	}
 
-	fmt.Printf("Remote name [%s]: ", currentConfig.RemoteName)
-	fmt.Println("  Default: origin - Press Enter to keep or type new remote name")
-	remoteInput, _ := reader.ReadString('\n')
-	remoteInput = strings.TrimSpace(remoteInput)
-	if remoteInput == "" {
-		newConfig.RemoteName = currentConfig.RemoteName
-	} else {
-		// Basic validation for remote name (no spaces, no special chars except -_)
-		if matched, _ := regexp.MatchString(`^[a-zA-Z0-9_-]+$`, remoteInput); !matched {
-			return fmt.Errorf("invalid remote name '%s': must contain only letters, numbers, hyphens, and underscores", remoteInput)
+	if !showLockedSetting(configService, "remoteName", "Remote name", currentConfig.RemoteName) {
+		fmt.Printf("Remote name [%s]: ", currentConfig.RemoteName)
+		fmt.Println("  Default: origin - Press Enter to keep or type new remote name")
+		remoteInput, _ := reader.ReadString('\n')
+		remoteInput = strings.TrimSpace(remoteInput)
+		if remoteInput == "" {
+			newConfig.RemoteName = currentConfig.RemoteName
+		} else {
+			if err := config.ValidateRemoteName(remoteInput); err != nil {
+				return err
+			}
+			newConfig.RemoteName = remoteInput
 		}
-		newConfig.RemoteName = remoteInput
 	}
 
 	fmt.Println("\n=== Configuration Summary ===")
 	fmt.Printf("Base branches: %s\n", strings.Join(newConfig.BaseBranches, ", "))
-	fmt.Printf("Max age: %s\n", formatDuration(newConfig.MaxAge))
+	fmt.Printf("Max age: %s\n", newConfig.MaxAge.String())
 	fmt.Printf("Protected patterns: %s\n", strings.Join(newConfig.ProtectedRegex, ", "))
 	fmt.Printf("Include patterns: %s\n", strings.Join(newConfig.IncludeRegex, ", "))
 	fmt.Printf("Remote name: %s\n", newConfig.RemoteName)
@@ -750,10 +1175,63 @@ func runInteractiveConfiguration(configService config.Service) error {
 	fmt.Printf("Configuration file: %s\n", configPath)
 	fmt.Println("\nSaved configuration:")
 	fmt.Printf("  • Base branches: %s\n", strings.Join(newConfig.BaseBranches, ", "))
-	fmt.Printf("  • Max age: %s\n", formatDuration(newConfig.MaxAge))
+	fmt.Printf("  • Max age: %s\n", newConfig.MaxAge.String())
 	fmt.Printf("  • Protected patterns: %s\n", strings.Join(newConfig.ProtectedRegex, ", "))
 	fmt.Printf("  • Include patterns: %s\n", strings.Join(newConfig.IncludeRegex, ", "))
 	fmt.Printf("  • Remote name: %s\n", newConfig.RemoteName)
+
+	if newConfig.DefaultProfile == "" && !configService.IsLocked("defaultProfile") {
+		if err := offerDefaultProfile(reader, configService); err != nil {
+			return fmt.Errorf("failed to create profile: %w", err)
+		}
+	}
+
 	fmt.Println("\nYou can now use clean-git to manage your repository branches!")
 	return nil
 }
+
+// offerDefaultProfile lets onboarding create the profile applied when
+// --profile is not given. Other profiles are added to the config file by hand.
+func offerDefaultProfile(reader *bufio.Reader, configService config.Service) error {
+	fmt.Println("\nProfiles bundle settings you switch between with --profile, such as a")
+	fmt.Println("cautious 'safe' cleanup and an aggressive 'sweep' of the remote.")
+	fmt.Print("Name of a default profile to create (press Enter to skip): ")
+	name, _ := reader.ReadString('\n')
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	currentConfig := configService.Config()
+	values := make(map[string]string)
+	if !configService.IsLocked("maxAge") {
+		fmt.Printf("Maximum age for stale branches in %s [%s]: ", name, currentConfig.MaxAge)
+		maxAgeInput, _ := reader.ReadString('\n')
+		if maxAgeInput = strings.TrimSpace(maxAgeInput); maxAgeInput != "" {
+			values["maxAge"] = maxAgeInput
+		}
+	}
+	if !configService.IsLocked("archive") {
+		fmt.Printf("Archive branches instead of deleting them in %s? (y/N): ", name)
+		archiveInput, _ := reader.ReadString('\n')
+		archiveInput = strings.TrimSpace(strings.ToLower(archiveInput))
+		if archiveInput == "y" || archiveInput == "yes" {
+			values["archive"] = "true"
+		}
+	}
+
+	if err := configService.SetProfile(name, values); err != nil {
+		return err
+	}
+	updatedConfig := *configService.Config()
+	updatedConfig.DefaultProfile = name
+	if err := configService.Update(&updatedConfig); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created profile '%s' and made it the default. Run 'clean-git config list-profiles' to review it.\n", name)
+	return nil
+}
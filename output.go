@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/output"
+)
+
+func parseOutputFormat(input string) output.Format {
+	format, err := output.ParseFormat(input)
+	if err != nil {
+		errors.FatalError(errors.ExitGeneral, "%v", err)
+	}
+	return format
+}
+
+// newRecordWriter writes records to stdout, rendered with tmpl in
+// FormatTemplate, and returns where the command prints for people. In the
+// machine formats that is stderr, prompts included, so stdout stays parseable.
+func newRecordWriter(format output.Format, tmpl *output.Template) (*output.Writer, io.Writer) {
+	records := output.NewWriter(format, os.Stdout)
+	if format == output.FormatTemplate {
+		records = output.NewTemplateWriter(tmpl, os.Stdout)
+	}
+	if format.IsMachine() {
+		return records, os.Stderr
+	}
+	return records, os.Stdout
+}
+
+// fatalWithRecords is errors.FatalError for commands with a record writer:
+// exiting skips their deferred Close, so the records are finished here and a
+// JSON document on stdout stays valid.
+func fatalWithRecords(records *output.Writer, code errors.ExitCode, format string, args ...interface{}) {
+	closeRecordWriter(records)
+	errors.FatalError(code, format, args...)
+}
+
+func closeRecordWriter(records *output.Writer) {
+	if err := records.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write %s output: %v\n", records.Format(), err)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/journal"
+	"github.com/abey/clean-git/internal/output"
+	"github.com/abey/clean-git/internal/pool"
+)
+
+// removal deletes or archives the branches of a clean or apply run, journals
+// every branch it removes and reports the outcome for each.
+type removal struct {
+	branchService git.BranchService
+	journal       *journal.Journal
+	runID         string
+	now           time.Time
+	archive       bool
+	archiveRemote bool
+	force         bool
+	// jobs is how many branches are removed at the same time
+	jobs int
+	// reader offers a forced deletion when a safe one is refused; nil never asks
+	reader  *bufio.Reader
+	records *output.Writer
+	// out receives the progress and summary written for people
+	out io.Writer
+
+	// mu guards the output, the journal and the results below
+	mu           sync.Mutex
+	successCount int
+	errors       []string
+	refused      []string
+	forced       []string
+	stale        []string
+	// interrupted lists the branches Ctrl-C stopped before they were started
+	interrupted []string
+	// authFailed is set once the remote refused our credentials, so the
+	// remaining remote branches are not tried with them again
+	authFailed bool
+}
+
+func newRemoval(branchService git.BranchService, repoRoot string, now time.Time, records *output.Writer, out io.Writer) *removal {
+	return &removal{
+		branchService: branchService,
+		journal:       openJournal(repoRoot),
+		runID:         journal.NewRunID(now),
+		now:           now,
+		records:       records,
+		out:           out,
+	}
+}
+
+func (r *removal) verbs() (action, progress, summary string) {
+	if r.archive {
+		return "archive", "Archiving", "Archive"
+	}
+	return "delete", "Deleting", "Deletion"
+}
+
+// run removes branches, up to jobs at a time. recordFor returns the record
+// each outcome is reported in. Ctrl-C stops it from starting further branches;
+// those are reported as interrupted.
+func (r *removal) run(branches []*git.Branch, recordFor func(*git.Branch) *output.Branch) {
+	_, progress, _ := r.verbs()
+	fmt.Fprintf(r.out, "\n%s %d branch(es)...\n", progress, len(branches))
+
+	endSection := interrupts.begin()
+	started := pool.Run(interrupts.stop, r.jobs, len(branches), func(i int) {
+		r.remove(branches[i], recordFor(branches[i]))
+	})
+	endSection()
+
+	for _, branch := range branches[started:] {
+		r.interrupted = append(r.interrupted, branch.Name)
+		record := recordFor(branch)
+		record.Outcome = output.OutcomeInterrupted
+		r.records.Write(record)
+	}
+}
+
+// remove deletes or archives a single branch. Git commands run without r.mu
+// so branches are removed in parallel; reporting and prompts hold it.
+func (r *removal) remove(branch *git.Branch, record *output.Branch) {
+	action, _, _ := r.verbs()
+	branchType := "local"
+	if branch.IsRemote {
+		branchType = "remote"
+	}
+
+	if branch.IsRemote && r.remoteAuthFailed() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		errorMsg := fmt.Sprintf("Skipped %s branch %s: authentication with the remote already failed", branchType, branch.Name)
+		r.errors = append(r.errors, errorMsg)
+		fmt.Fprintf(r.out, "  ✗ %s\n", errorMsg)
+		record.Outcome, record.Error = output.OutcomeFailed, errorMsg
+		r.records.Write(record)
+		return
+	}
+
+	// Record the full tip before deleting so the branch can be restored later
+	sha, err := r.branchService.ResolveBranchSHA(branch)
+	if err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		errorMsg := fmt.Sprintf("Refusing to %s %s branch %s: could not resolve its tip: %v", action, branchType, branch.Name, err)
+		r.errors = append(r.errors, errorMsg)
+		fmt.Fprintf(r.out, "  ✗ %s\n", errorMsg)
+		record.Outcome, record.Error = output.OutcomeFailed, errorMsg
+		r.records.Write(record)
+		return
+	}
+
+	archiveRef := ""
+	var forcedReason string
+	if r.archive {
+		archiveRef = git.ArchiveRefForBranch(r.now, branch)
+		err = r.branchService.ArchiveBranch(branch, archiveRef, r.archiveRemote)
+	} else {
+		err = r.branchService.DeleteBranch(branch)
+		if unsafeErr, ok := err.(*git.UnsafeDeleteError); ok {
+			if r.force || r.confirmForce(unsafeErr) {
+				err = r.branchService.ForceDeleteBranch(branch)
+				if err == nil {
+					forcedReason = unsafeErr.Reason
+				}
+			} else {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				r.refused = append(r.refused, unsafeErr.Error())
+				fmt.Fprintf(r.out, "  ! Refused %s branch %s: %s\n", branchType, branch.Name, unsafeErr.Reason)
+				record.Outcome, record.Error = output.OutcomeRefused, unsafeErr.Error()
+				r.records.Write(record)
+				return
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	forced := forcedReason != ""
+	if staleErr, ok := err.(*git.StaleTipError); ok {
+		r.stale = append(r.stale, staleErr.Error())
+		fmt.Fprintf(r.out, "  ! Skipped %s branch %s: tip moved from %s to %s\n", branchType, branch.Name, shortSHA(staleErr.Expected), shortSHA(staleErr.Actual))
+		record.Outcome, record.Error = output.OutcomeStale, staleErr.Error()
+	} else if err != nil {
+		errorMsg := fmt.Sprintf("Failed to %s %s branch %s: %v", action, branchType, branch.Name, err)
+		r.errors = append(r.errors, errorMsg)
+		fmt.Fprintf(r.out, "  ✗ %s\n", errorMsg)
+		printGitErrorHint(r.out, err)
+		record.Outcome, record.Error = output.OutcomeFailed, err.Error()
+		if isAuthFailure(err) {
+			r.authFailed = true
+		}
+	} else {
+		r.successCount++
+		if r.archive {
+			fmt.Fprintf(r.out, "  ✓ Archived %s branch: %s -> %s\n", branchType, branch.Name, archiveRef)
+			record.Outcome, record.ArchiveRef = output.OutcomeArchived, archiveRef
+		} else if forced {
+			r.forced = append(r.forced, fmt.Sprintf("%s (%s)", branch.Name, forcedReason))
+			fmt.Fprintf(r.out, "  ✓ Force deleted %s branch: %s\n", branchType, branch.Name)
+			record.Outcome = output.OutcomeForceDeleted
+		} else {
+			fmt.Fprintf(r.out, "  ✓ Deleted %s branch: %s\n", branchType, branch.Name)
+			record.Outcome = output.OutcomeDeleted
+		}
+
+		entry := journal.Entry{
+			RunID:       r.runID,
+			Branch:      branch.Name,
+			IsRemote:    branch.IsRemote,
+			Remote:      branch.Remote,
+			SHA:         sha,
+			Author:      branch.AuthorUserName,
+			AuthorEmail: branch.AuthorEmail,
+			ArchiveRef:  archiveRef,
+			Forced:      forced,
+			DeletedAt:   time.Now(),
+		}
+		if err := r.journal.Append(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to journal deletion of %s: %v\n", branch.Name, err)
+		}
+	}
+	r.records.Write(record)
+}
+
+func (r *removal) remoteAuthFailed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.authFailed
+}
+
+// confirmForce asks whether to force delete a refused branch, one prompt at a time.
+func (r *removal) confirmForce(unsafeErr *git.UnsafeDeleteError) bool {
+	if r.reader == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return confirmForceDelete(r.out, r.reader, unsafeErr)
+}
+
+func (r *removal) printSummary() {
+	action, _, summary := r.verbs()
+	fmt.Fprintf(r.out, "\n=== %s Summary ===\n", summary)
+	fmt.Fprintf(r.out, "Successfully %sd: %d branch(es)\n", action, r.successCount)
+	if len(r.forced) > 0 {
+		fmt.Fprintf(r.out, "Force deleted (safety checks overridden): %d branch(es)\n", len(r.forced))
+		for _, forcedDeletion := range r.forced {
+			fmt.Fprintf(r.out, "  - %s\n", forcedDeletion)
+		}
+	}
+	if len(r.refused) > 0 {
+		fmt.Fprintf(r.out, "Refused by safety checks: %d branch(es) (use --force to override)\n", len(r.refused))
+		for _, refusal := range r.refused {
+			fmt.Fprintf(r.out, "  - %s\n", refusal)
+		}
+	}
+	if len(r.stale) > 0 {
+		fmt.Fprintf(r.out, "Skipped because their tip moved: %d branch(es)\n", len(r.stale))
+		for _, stale := range r.stale {
+			fmt.Fprintf(r.out, "  - %s\n", stale)
+		}
+	}
+	if len(r.interrupted) > 0 {
+		fmt.Fprintf(r.out, "Not started because of Ctrl-C: %d branch(es)\n", len(r.interrupted))
+		for _, name := range r.interrupted {
+			fmt.Fprintf(r.out, "  - %s\n", name)
+		}
+	}
+	if len(r.errors) > 0 {
+		fmt.Fprintf(r.out, "Failed to %s: %d branch(es)\n", action, len(r.errors))
+		fmt.Fprintf(r.out, "\n%s errors:\n", summary)
+		for _, err := range r.errors {
+			fmt.Fprintf(r.out, "  - %s\n", err)
+		}
+	}
+}
+
+func (r *removal) printRunID() {
+	if r.successCount > 0 {
+		fmt.Fprintf(r.out, "Run ID: %s (undo with 'clean-git restore --run %s')\n", r.runID, r.runID)
+	}
+}
+
+// exitIfInterrupted ends a run stopped by Ctrl-C with ExitInterrupted, once
+// its summary has been printed. Exiting skips the command's deferred calls, so
+// the records of the branches already removed are written out here.
+func (r *removal) exitIfInterrupted() {
+	if interrupts.interrupted() {
+		fatalWithRecords(r.records, errors.ExitInterrupted, "Interrupted")
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
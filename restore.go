@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/errors"
+	"github.com/abey/clean-git/internal/journal"
+)
+
+func handleRestoreCommand(args []string, configService config.Service, repoRoot string) {
+	restoreFlags := flag.NewFlagSet("restore", flag.ExitOnError)
+	runID := restoreFlags.String("run", "", "Restore every branch deleted in the given run")
+	last := restoreFlags.Bool("last", false, "Restore every branch deleted in the most recent run")
+	push := restoreFlags.Bool("push", false, "Push restored remote branches back to their remote")
+	list := restoreFlags.Bool("list", false, "List journaled deletions instead of restoring")
+
+	restoreFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s restore [OPTIONS] [BRANCH]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Restore branches deleted by a previous clean run.\n\n")
+		fmt.Fprintf(os.Stderr, "Select a single BRANCH (its most recent deletion), a whole run with --run,\n")
+		fmt.Fprintf(os.Stderr, "or the most recent run with --last. Branches are recreated locally;\n")
+		fmt.Fprintf(os.Stderr, "use --push to also recreate deleted remote branches on their remote.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		restoreFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nGlobal options like --dry-run, --verbose are also available.\n")
+	}
+
+	restoreFlags.Parse(args)
+
+	deletionJournal := openJournal(repoRoot)
+
+	if *list {
+		listJournal(deletionJournal)
+		return
+	}
+
+	branchName := restoreFlags.Arg(0)
+	selectors := 0
+	for _, selected := range []bool{branchName != "", *runID != "", *last} {
+		if selected {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		restoreFlags.Usage()
+		errors.FatalError(errors.ExitGeneral, "Specify exactly one of BRANCH, --run or --last")
+	}
+
+	var entries []journal.Entry
+	var err error
+	switch {
+	case branchName != "":
+		entries, err = deletionJournal.LatestForBranch(branchName)
+	case *runID != "":
+		entries, err = deletionJournal.Run(*runID)
+	default:
+		entries, err = deletionJournal.LastRun()
+	}
+	if err != nil {
+		errors.FatalError(errors.ExitGeneral, "Failed to read deletion journal: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No matching deletions found in the journal.")
+		return
+	}
+
+	cfg := configService.Config()
+	branchService := newBranchService(cfg)
+
+	// Local entries first so a branch deleted both locally and remotely is
+	// recreated from its local tip.
+	var ordered []journal.Entry
+	for _, entry := range entries {
+		if !entry.IsRemote {
+			ordered = append(ordered, entry)
+		}
+	}
+	for _, entry := range entries {
+		if entry.IsRemote {
+			ordered = append(ordered, entry)
+		}
+	}
+
+	// A branch's local copy is recreated once, by its first entry. A remote
+	// entry is still pushed when that fails, e.g. because a local branch of
+	// the same name exists again.
+	attemptedLocal := make(map[string]bool)
+	var successCount, failCount int
+
+	fmt.Printf("Restoring %d journaled deletion(s)...\n", len(ordered))
+	for _, entry := range ordered {
+		acted := false
+		if !attemptedLocal[entry.Branch] {
+			attemptedLocal[entry.Branch] = true
+			if *dryRun {
+				fmt.Printf("  [DRY RUN] Would create local branch %s at %s\n", entry.Branch, entry.SHA)
+				acted = true
+			} else if err := branchService.RestoreBranch(entry.Branch, entry.SHA); err != nil {
+				failCount++
+				fmt.Printf("  ✗ Failed to restore local branch %s: %v\n", entry.Branch, err)
+				printGitErrorHint(os.Stdout, err)
+			} else {
+				fmt.Printf("  ✓ Restored local branch %s at %s\n", entry.Branch, entry.SHA)
+				acted = true
+			}
+		}
+
+		if entry.IsRemote && *push {
+			if *dryRun {
+				fmt.Printf("  [DRY RUN] Would push %s to %s/%s\n", entry.SHA, entry.Remote, entry.Branch)
+				acted = true
+			} else if err := branchService.PushBranch(entry.Remote, entry.Branch, entry.SHA); err != nil {
+				failCount++
+				fmt.Printf("  ✗ Failed to push %s/%s: %v\n", entry.Remote, entry.Branch, err)
+				printGitErrorHint(os.Stdout, err)
+			} else {
+				fmt.Printf("  ✓ Pushed %s/%s at %s\n", entry.Remote, entry.Branch, entry.SHA)
+				acted = true
+			}
+		}
+
+		if acted {
+			successCount++
+		}
+	}
+
+	fmt.Printf("\n=== Restore Summary ===\n")
+	fmt.Printf("Restored: %d, Failed: %d\n", successCount, failCount)
+	if failCount > 0 {
+		os.Exit(int(errors.ExitGit))
+	}
+}
+
+func listJournal(deletionJournal *journal.Journal) {
+	entries, err := deletionJournal.Entries()
+	if err != nil {
+		errors.FatalError(errors.ExitGeneral, "Failed to read deletion journal: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("The deletion journal is empty.")
+		return
+	}
+
+	currentRun := ""
+	for _, entry := range entries {
+		if entry.RunID != currentRun {
+			currentRun = entry.RunID
+			fmt.Printf("\nRun %s\n", currentRun)
+		}
+		location := "local"
+		if entry.IsRemote {
+			location = "remote " + entry.Remote
+		}
+		fmt.Printf("  %s (%s) %s by %s, deleted %s\n",
+			entry.Branch, location, entry.SHA, entry.Author, entry.DeletedAt.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func openJournal(repoRoot string) *journal.Journal {
+	gitDir, err := config.FindGitCommonDir(repoRoot)
+	if err != nil {
+		errors.FatalError(errors.ExitGit, "Failed to locate git directory: %v", err)
+	}
+	return journal.Open(gitDir)
+}
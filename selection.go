@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+)
+
+const selectionLogLimit = 10
+
+// reviewSelection lets the user adjust the set of qualifying branches before
+// anything is deleted. It returns the selected branches and false if the user
+// aborted the review.
+func reviewSelection(w io.Writer, reader *bufio.Reader, branches []*git.Branch, branchService git.BranchService) ([]*git.Branch, bool) {
+	selected := make([]bool, len(branches))
+	for i := range selected {
+		selected[i] = true
+	}
+
+	fmt.Fprintln(w, "\n=== Review Branches ===")
+	printSelection(w, branches, selected)
+	printSelectionHelp(w)
+
+	for {
+		fmt.Fprintf(w, "\nSelection (%d of %d selected) > ", countSelected(selected), len(branches))
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if err != nil && input == "" {
+			fmt.Fprintln(w)
+			return nil, false
+		}
+
+		switch {
+		case input == "":
+			printSelection(w, branches, selected)
+		case input == "c" || input == "confirm":
+			var result []*git.Branch
+			for i, branch := range branches {
+				if selected[i] {
+					result = append(result, branch)
+				}
+			}
+			return result, true
+		case input == "q" || input == "quit":
+			return nil, false
+		case input == "?" || input == "h" || input == "help":
+			printSelectionHelp(w)
+		case input == "a" || input == "all":
+			setAll(selected, true)
+			printSelection(w, branches, selected)
+		case input == "n" || input == "none":
+			setAll(selected, false)
+			printSelection(w, branches, selected)
+		case strings.HasPrefix(input, "l ") || strings.HasPrefix(input, "log "):
+			_, arg, _ := strings.Cut(input, " ")
+			index, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || index < 1 || index > len(branches) {
+				fmt.Fprintf(w, "Invalid branch number '%s'\n", strings.TrimSpace(arg))
+				continue
+			}
+			showBranchLog(w, branches[index-1], branchService)
+		case strings.HasPrefix(input, "+") || strings.HasPrefix(input, "-"):
+			pattern, err := regexp.Compile(strings.TrimSpace(input[1:]))
+			if err != nil {
+				fmt.Fprintf(w, "Invalid regex pattern: %v\n", err)
+				continue
+			}
+			matches := 0
+			for i, branch := range branches {
+				if pattern.MatchString(branch.Name) {
+					selected[i] = input[0] == '+'
+					matches++
+				}
+			}
+			fmt.Fprintf(w, "Pattern matched %d branch(es)\n", matches)
+			printSelection(w, branches, selected)
+		default:
+			indices, err := parseIndexList(input, len(branches))
+			if err != nil {
+				fmt.Fprintf(w, "%v (type ? for help)\n", err)
+				continue
+			}
+			for _, index := range indices {
+				selected[index] = !selected[index]
+			}
+			printSelection(w, branches, selected)
+		}
+	}
+}
+
+func printSelection(w io.Writer, branches []*git.Branch, selected []bool) {
+	width := len(strconv.Itoa(len(branches)))
+	for i, branch := range branches {
+		mark := " "
+		if selected[i] {
+			mark = "x"
+		}
+		branchType := "local"
+		if branch.IsRemote {
+			branchType = "remote"
+		}
+		fmt.Fprintf(w, "  [%s] %*d. %s (%s): last commit %s ago by %s\n",
+			mark, width, i+1, branch.Name, branchType, formatDuration(time.Since(branch.LastCommitAt)), branch.AuthorUserName)
+	}
+}
+
+func printSelectionHelp(w io.Writer) {
+	fmt.Fprintln(w, "\nCommands:")
+	fmt.Fprintln(w, "  1 3 5-7    Toggle branches by number or range")
+	fmt.Fprintln(w, "  +REGEX     Select branches matching REGEX")
+	fmt.Fprintln(w, "  -REGEX     Deselect branches matching REGEX")
+	fmt.Fprintln(w, "  a / n      Select all / none")
+	fmt.Fprintln(w, "  l N        Show the recent log of branch N")
+	fmt.Fprintln(w, "  Enter      Show the list again")
+	fmt.Fprintln(w, "  c          Confirm the selection")
+	fmt.Fprintln(w, "  q          Abort without changes")
+}
+
+func showBranchLog(w io.Writer, branch *git.Branch, branchService git.BranchService) {
+	log, err := branchService.GetBranchLog(branch, selectionLogLimit)
+	if err != nil {
+		fmt.Fprintf(w, "Failed to get log for %s: %v\n", branch.Name, err)
+		return
+	}
+	fmt.Fprintf(w, "\n--- %s (last %d commits) ---\n%s\n", branch.Name, selectionLogLimit, log)
+}
+
+// parseIndexList converts "1,3 5-7" into zero-based indices, validating each
+// number against the list length.
+func parseIndexList(input string, count int) ([]int, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	var indices []int
+	for _, field := range fields {
+		start, end := field, field
+		if from, to, isRange := strings.Cut(field, "-"); isRange {
+			start, end = from, to
+		}
+
+		first, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection '%s'", field)
+		}
+		last, err := strconv.Atoi(end)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection '%s'", field)
+		}
+		if first < 1 || last > count || first > last {
+			return nil, fmt.Errorf("selection '%s' is out of range 1-%d", field, count)
+		}
+		for i := first; i <= last; i++ {
+			indices = append(indices, i-1)
+		}
+	}
+
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("unknown command '%s'", input)
+	}
+	return indices, nil
+}
+
+func setAll(selected []bool, value bool) {
+	for i := range selected {
+		selected[i] = value
+	}
+}
+
+func countSelected(selected []bool) int {
+	count := 0
+	for _, isSelected := range selected {
+		if isSelected {
+			count++
+		}
+	}
+	return count
+}
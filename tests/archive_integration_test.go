@@ -0,0 +1,146 @@
+package clean_git_tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRefFor(t *testing.T) {
+	date := time.Date(2024, 3, 9, 15, 0, 0, 0, time.Local)
+	assert.Equal(t, "refs/clean-git/archive/2024-03-09/feature/x", git.ArchiveRefFor(date, "feature/x"))
+}
+
+func TestBranchService_ArchiveBranch(t *testing.T) {
+	date := time.Date(2024, 3, 9, 0, 0, 0, 0, time.Local)
+
+	t.Run("archive local branch", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+		ref := git.ArchiveRefFor(date, "feature/test")
+
+		err := service.ArchiveBranch(&git.Branch{Name: "feature/test"}, ref, false)
+		require.NoError(t, err)
+
+		sha, exists := mockClient.GetRef(ref)
+		assert.True(t, exists)
+		assert.Equal(t, "def456", sha)
+		assert.False(t, mockClient.HasBranch("feature/test"), "visible branch should be removed")
+	})
+
+	t.Run("archive remote branch on the remote", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/remote", CommitSHA: "rem123", IsRemote: true, Remote: "origin"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+		ref := git.ArchiveRefFor(date, "feature/remote")
+
+		err := service.ArchiveBranch(&git.Branch{Name: "feature/remote", IsRemote: true, Remote: "origin"}, ref, true)
+		require.NoError(t, err)
+
+		sha, exists := mockClient.GetRemoteRef("origin", ref)
+		assert.True(t, exists)
+		assert.Equal(t, "rem123", sha)
+		_, exists = mockClient.GetRef(ref)
+		assert.True(t, exists, "remote branches are always archived locally too")
+		calls := mockClient.GetDeleteRemoteBranchCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "feature/remote", calls[0].BranchName)
+	})
+
+	t.Run("refuse to overwrite a different archive", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		ref := git.ArchiveRefFor(date, "feature/test")
+		mockClient.SetRef(ref, "other999")
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.ArchiveBranch(&git.Branch{Name: "feature/test"}, ref, false)
+		assert.Error(t, err)
+		assert.True(t, mockClient.HasBranch("feature/test"), "branch must survive a failed archive")
+	})
+
+	t.Run("branch survives when archive ref cannot be written", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("UpdateRef", errors.New("cannot lock ref"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.ArchiveBranch(&git.Branch{Name: "feature/test"}, git.ArchiveRefFor(date, "feature/test"), false)
+		assert.Error(t, err)
+		assert.True(t, mockClient.HasBranch("feature/test"))
+	})
+}
+
+func TestBranchService_ArchiveLocalAndRemoteCopy(t *testing.T) {
+	date := time.Date(2024, 3, 9, 0, 0, 0, 0, time.Local)
+	mockClient := mocks.NewMockedGitClient()
+	mockClient.AddBranch(mocks.BranchData{Name: "feature/test", CommitSHA: "old111", IsRemote: true, Remote: "origin"})
+	service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+	local := &git.Branch{Name: "feature/test"}
+	remote := &git.Branch{Name: "feature/test", IsRemote: true, Remote: "origin"}
+	assert.Equal(t, "refs/clean-git/archive/2024-03-09/heads/feature/test", git.ArchiveRefForBranch(date, local))
+	assert.Equal(t, "refs/clean-git/archive/2024-03-09/remotes/origin/feature/test", git.ArchiveRefForBranch(date, remote))
+
+	require.NoError(t, service.ArchiveBranch(local, git.ArchiveRefForBranch(date, local), false))
+	require.NoError(t, service.ArchiveBranch(remote, git.ArchiveRefForBranch(date, remote), false),
+		"a remote copy with another tip gets an archive of its own")
+
+	archived, err := service.ListArchivedBranches("")
+	require.NoError(t, err)
+	require.Len(t, archived, 2)
+	assert.Equal(t, "feature/test", archived[0].BranchName())
+	assert.Equal(t, "def456", archived[0].SHA)
+	assert.Equal(t, "origin/feature/test", archived[1].BranchName())
+	assert.Equal(t, "feature/test", archived[1].Name)
+	assert.Equal(t, "old111", archived[1].SHA)
+}
+
+func TestBranchService_ListAndPurgeArchives(t *testing.T) {
+	mockClient := mocks.NewMockedGitClient()
+	mockClient.SetRef("refs/clean-git/archive/2024-01-05/heads/feature/new", "new1")
+	mockClient.SetRef("refs/clean-git/archive/2023-06-01/feature/old", "old1")
+	mockClient.SetRef("refs/clean-git/archive/not-a-date/heads/feature/bad", "bad1")
+	mockClient.SetRemoteRef("origin", "refs/clean-git/archive/2023-01-01/feature/remote", "rem1")
+	service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+	archived, err := service.ListArchivedBranches("")
+	require.NoError(t, err)
+	require.Len(t, archived, 2, "refs without a valid date are ignored")
+	assert.Equal(t, "feature/old", archived[0].Name, "oldest archives come first, unsegmented ones are local")
+	assert.Equal(t, "feature/new", archived[1].Name)
+	assert.Equal(t, "old1", archived[0].SHA)
+
+	remoteArchived, err := service.ListArchivedBranches("origin")
+	require.NoError(t, err)
+	require.Len(t, remoteArchived, 1)
+	assert.Equal(t, "origin", remoteArchived[0].Remote)
+
+	require.NoError(t, service.DeleteArchivedBranch(archived[0]))
+	_, exists := mockClient.GetRef(archived[0].Ref)
+	assert.False(t, exists)
+
+	require.NoError(t, service.DeleteArchivedBranch(remoteArchived[0]))
+	_, exists = mockClient.GetRemoteRef("origin", remoteArchived[0].Ref)
+	assert.False(t, exists)
+}
+
+func TestBranchService_ArchiveLocalBranchNamedLikeARemote(t *testing.T) {
+	date := time.Date(2024, 3, 9, 0, 0, 0, 0, time.Local)
+	mockClient := mocks.NewMockedGitClient()
+	mockClient.AddBranch(mocks.BranchData{Name: "remotes/origin/x", CommitSHA: "abc111"})
+	service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+	branch := &git.Branch{Name: "remotes/origin/x"}
+	require.NoError(t, service.ArchiveBranch(branch, git.ArchiveRefForBranch(date, branch), false))
+
+	archived, err := service.ListArchivedBranches("")
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Equal(t, "remotes/origin/x", archived[0].Name)
+	assert.Empty(t, archived[0].BranchRemote, "a local branch stays local")
+}
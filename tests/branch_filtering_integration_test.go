@@ -78,7 +78,7 @@ func TestBranchFiltering_ByAge(t *testing.T) {
 
 			cfg := &config.Config{
 				BaseBranches: []string{"main"},
-				MaxAge:       tt.maxAge,
+				MaxAge:       config.Duration(tt.maxAge),
 				RemoteName:   "origin",
 			}
 
@@ -92,7 +92,7 @@ func TestBranchFiltering_ByAge(t *testing.T) {
 			var qualifyingBranches []git.Branch
 			for _, branch := range branches {
 				age := time.Since(branch.LastCommitAt)
-				if age >= cfg.MaxAge {
+				if age >= time.Duration(cfg.MaxAge) {
 					qualifyingBranches = append(qualifyingBranches, branch)
 				}
 			}
@@ -151,7 +151,7 @@ func TestBranchFiltering_LocalOnlyFlag(t *testing.T) {
 
 	cfg := &config.Config{
 		BaseBranches: []string{"main"},
-		MaxAge:       48 * time.Hour, // 2 days
+		MaxAge:       config.Duration(48 * time.Hour), // 2 days
 		RemoteName:   "origin",
 	}
 
@@ -165,7 +165,7 @@ func TestBranchFiltering_LocalOnlyFlag(t *testing.T) {
 
 	for _, branch := range branches {
 		age := time.Since(branch.LastCommitAt)
-		if age >= cfg.MaxAge {
+		if age >= time.Duration(cfg.MaxAge) {
 			allQualifyingBranches = append(allQualifyingBranches, branch)
 			if !branch.IsRemote { // local-only filter
 				localOnlyBranches = append(localOnlyBranches, branch)
@@ -231,7 +231,7 @@ func TestBranchFiltering_RemoteOnlyFlag(t *testing.T) {
 
 	cfg := &config.Config{
 		BaseBranches: []string{"main"},
-		MaxAge:       48 * time.Hour, // 2 days
+		MaxAge:       config.Duration(48 * time.Hour), // 2 days
 		RemoteName:   "origin",
 	}
 
@@ -245,7 +245,7 @@ func TestBranchFiltering_RemoteOnlyFlag(t *testing.T) {
 
 	for _, branch := range branches {
 		age := time.Since(branch.LastCommitAt)
-		if age >= cfg.MaxAge {
+		if age >= time.Duration(cfg.MaxAge) {
 			allQualifyingBranches = append(allQualifyingBranches, branch)
 			if branch.IsRemote { // remote-only filter
 				remoteOnlyBranches = append(remoteOnlyBranches, branch)
@@ -617,21 +617,18 @@ func TestBranchService_RemoteBranchDeletion(t *testing.T) {
 			},
 		},
 		{
-			name: "uses existing remote name when already set",
+			name: "refuses a remote that is not configured",
 			branch: &git.Branch{
 				Name:     "feature/has-remote",
 				IsRemote: true,
-				Remote:   "origin", // Already has remote
+				Remote:   "origin", // Not the configured "upstream"
 			},
 			config: &config.Config{
 				RemoteName: "upstream",
 			},
-			expectedError: false,
+			expectedError: true,
 			validateCall: func(t *testing.T, m *mocks.SophisticatedGitClient) {
-				// Should use existing "origin", not config "upstream"
-				calls := m.GetDeleteRemoteBranchCalls()
-				require.Len(t, calls, 1)
-				assert.Equal(t, "origin", calls[0].Remote)
+				assert.Empty(t, m.GetDeleteRemoteBranchCalls())
 			},
 		},
 		{
@@ -670,8 +667,8 @@ func TestBranchService_RemoteBranchDeletion(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				tt.validateCall(t, mockClient)
 			}
+			tt.validateCall(t, mockClient)
 		})
 	}
 }
@@ -753,3 +750,410 @@ func TestBranchService_EdgeCases(t *testing.T) {
 		assert.True(t, time.Since(branch.LastCommitAt) > 365*24*time.Hour)
 	})
 }
+
+func TestBranchService_RestoreSupport(t *testing.T) {
+	t.Run("resolve tip of local and remote branches", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/remote", CommitSHA: "remote123", IsRemote: true, Remote: "origin"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		sha, err := service.ResolveBranchSHA(&git.Branch{Name: "feature/test"})
+		require.NoError(t, err)
+		assert.Equal(t, "def456", sha)
+
+		sha, err = service.ResolveBranchSHA(&git.Branch{Name: "feature/remote", IsRemote: true})
+		require.NoError(t, err)
+		assert.Equal(t, "remote123", sha)
+
+		_, err = service.ResolveBranchSHA(&git.Branch{Name: "feature/missing"})
+		assert.Error(t, err)
+	})
+
+	t.Run("restore and push a deleted branch", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		require.NoError(t, service.RestoreBranch("feature/restored", "abc999"))
+		assert.True(t, mockClient.HasBranch("feature/restored"))
+		assert.Error(t, service.RestoreBranch("feature/restored", "abc999"), "existing branches must not be overwritten")
+
+		require.NoError(t, service.PushBranch("origin", "feature/restored", "abc999"))
+		calls := mockClient.GetPushBranchCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, mocks.PushBranchCall{Remote: "origin", BranchName: "feature/restored", SHA: "abc999"}, calls[0])
+	})
+}
+
+func TestBranchService_DeleteSafety(t *testing.T) {
+	t.Run("refuses branch with unpushed commits", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test", HasUnpushedCommits: true})
+		var unsafeErr *git.UnsafeDeleteError
+		require.ErrorAs(t, err, &unsafeErr)
+		assert.Equal(t, "feature/test", unsafeErr.Branch)
+		assert.Contains(t, unsafeErr.Reason, "not pushed")
+		assert.True(t, mockClient.HasBranch("feature/test"))
+	})
+
+	t.Run("refuses branch not reachable from its upstream", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetUpstreamState("feature/test", mocks.UpstreamState{HasUpstream: true, Contains: false})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.CheckDeleteSafety(&git.Branch{Name: "feature/test"})
+		var unsafeErr *git.UnsafeDeleteError
+		require.ErrorAs(t, err, &unsafeErr)
+		assert.Contains(t, unsafeErr.Reason, "not reachable")
+	})
+
+	t.Run("allows branch contained in its upstream", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetUpstreamState("feature/test", mocks.UpstreamState{HasUpstream: true, Contains: true})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		require.NoError(t, service.DeleteBranch(&git.Branch{Name: "feature/test"}))
+		assert.False(t, mockClient.HasBranch("feature/test"))
+		assert.Empty(t, mockClient.GetForceDeletedBranches())
+	})
+
+	t.Run("failed safe delete is refused, not forced", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("DeleteLocalBranch", errors.New("the branch 'feature/test' is not fully merged"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test"})
+		var unsafeErr *git.UnsafeDeleteError
+		require.ErrorAs(t, err, &unsafeErr)
+		assert.Equal(t, "safe delete failed", unsafeErr.Reason)
+		assert.Contains(t, err.Error(), "not fully merged")
+		assert.Empty(t, mockClient.GetForceDeletedBranches())
+		assert.True(t, mockClient.HasBranch("feature/test"))
+	})
+
+	t.Run("force delete overrides the checks", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		require.NoError(t, service.ForceDeleteBranch(&git.Branch{Name: "feature/test", HasUnpushedCommits: true}))
+		assert.Equal(t, []string{"feature/test"}, mockClient.GetForceDeletedBranches())
+	})
+
+	t.Run("remote branches are not subject to local checks", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		assert.NoError(t, service.CheckDeleteSafety(&git.Branch{Name: "feature/x", IsRemote: true, HasUnpushedCommits: true}))
+	})
+}
+
+func TestBranchService_GetBranchLog(t *testing.T) {
+	mockClient := mocks.NewMockedGitClient()
+	service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+	log, err := service.GetBranchLog(&git.Branch{Name: "feature/test"}, 5)
+	require.NoError(t, err)
+	assert.Contains(t, log, "def456")
+
+	mockClient.SetCommandFailure("GetBranchLog", errors.New("bad revision"))
+	_, err = service.GetBranchLog(&git.Branch{Name: "feature/test"}, 5)
+	assert.Error(t, err)
+}
+
+func TestBranchService_SquashMergeDetection(t *testing.T) {
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetSquashMerged("main", []string{"feature/test"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+		for _, branch := range branches {
+			assert.NotEqual(t, "feature/test", branch.Name)
+		}
+	})
+
+	t.Run("ReportsSquashedBranchesAsMerged", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetSquashMerged("main", []string{"feature/test"})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{RemoteName: "origin", DetectSquashMerges: true})
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+
+		methods := make(map[string]git.MergeMethod)
+		for _, branch := range branches {
+			assert.True(t, branch.IsMerged)
+			methods[branch.Name] = branch.MergeMethod
+		}
+		assert.Equal(t, git.MergeMethodSquash, methods["feature/test"])
+		assert.Equal(t, git.MergeMethodMerge, methods["feature/merged"])
+		assert.NotContains(t, methods, "main")
+	})
+
+	t.Run("ChecksRemoteBase", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetSquashMerged("origin/main", []string{"feature/test"})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{RemoteName: "origin", DetectSquashMerges: true})
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+
+		found := false
+		for _, branch := range branches {
+			if branch.Name == "feature/test" {
+				found = true
+				assert.Equal(t, git.MergeMethodSquash, branch.MergeMethod)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("ReportsSquashedRemoteBranches", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/pushed", CommitSHA: "pus123", IsRemote: true, Remote: "origin"})
+		mockClient.SetSquashMerged("origin/main", []string{"remotes/origin/feature/pushed"})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{RemoteName: "origin", DetectSquashMerges: true})
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+
+		found := false
+		for _, branch := range branches {
+			if branch.IsRemote && branch.Name == "feature/pushed" {
+				found = true
+				assert.Equal(t, git.MergeMethodSquash, branch.MergeMethod)
+			}
+			assert.False(t, branch.IsRemote && branch.Name == "main", "the base on the remote is never a candidate")
+		}
+		assert.True(t, found, "remote branches without a local copy are probed too")
+	})
+
+	t.Run("ComparisonErrorsMeanNotMerged", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("IsSquashMerged", errors.New("no merge base"))
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{RemoteName: "origin", DetectSquashMerges: true})
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+		for _, branch := range branches {
+			assert.Equal(t, git.MergeMethodMerge, branch.MergeMethod)
+		}
+	})
+
+	t.Run("DeleteFallsBackToForceForSquashedBranches", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("DeleteLocalBranch", errors.New("not fully merged"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test", MergeMethod: git.MergeMethodSquash})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"feature/test"}, mockClient.GetForceDeletedBranches())
+
+		err = service.DeleteBranch(&git.Branch{Name: "feature/merged", MergeMethod: git.MergeMethodMerge})
+		var unsafeErr *git.UnsafeDeleteError
+		assert.ErrorAs(t, err, &unsafeErr)
+	})
+}
+
+func TestBranchService_RebaseMergeDetection(t *testing.T) {
+	newService := func(mockClient *mocks.SophisticatedGitClient) git.BranchService {
+		return git.NewBranchServiceWithClientOptions(mockClient, git.Options{RemoteName: "origin", DetectRebaseMerges: true})
+	}
+
+	t.Run("ReportsFullyUpstreamBranchesAsMerged", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCherryCounts("main", "feature/test", mocks.CherryCount{Upstream: 3, Total: 3})
+
+		branches, err := newService(mockClient).GetMergedBranches("main")
+		require.NoError(t, err)
+
+		methods := make(map[string]git.MergeMethod)
+		for _, branch := range branches {
+			methods[branch.Name] = branch.MergeMethod
+		}
+		assert.Equal(t, git.MergeMethodRebase, methods["feature/test"])
+		assert.Equal(t, git.MergeMethodMerge, methods["feature/merged"])
+	})
+
+	t.Run("PartialBranchesAreNotMerged", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCherryCounts("main", "feature/test", mocks.CherryCount{Upstream: 1, Total: 3})
+		service := newService(mockClient)
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+		for _, branch := range branches {
+			assert.NotEqual(t, "feature/test", branch.Name)
+		}
+
+		partial, err := service.GetPartiallyMergedBranches("main")
+		require.NoError(t, err)
+		require.Len(t, partial, 1)
+		assert.Equal(t, "feature/test", partial[0].Name)
+		assert.False(t, partial[0].IsMerged)
+		assert.Equal(t, 1, partial[0].CommitsUpstream)
+		assert.Equal(t, 3, partial[0].CommitsUnique)
+	})
+
+	t.Run("MergeStatusComparesEachBranchOnce", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCherryCounts("main", "feature/test", mocks.CherryCount{Upstream: 1, Total: 3})
+		mockClient.SetCherryCounts("main", "feature/rebased", mocks.CherryCount{Upstream: 2, Total: 2})
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/rebased", CommitSHA: "reb123"})
+
+		merged, partial, err := newService(mockClient).GetMergeStatus("main")
+		require.NoError(t, err)
+
+		var mergedNames []string
+		for _, branch := range merged {
+			mergedNames = append(mergedNames, branchKeyOf(branch))
+		}
+		assert.Contains(t, mergedNames, "feature/rebased")
+		assert.NotContains(t, mergedNames, "feature/test")
+		require.Len(t, partial, 1)
+		assert.Equal(t, "feature/test", partial[0].Name)
+		assert.Equal(t, 3, partial[0].CommitsUnique)
+
+		// feature/test and feature/rebased against main and origin/main
+		assert.Equal(t, 4, mockClient.GetCherryCallCount(), "no second pass for the partial status")
+	})
+
+	t.Run("PrefersBaseWithMoreCommitsUpstream", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCherryCounts("main", "feature/test", mocks.CherryCount{Upstream: 1, Total: 2})
+		mockClient.SetCherryCounts("origin/main", "feature/test", mocks.CherryCount{Upstream: 2, Total: 2})
+
+		branches, err := newService(mockClient).GetMergedBranches("main")
+		require.NoError(t, err)
+
+		found := false
+		for _, branch := range branches {
+			if branch.Name == "feature/test" {
+				found = true
+				assert.Equal(t, git.MergeMethodRebase, branch.MergeMethod)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("BranchesWithoutUniqueCommitsAreIgnored", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+
+		partial, err := newService(mockClient).GetPartiallyMergedBranches("main")
+		require.NoError(t, err)
+		assert.Empty(t, partial)
+	})
+
+	t.Run("ComparisonErrorsMeanNotMerged", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("CherryCounts", errors.New("bad revision"))
+
+		branches, err := newService(mockClient).GetMergedBranches("main")
+		require.NoError(t, err)
+		for _, branch := range branches {
+			assert.Equal(t, git.MergeMethodMerge, branch.MergeMethod)
+		}
+	})
+}
+
+func TestBranchService_UpstreamTracking(t *testing.T) {
+	t.Run("PopulatesUpstreamFields", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetUpstreamTracking("feature/test", mocks.UpstreamTracking{Upstream: "origin/feature/test", Track: "[ahead 2, behind 5]"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branch, err := service.GetBranchByName("feature/test")
+		require.NoError(t, err)
+		assert.Equal(t, "origin/feature/test", branch.Upstream)
+		assert.False(t, branch.UpstreamGone)
+		assert.Equal(t, 2, branch.Ahead)
+		assert.Equal(t, 5, branch.Behind)
+	})
+
+	t.Run("DetectsGoneUpstream", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetUpstreamTracking("feature/test", mocks.UpstreamTracking{Upstream: "origin/feature/test", Track: "[gone]"})
+		mockClient.SetUpstreamTracking("feature/merged", mocks.UpstreamTracking{Upstream: "origin/feature/merged"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches, err := service.GetGoneBranches()
+		require.NoError(t, err)
+		require.Len(t, branches, 1)
+		assert.Equal(t, "feature/test", branches[0].Name)
+		assert.True(t, branches[0].UpstreamGone)
+	})
+
+	t.Run("UpstreamErrorsAreIgnored", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("GetUpstreamInfo", errors.New("bad ref"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branch, err := service.GetBranchByName("feature/test")
+		require.NoError(t, err)
+		assert.Empty(t, branch.Upstream)
+	})
+
+	t.Run("UnmergedGoneBranchNeedsForce", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("DeleteLocalBranch", errors.New("not fully merged"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test", Upstream: "origin/feature/test", UpstreamGone: true})
+		var unsafeErr *git.UnsafeDeleteError
+		require.ErrorAs(t, err, &unsafeErr, "a gone upstream says nothing about unmerged local commits")
+		assert.Empty(t, mockClient.GetForceDeletedBranches())
+	})
+}
+
+func TestBranchService_BatchMetadata(t *testing.T) {
+	t.Run("ListsBranchesWithoutPerBranchCalls", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("GetBranchCommitInfo", errors.New("per-branch path used"))
+		mockClient.SetUnpushedCommits("feature/test", 2)
+		mockClient.SetUpstreamTracking("feature/merged", mocks.UpstreamTracking{Upstream: "origin/feature/merged", Track: "[gone]"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches, err := service.GetBranchesWithTrackedRemotes()
+		require.NoError(t, err)
+
+		byName := make(map[string]git.Branch)
+		for _, branch := range branches {
+			if !branch.IsRemote {
+				byName[branch.Name] = branch
+			}
+		}
+		require.Contains(t, byName, "main")
+		assert.True(t, byName["main"].IsCurrent)
+		assert.Equal(t, "john@example.com", byName["main"].AuthorEmail)
+		assert.Equal(t, "abc123", byName["main"].LastCommitSHA)
+		assert.True(t, byName["feature/test"].HasUnpushedCommits)
+		assert.Equal(t, 2, byName["feature/test"].Ahead)
+		assert.True(t, byName["feature/merged"].UpstreamGone)
+
+		merged, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+		assert.NotEmpty(t, merged)
+
+		branch, err := service.GetBranchByName("remotes/origin/main")
+		require.NoError(t, err)
+		assert.True(t, branch.IsRemote)
+		assert.Equal(t, "origin", branch.Remote)
+	})
+
+	t.Run("FallsBackToPerBranchPath", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("GetBranchMetadata", errors.New("unknown field name"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches, err := service.GetBranchesWithTrackedRemotes()
+		require.NoError(t, err)
+		assert.NotEmpty(t, branches)
+
+		mockClient.SetCommandFailure("GetBranchCommitInfo", errors.New("per-branch path used"))
+		branches, err = service.GetBranchesWithTrackedRemotes()
+		require.NoError(t, err)
+		assert.Empty(t, branches)
+	})
+}
@@ -35,7 +35,7 @@ func TestConfigService_SaveAndLoad(t *testing.T) {
 
 		updatedConfig := &config.Config{
 			BaseBranches:   []string{"main", "master"},
-			MaxAge:         48 * time.Hour,
+			MaxAge:         config.Duration(48 * time.Hour),
 			ProtectedRegex: []string{"release/.*", "main", "master"},
 			IncludeRegex:   []string{".*"},
 			RemoteName:     "upstream",
@@ -49,13 +49,13 @@ func TestConfigService_SaveAndLoad(t *testing.T) {
 
 		persistedConfig := service2.Config()
 		assert.Equal(t, "upstream", persistedConfig.RemoteName)
-		assert.Equal(t, 48*time.Hour, persistedConfig.MaxAge)
+		assert.Equal(t, config.Duration(48*time.Hour), persistedConfig.MaxAge)
 		assert.Equal(t, []string{"main", "master"}, persistedConfig.BaseBranches)
 	})
 }
 
-// TestConfigService_GlobalConfigPath tests that config is shared across multiple repositories
-func TestConfigService_GlobalConfigPath(t *testing.T) {
+// TestConfigService_PerRepositoryConfig tests that each repository has its own config layered over a shared global file
+func TestConfigService_PerRepositoryConfig(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "clean-git-global-test")
 	require.NoError(t, err)
 	defer os.RemoveAll(tempDir)
@@ -66,40 +66,51 @@ func TestConfigService_GlobalConfigPath(t *testing.T) {
 
 	repoRoot1 := filepath.Join(tempDir, "repo1")
 	repoRoot2 := filepath.Join(tempDir, "repo2")
-	
+
 	err = os.MkdirAll(repoRoot1, 0755)
 	require.NoError(t, err)
 	err = os.MkdirAll(repoRoot2, 0755)
 	require.NoError(t, err)
 
+	globalPath := filepath.Join(tempDir, ".clean-git", "configs", "global.yaml")
+	err = os.MkdirAll(filepath.Dir(globalPath), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(globalPath, []byte("remoteName: shared-remote\n"), 0644)
+	require.NoError(t, err)
+
 	service1, err := config.NewService(repoRoot1)
 	require.NoError(t, err)
-	
+
 	service2, err := config.NewService(repoRoot2)
 	require.NoError(t, err)
 
-	assert.Equal(t, service1.ConfigPath(), service2.ConfigPath())
-	
-	expectedGlobalPath := filepath.Join(tempDir, ".clean-git", "config.yaml")
-	assert.Equal(t, expectedGlobalPath, service1.ConfigPath())
+	assert.NotEqual(t, service1.ConfigPath(), service2.ConfigPath())
+	assert.Equal(t, filepath.Join(tempDir, ".clean-git", "configs", "repos"), filepath.Dir(service1.ConfigPath()))
+	assert.Equal(t, "shared-remote", service2.Config().RemoteName)
 
 	testConfig := &config.Config{
 		BaseBranches:   []string{"main", "develop"},
-		MaxAge:         96 * time.Hour,
+		MaxAge:         config.Duration(96 * time.Hour),
 		ProtectedRegex: []string{"release/.*"},
 		IncludeRegex:   []string{".*"},
-		RemoteName:     "shared-remote",
+		RemoteName:     "repo1-remote",
 	}
-	
+
 	err = service1.Update(testConfig)
 	require.NoError(t, err)
 
+	service1, err = config.NewService(repoRoot1)
+	require.NoError(t, err)
+	assert.Equal(t, "repo1-remote", service1.Config().RemoteName)
+	assert.Equal(t, config.Duration(96*time.Hour), service1.Config().MaxAge)
+
 	service2, err = config.NewService(repoRoot2)
 	require.NoError(t, err)
 
-	sharedConfig := service2.Config()
-	assert.Equal(t, "shared-remote", sharedConfig.RemoteName)
-	assert.Equal(t, 96*time.Hour, sharedConfig.MaxAge)
+	unaffectedConfig := service2.Config()
+	assert.Equal(t, "shared-remote", unaffectedConfig.RemoteName)
+	assert.Equal(t, config.DefaultConfig().MaxAge, unaffectedConfig.MaxAge)
+	assert.True(t, service2.IsOnboarded(), "the global config applies to every repository")
 }
 
 // TestConfigService_ErrorHandling tests integration error scenarios
@@ -0,0 +1,85 @@
+package clean_git_tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchService_AddDivergence(t *testing.T) {
+	t.Run("single base counts unique commits as ahead", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetDivergence("main", "refs/heads/feature/test", mocks.Divergence{Ahead: 3, Behind: 5})
+		mockClient.SetDivergence("main", "refs/remotes/origin/main", mocks.Divergence{})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches := []git.Branch{
+			{Name: "feature/test"},
+			{Name: "main", IsRemote: true, Remote: "origin"},
+		}
+		require.NoError(t, service.AddDivergence(branches, []string{"main"}))
+
+		assert.Equal(t, []git.Divergence{{Base: "main", Ahead: 3, Behind: 5}}, branches[0].Divergence)
+		assert.Equal(t, 3, branches[0].UniqueCommits)
+		assert.Equal(t, []git.Divergence{{Base: "main"}}, branches[1].Divergence)
+		assert.Equal(t, 0, branches[1].UniqueCommits)
+	})
+
+	t.Run("falls back to per-branch counts on older git", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("GetAheadBehind", errors.New("unknown field name: ahead-behind:main"))
+		mockClient.SetDivergence("main", "refs/heads/feature/test", mocks.Divergence{Ahead: 2, Behind: 1})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches := []git.Branch{{Name: "feature/test"}}
+		require.NoError(t, service.AddDivergence(branches, []string{"main"}))
+		assert.Equal(t, []git.Divergence{{Base: "main", Ahead: 2, Behind: 1}}, branches[0].Divergence)
+	})
+
+	t.Run("multiple bases count commits in none of them", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetDivergence("main", "refs/heads/feature/test", mocks.Divergence{Ahead: 4, Behind: 1})
+		mockClient.SetDivergence("develop", "refs/heads/feature/test", mocks.Divergence{Ahead: 2, Behind: 7})
+		mockClient.SetUniqueCommits("refs/heads/feature/test", 1)
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		branches := []git.Branch{{Name: "feature/test"}}
+		require.NoError(t, service.AddDivergence(branches, []string{"main", "develop"}))
+
+		assert.Equal(t, []git.Divergence{
+			{Base: "main", Ahead: 4, Behind: 1},
+			{Base: "develop", Ahead: 2, Behind: 7},
+		}, branches[0].Divergence)
+		assert.Equal(t, 1, branches[0].UniqueCommits)
+	})
+
+	t.Run("reports failures of the fallback", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("GetAheadBehind", errors.New("unsupported"))
+		mockClient.SetCommandFailure("CountAheadBehind", errors.New("bad revision"))
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		assert.Error(t, service.AddDivergence([]git.Branch{{Name: "feature/test"}}, []string{"main"}))
+	})
+}
+
+func TestBranchService_Subject(t *testing.T) {
+	mockClient := mocks.NewMockedGitClient()
+	mockClient.AddBranch(mocks.BranchData{Name: "feature/subject", CommitSHA: "aaa111", Subject: "Fix a | b parsing"})
+	service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+	branch, err := service.GetBranchByName("feature/subject")
+	require.NoError(t, err)
+	assert.Equal(t, "Fix a | b parsing", branch.Subject)
+
+	// The per-branch path keeps subjects containing the field separator intact
+	mockClient.SetCommandFailure("GetBranchMetadata", errors.New("for-each-ref failed"))
+	branch, err = service.GetBranchByName("feature/subject")
+	require.NoError(t, err)
+	assert.Equal(t, "Fix a | b parsing", branch.Subject)
+}
@@ -0,0 +1,162 @@
+package clean_git_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/config"
+	"github.com/abey/clean-git/internal/evaluator"
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEvaluatorConfig() *config.Config {
+	return &config.Config{
+		BaseBranches:   []string{"main", "develop"},
+		MaxAge:         config.Duration(24 * time.Hour),
+		GoneMaxAge:     config.Duration(time.Hour),
+		IncludeRegex:   []string{"^bugfix/", "^feature/"},
+		ProtectedRegex: []string{"^release/", "^feature/keep"},
+		RemoteName:     "origin",
+	}
+}
+
+func resultsByLabel(decision evaluator.Decision) map[string]evaluator.Result {
+	results := make(map[string]evaluator.Result)
+	for _, check := range decision.Checks {
+		results[check.Label()] = check.Result
+	}
+	return results
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	now := time.Now()
+	cfg := newEvaluatorConfig()
+	service := git.NewBranchServiceWithClient(mocks.NewMockedGitClient(), cfg.RemoteName)
+
+	t.Run("QualifyingBranch", func(t *testing.T) {
+		e := evaluator.New(cfg, service, evaluator.Options{Now: now})
+		branch := &git.Branch{Name: "feature/old", LastCommitAt: now.Add(-48 * time.Hour)}
+
+		decision := e.Evaluate(branch, "merged into main", 24*time.Hour)
+		assert.True(t, decision.Qualifies)
+		assert.Equal(t, "merged into main", decision.Rule)
+		assert.Empty(t, decision.Reason())
+		assert.Equal(t, map[string]evaluator.Result{
+			"current branch":          evaluator.ResultPass,
+			"age":                     evaluator.ResultPass,
+			"include ^bugfix/":        evaluator.ResultIgnored,
+			"include ^feature/":       evaluator.ResultPass,
+			"protected ^release/":     evaluator.ResultPass,
+			"protected ^feature/keep": evaluator.ResultPass,
+		}, resultsByLabel(decision))
+	})
+
+	t.Run("EveryFailureIsReported", func(t *testing.T) {
+		e := evaluator.New(cfg, service, evaluator.Options{Now: now, LocalOnly: true})
+		branch := &git.Branch{Name: "feature/keep-me", IsCurrent: true, IsRemote: true, LastCommitAt: now.Add(-2 * time.Hour)}
+
+		decision := e.Evaluate(branch, "merged into main", 24*time.Hour)
+		assert.False(t, decision.Qualifies)
+		assert.Equal(t, "current branch", decision.Reason())
+		results := resultsByLabel(decision)
+		assert.Equal(t, evaluator.ResultFail, results["age"])
+		assert.Equal(t, evaluator.ResultFail, results["--local-only"])
+		assert.Equal(t, evaluator.ResultFail, results["protected ^feature/keep"])
+		assert.Equal(t, evaluator.ResultPass, results["include ^feature/"])
+
+		for _, check := range decision.Checks {
+			if check.Kind == evaluator.KindAge {
+				assert.Equal(t, "2h old, minimum 1d", check.Value)
+			}
+		}
+	})
+
+	t.Run("Reasons", func(t *testing.T) {
+		e := evaluator.New(cfg, service, evaluator.Options{Now: now, RemoteOnly: true})
+		old := now.Add(-48 * time.Hour)
+
+		assert.Equal(t, "too recent (< 1h old, minimum 1d)", e.Evaluate(&git.Branch{Name: "feature/a", IsRemote: true, LastCommitAt: now}, "r", 24*time.Hour).Reason())
+		assert.Equal(t, "local branch with --remote-only", e.Evaluate(&git.Branch{Name: "feature/a", LastCommitAt: old}, "r", 24*time.Hour).Reason())
+		assert.Equal(t, "no include pattern matches", e.Evaluate(&git.Branch{Name: "chore/a", IsRemote: true, LastCommitAt: old}, "r", 24*time.Hour).Reason())
+		assert.Equal(t, "protected by ^feature/keep", e.Evaluate(&git.Branch{Name: "feature/keep", IsRemote: true, LastCommitAt: old}, "r", 24*time.Hour).Reason())
+	})
+}
+
+func TestEvaluator_Explain(t *testing.T) {
+	cfg := newEvaluatorConfig()
+	mockClient := mocks.NewMockedGitClient()
+	e := evaluator.New(cfg, git.NewBranchServiceWithClient(mockClient, cfg.RemoteName), evaluator.Options{})
+
+	branch, err := e.FindBranch("feature/merged")
+	require.NoError(t, err)
+	assert.False(t, branch.IsRemote)
+
+	explanation := e.Explain(branch)
+	require.Len(t, explanation.Bases, 2)
+	assert.True(t, explanation.Bases[0].Merged)
+	require.NotNil(t, explanation.Bases[0].Decision)
+	assert.True(t, explanation.Bases[0].Decision.Qualifies)
+	assert.False(t, explanation.Bases[1].Exists, "develop does not exist in the mock")
+	assert.Nil(t, explanation.Gone)
+	require.NotNil(t, explanation.Qualifying)
+	assert.Equal(t, "merged into main", explanation.Qualifying.Rule)
+
+	explanation = e.Explain(mustFindBranch(t, e, "feature/test"))
+	assert.False(t, explanation.Bases[0].Merged)
+	assert.Nil(t, explanation.Qualifying)
+
+	remote, err := e.FindBranch("origin/main")
+	require.NoError(t, err)
+	assert.True(t, remote.IsRemote)
+
+	_, err = e.FindBranch("feature/missing")
+	assert.Error(t, err)
+}
+
+func mustFindBranch(t *testing.T, e *evaluator.Evaluator, name string) git.Branch {
+	t.Helper()
+	branch, err := e.FindBranch(name)
+	require.NoError(t, err)
+	return branch
+}
+
+func TestEvaluator_NoLocalCopy(t *testing.T) {
+	cfg := newEvaluatorConfig()
+	cfg.AllRemoteMaxAge = config.Duration(24 * time.Hour)
+	mockClient := mocks.NewMockedGitClient()
+	mockClient.AddBranch(mocks.BranchData{
+		Name: "feature/teammate", IsRemote: true, Remote: "origin",
+		CommitSHA: "fff000", CommitDate: time.Now().Add(-72 * time.Hour),
+	})
+	service := git.NewBranchServiceWithClient(mockClient, cfg.RemoteName)
+
+	e := evaluator.New(cfg, service, evaluator.Options{CleanAllRemote: true})
+	branch := mustFindBranch(t, e, "feature/teammate")
+	assert.True(t, branch.IsRemote)
+	assert.False(t, branch.HasLocal)
+	assert.True(t, e.HasNoLocalCopy(&branch))
+
+	explanation := e.Explain(branch)
+	require.NotNil(t, explanation.AllRemote)
+	assert.Equal(t, "no local copy", explanation.AllRemote.Rule)
+	assert.False(t, explanation.AllRemote.Qualifies, "age alone does not clean someone else's unmerged work")
+	assert.Equal(t, "not merged (use --force)", explanation.AllRemote.Reason())
+	assert.Nil(t, explanation.Qualifying)
+	assert.True(t, e.EvaluateNoLocalCopy(&branch, true).Qualifies, "merged branches qualify without --force")
+
+	forced := evaluator.New(cfg, service, evaluator.Options{CleanAllRemote: true, Force: true})
+	explanation = forced.Explain(branch)
+	require.NotNil(t, explanation.AllRemote)
+	assert.Same(t, explanation.AllRemote, explanation.Qualifying)
+
+	base := mustFindBranch(t, e, "origin/main")
+	assert.False(t, e.HasNoLocalCopy(&base), "base branches are never cleaned by this rule")
+	assert.False(t, e.HasNoLocalCopy(&git.Branch{Name: "feature/test"}))
+
+	e = evaluator.New(cfg, service, evaluator.Options{})
+	assert.Nil(t, e.Explain(branch).AllRemote, "the rule is off unless asked for")
+}
@@ -0,0 +1,101 @@
+package clean_git_tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchService_FetchRemotes(t *testing.T) {
+	t.Run("ReportsPrunedTrackingRefs", func(t *testing.T) {
+		mockClient := newForkClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/gone", CommitSHA: "ddd444", IsRemote: true, Remote: "origin"})
+		mockClient.SetFetchPrunes("origin", []string{"feature/gone", "feature/x"})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+
+		pruned, err := service.FetchRemotes()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"origin/feature/gone", "origin/feature/x"}, pruned)
+		assert.Equal(t, []string{"origin", "upstream"}, mockClient.GetFetchCalls(), "every configured remote is fetched")
+		assert.False(t, mockClient.HasBranch("remotes/origin/feature/gone"))
+		assert.True(t, mockClient.HasBranch("remotes/upstream/feature/x"), "a prune on one remote leaves the others alone")
+	})
+
+	t.Run("NothingPruned", func(t *testing.T) {
+		mockClient := newForkClient()
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		pruned, err := service.FetchRemotes()
+		require.NoError(t, err)
+		assert.Empty(t, pruned)
+		assert.Equal(t, []string{"origin"}, mockClient.GetFetchCalls())
+	})
+
+	t.Run("FailedFetchIsReported", func(t *testing.T) {
+		mockClient := newForkClient()
+		mockClient.SetCommandFailure("FetchPrune", git.ErrAuthFailed)
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+
+		pruned, err := service.FetchRemotes()
+		assert.Empty(t, pruned)
+		assert.True(t, errors.Is(err, git.ErrAuthFailed))
+	})
+}
+
+func TestBranchService_LastFetch(t *testing.T) {
+	t.Run("NeverFetched", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetGitDir(t.TempDir())
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		lastFetch, err := service.LastFetch()
+		require.NoError(t, err)
+		assert.True(t, lastFetch.IsZero())
+	})
+
+	t.Run("FetchHeadModificationTime", func(t *testing.T) {
+		gitDir := t.TempDir()
+		fetchHead := filepath.Join(gitDir, "FETCH_HEAD")
+		require.NoError(t, os.WriteFile(fetchHead, nil, 0o644))
+		fetchedAt := time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Second)
+		require.NoError(t, os.Chtimes(fetchHead, fetchedAt, fetchedAt))
+
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetGitDir(gitDir)
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		lastFetch, err := service.LastFetch()
+		require.NoError(t, err)
+		assert.True(t, fetchedAt.Equal(lastFetch))
+	})
+
+	t.Run("LinkedWorktreeSeesFetchesFromTheMainCheckout", func(t *testing.T) {
+		commonDir := t.TempDir()
+		worktreeDir := filepath.Join(commonDir, "worktrees", "feature")
+		require.NoError(t, os.MkdirAll(worktreeDir, 0o755))
+		touch := func(path string, at time.Time) {
+			require.NoError(t, os.WriteFile(path, nil, 0o644))
+			require.NoError(t, os.Chtimes(path, at, at))
+		}
+		mainFetch := time.Now().Add(-time.Hour).Truncate(time.Second)
+		touch(filepath.Join(worktreeDir, "FETCH_HEAD"), mainFetch.Add(-30*24*time.Hour))
+		touch(filepath.Join(commonDir, "FETCH_HEAD"), mainFetch)
+
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetGitDir(worktreeDir)
+		mockClient.SetGitCommonDir(commonDir)
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		lastFetch, err := service.LastFetch()
+		require.NoError(t, err)
+		assert.True(t, mainFetch.Equal(lastFetch), "the newest FETCH_HEAD counts")
+	})
+}
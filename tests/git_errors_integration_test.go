@@ -0,0 +1,105 @@
+package clean_git_tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandError(t *testing.T) {
+	t.Run("MessageShowsTheRejectedRef", func(t *testing.T) {
+		err := &git.CommandError{
+			Args:     []string{"push", "origin", "--delete", "feature/x"},
+			ExitCode: 1,
+			Stderr: "remote: error: denying ref deletion for refs/heads/feature/x\n" +
+				"To /srv/remote.git\n" +
+				" ! [remote rejected] feature/x (deletion prohibited)\n" +
+				"error: failed to push some refs to '/srv/remote.git'\n",
+			Kind: git.ErrProtectedByRemote,
+		}
+		assert.Equal(t, "git push origin --delete feature/x exited with status 1: [remote rejected] feature/x (deletion prohibited)", err.Error())
+	})
+
+	t.Run("MessageFallsBackToTheErrorLine", func(t *testing.T) {
+		err := &git.CommandError{
+			Args:     []string{"branch", "-d", "feature/x"},
+			ExitCode: 1,
+			Stderr:   "error: The branch 'feature/x' is not fully merged.\nIf you are sure you want to delete it, run 'git branch -D feature/x'.\n",
+		}
+		assert.Equal(t, "git branch -d feature/x exited with status 1: The branch 'feature/x' is not fully merged.", err.Error())
+	})
+
+	t.Run("UnwrapsToKindAndCause", func(t *testing.T) {
+		cause := errors.New("exit status 1")
+		err := fmt.Errorf("failed to delete: %w", &git.CommandError{Args: []string{"branch"}, Kind: git.ErrNotFullyMerged, Err: cause})
+
+		assert.ErrorIs(t, err, git.ErrNotFullyMerged)
+		assert.ErrorIs(t, err, cause)
+		assert.NotErrorIs(t, err, git.ErrBranchNotFound)
+
+		var commandErr *git.CommandError
+		require.ErrorAs(t, err, &commandErr)
+		assert.Equal(t, []string{"branch"}, commandErr.Args)
+	})
+}
+
+func TestGitErrors_ClassifiesRealGitOutput(t *testing.T) {
+	branchService := git.NewBranchService("origin")
+
+	err := branchService.DeleteBranch(&git.Branch{Name: "clean-git-test/does-not-exist"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, git.ErrBranchNotFound)
+
+	var unsafeErr *git.UnsafeDeleteError
+	assert.False(t, errors.As(err, &unsafeErr), "a missing branch is not a safety refusal")
+
+	var commandErr *git.CommandError
+	require.ErrorAs(t, err, &commandErr)
+	assert.Equal(t, "branch", commandErr.Args[0])
+	assert.Equal(t, 1, commandErr.ExitCode)
+	assert.Contains(t, commandErr.Stderr, "not found")
+}
+
+func TestBranchService_DeleteReactsToGitErrors(t *testing.T) {
+	failWith := func(kind error) *mocks.SophisticatedGitClient {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetCommandFailure("DeleteLocalBranch", &git.CommandError{Args: []string{"branch", "-d"}, ExitCode: 1, Kind: kind})
+		return mockClient
+	}
+
+	t.Run("NotFullyMergedIsARefusal", func(t *testing.T) {
+		service := git.NewBranchServiceWithClient(failWith(git.ErrNotFullyMerged), "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test"})
+		var unsafeErr *git.UnsafeDeleteError
+		require.ErrorAs(t, err, &unsafeErr)
+		assert.Equal(t, "git does not see it merged", unsafeErr.Reason)
+		assert.ErrorIs(t, err, git.ErrNotFullyMerged)
+	})
+
+	t.Run("WorktreeIsNotARefusal", func(t *testing.T) {
+		mockClient := failWith(git.ErrCheckedOutInWorktree)
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test", MergeMethod: git.MergeMethodSquash})
+		assert.ErrorIs(t, err, git.ErrCheckedOutInWorktree)
+		var unsafeErr *git.UnsafeDeleteError
+		assert.False(t, errors.As(err, &unsafeErr))
+		assert.Empty(t, mockClient.GetForceDeletedBranches(), "forcing cannot delete a checked out branch")
+	})
+
+	t.Run("MissingBranchIsNotARefusal", func(t *testing.T) {
+		service := git.NewBranchServiceWithClient(failWith(git.ErrBranchNotFound), "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/test"})
+		assert.ErrorIs(t, err, git.ErrBranchNotFound)
+		var unsafeErr *git.UnsafeDeleteError
+		assert.False(t, errors.As(err, &unsafeErr))
+	})
+}
@@ -0,0 +1,65 @@
+package clean_git_tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/journal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal_AppendAndSelectRuns(t *testing.T) {
+	gitDir := t.TempDir()
+	j := journal.Open(gitDir)
+
+	entries, err := j.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	firstRun := journal.NewRunID(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	secondRun := journal.NewRunID(time.Date(2024, 2, 2, 3, 4, 5, 0, time.UTC))
+	assert.Regexp(t, `^20240102T030405Z-[0-9a-f]{6}$`, firstRun)
+	assert.NotEqual(t, firstRun, journal.NewRunID(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		"runs started in the same second get different IDs")
+
+	require.NoError(t, j.Append(journal.Entry{RunID: firstRun, Branch: "feature/a", SHA: "aaa"}))
+	require.NoError(t, j.Append(journal.Entry{RunID: firstRun, Branch: "feature/b", SHA: "bbb"}))
+	require.NoError(t, j.Append(journal.Entry{RunID: secondRun, Branch: "feature/a", SHA: "ccc"}))
+	require.NoError(t, j.Append(journal.Entry{RunID: secondRun, Branch: "feature/a", SHA: "ddd", IsRemote: true, Remote: "origin"}))
+
+	assert.Equal(t, filepath.Join(gitDir, "clean-git", "journal.jsonl"), j.Path())
+
+	run, err := j.Run(firstRun)
+	require.NoError(t, err)
+	assert.Len(t, run, 2)
+
+	last, err := j.LastRun()
+	require.NoError(t, err)
+	require.Len(t, last, 2)
+	assert.Equal(t, secondRun, last[0].RunID)
+
+	latest, err := j.LatestForBranch("feature/a")
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, "ccc", latest[0].SHA)
+	assert.True(t, latest[1].IsRemote)
+
+	missing, err := j.LatestForBranch("feature/none")
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestJournal_CorruptedLine(t *testing.T) {
+	gitDir := t.TempDir()
+	j := journal.Open(gitDir)
+	require.NoError(t, os.MkdirAll(filepath.Dir(j.Path()), 0755))
+	require.NoError(t, os.WriteFile(j.Path(), []byte("{not json}\n"), 0644))
+
+	_, err := j.Entries()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1")
+}
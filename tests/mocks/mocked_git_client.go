@@ -2,7 +2,9 @@ package mocks
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,6 +12,39 @@ import (
 type DeleteRemoteBranchCall struct {
 	Remote     string
 	BranchName string
+	// LeaseSHA is set for DeleteRemoteBranchWithLease calls
+	LeaseSHA string
+}
+
+// PushBranchCall tracks calls to PushBranch for testing
+type PushBranchCall struct {
+	Remote     string
+	BranchName string
+	SHA        string
+}
+
+// CherryCount is the result of comparing a branch's unique commits with a base by patch-id
+type CherryCount struct {
+	Upstream int
+	Total    int
+}
+
+// UpstreamTracking is what GetUpstreamInfo reports for a local branch
+type UpstreamTracking struct {
+	Upstream string
+	Track    string // e.g. "[gone]" or "[ahead 1, behind 2]"
+}
+
+// Divergence is what GetAheadBehind and CountAheadBehind report for a branch ref
+type Divergence struct {
+	Ahead  int
+	Behind int
+}
+
+// UpstreamState describes how a local branch relates to its upstream
+type UpstreamState struct {
+	HasUpstream bool
+	Contains    bool
 }
 
 // SophisticatedGitClient provides realistic git command simulation
@@ -21,6 +56,22 @@ type SophisticatedGitClient struct {
 	commandFailures         map[string]error         // command -> error to return
 	deleteRemoteBranchCalls []DeleteRemoteBranchCall // Track delete remote branch calls
 	mergedBranchesByBase    map[string][]string      // base branch -> merged branch names
+	pushBranchCalls         []PushBranchCall         // Track push branch calls
+	upstreamStates          map[string]UpstreamState     // branch -> upstream comparison
+	forceDeletedBranches    []string                     // Track force deletions
+	refs                    map[string]string            // non-branch ref -> sha
+	remoteRefs              map[string]map[string]string // remote -> ref -> sha
+	squashMergedByBase      map[string][]string          // base ref -> squash merged branch names
+	cherryCountsByBase      map[string]map[string]CherryCount // base ref -> branch -> patch-id comparison
+	upstreamTracking        map[string]UpstreamTracking       // branch -> configured upstream
+	divergenceByBase        map[string]map[string]Divergence  // base ref -> branch ref -> ahead/behind
+	uniqueCommits           map[string]int                    // branch ref -> commits in no base
+	fetchPrunes             map[string][]string               // remote -> branches the next fetch prunes
+	fetchCalls              []string                          // remotes fetched, in order
+	gitDir                  string
+	gitCommonDir            string
+	movesBeforeDelete       map[string]string // branch -> sha it moves to right before a leased delete
+	cherryCalls             atomic.Int32 // CherryCounts calls, which the pool may make concurrently
 }
 
 type BranchData struct {
@@ -32,6 +83,7 @@ type BranchData struct {
 	IsMerged    bool
 	IsRemote    bool
 	Remote      string
+	Subject     string
 }
 
 func NewMockedGitClient() *SophisticatedGitClient {
@@ -82,6 +134,15 @@ func NewMockedGitClient() *SophisticatedGitClient {
 		commandFailures:         map[string]error{},
 		deleteRemoteBranchCalls: []DeleteRemoteBranchCall{},
 		mergedBranchesByBase:    map[string][]string{},
+		upstreamStates:          map[string]UpstreamState{},
+		refs:                    map[string]string{},
+		remoteRefs:              map[string]map[string]string{},
+		squashMergedByBase:      map[string][]string{},
+		cherryCountsByBase:      map[string]map[string]CherryCount{},
+		upstreamTracking:        map[string]UpstreamTracking{},
+		divergenceByBase:        map[string]map[string]Divergence{},
+		uniqueCommits:           map[string]int{},
+		fetchPrunes:             map[string][]string{},
 	}
 }
 
@@ -124,11 +185,94 @@ func (m *SophisticatedGitClient) SetMergedBranchesForBase(base string, branches
 	m.mergedBranchesByBase[base] = branches
 }
 
+// SetSquashMerged configures which branches IsSquashMerged reports as squash merged into base
+func (m *SophisticatedGitClient) SetSquashMerged(base string, branches []string) {
+	m.squashMergedByBase[base] = branches
+}
+
+// GetCherryCallCount returns how often CherryCounts was called
+func (m *SophisticatedGitClient) GetCherryCallCount() int {
+	return int(m.cherryCalls.Load())
+}
+
+// SetCherryCounts configures the CherryCounts result for a branch compared with base
+func (m *SophisticatedGitClient) SetCherryCounts(base, branch string, count CherryCount) {
+	if m.cherryCountsByBase[base] == nil {
+		m.cherryCountsByBase[base] = make(map[string]CherryCount)
+	}
+	m.cherryCountsByBase[base][branch] = count
+}
+
+// SetDivergence configures the ahead/behind counts of a branch ref (e.g.
+// refs/heads/feature/x) against base
+func (m *SophisticatedGitClient) SetDivergence(base, ref string, divergence Divergence) {
+	if m.divergenceByBase[base] == nil {
+		m.divergenceByBase[base] = make(map[string]Divergence)
+	}
+	m.divergenceByBase[base][ref] = divergence
+}
+
+// SetUniqueCommits configures CountUniqueCommits for a branch ref
+func (m *SophisticatedGitClient) SetUniqueCommits(ref string, count int) {
+	m.uniqueCommits[ref] = count
+}
+
 // GetDeleteRemoteBranchCalls returns all tracked DeleteRemoteBranch calls for testing
 func (m *SophisticatedGitClient) GetDeleteRemoteBranchCalls() []DeleteRemoteBranchCall {
 	return m.deleteRemoteBranchCalls
 }
 
+// SetUpstreamState configures the result of UpstreamContains for a branch
+func (m *SophisticatedGitClient) SetUpstreamState(branch string, state UpstreamState) {
+	m.upstreamStates[branch] = state
+}
+
+// SetUpstreamTracking configures the upstream GetUpstreamInfo reports for a branch
+func (m *SophisticatedGitClient) SetUpstreamTracking(branch string, tracking UpstreamTracking) {
+	m.upstreamTracking[branch] = tracking
+}
+
+// GetForceDeletedBranches returns every branch removed through ForceDeleteLocalBranch
+func (m *SophisticatedGitClient) GetForceDeletedBranches() []string {
+	return m.forceDeletedBranches
+}
+
+// SetRef creates or moves a non-branch ref such as an archive ref
+func (m *SophisticatedGitClient) SetRef(ref, sha string) {
+	m.refs[ref] = sha
+}
+
+// SetRemoteRef creates or moves a ref on a simulated remote
+func (m *SophisticatedGitClient) SetRemoteRef(remote, ref, sha string) {
+	if m.remoteRefs[remote] == nil {
+		m.remoteRefs[remote] = make(map[string]string)
+	}
+	m.remoteRefs[remote][ref] = sha
+}
+
+// GetRef returns the sha of a non-branch ref and whether it exists
+func (m *SophisticatedGitClient) GetRef(ref string) (string, bool) {
+	sha, exists := m.refs[ref]
+	return sha, exists
+}
+
+// GetRemoteRef returns the sha of a ref on a simulated remote and whether it exists
+func (m *SophisticatedGitClient) GetRemoteRef(remote, ref string) (string, bool) {
+	sha, exists := m.remoteRefs[remote][ref]
+	return sha, exists
+}
+
+// GetPushBranchCalls returns all tracked PushBranch calls for testing
+func (m *SophisticatedGitClient) GetPushBranchCalls() []PushBranchCall {
+	return m.pushBranchCalls
+}
+
+// HasBranch reports whether the mock currently holds the given branch key
+func (m *SophisticatedGitClient) HasBranch(key string) bool {
+	_, exists := m.branches[key]
+	return exists
+}
+
 // GitClient interface implementation
 func (m *SophisticatedGitClient) Run(args ...string) (string, error) {
 	command := strings.Join(args, " ")
@@ -242,12 +386,28 @@ func (m *SophisticatedGitClient) GetBranchCommitInfo(branchName string) (string,
 		return "", fmt.Errorf("branch %s not found", branchName)
 	}
 
-	return fmt.Sprintf("%s|%s|%s|%s",
+	info := fmt.Sprintf("%s|%s|%s|%s",
 		data.CommitDate.Format("2006-01-02 15:04:05 -0700"),
 		data.AuthorName,
 		data.AuthorEmail,
 		data.CommitSHA,
-	), nil
+	)
+	if data.Subject != "" {
+		info += "|" + data.Subject
+	}
+	return info, nil
+}
+
+func (m *SophisticatedGitClient) GetBranchLog(ref string, limit int) (string, error) {
+	if err, exists := m.commandFailures["GetBranchLog"]; exists {
+		return "", err
+	}
+
+	sha, err := m.ResolveRef(ref)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s commit on %s", sha, ref), nil
 }
 
 func (m *SophisticatedGitClient) DeleteLocalBranch(branchName string) error {
@@ -263,6 +423,70 @@ func (m *SophisticatedGitClient) DeleteLocalBranch(branchName string) error {
 	return nil
 }
 
+func (m *SophisticatedGitClient) ForceDeleteLocalBranch(branchName string) error {
+	if err, exists := m.commandFailures["ForceDeleteLocalBranch"]; exists {
+		return err
+	}
+
+	if branchName == m.currentBranch {
+		return fmt.Errorf("cannot delete current branch %s", branchName)
+	}
+
+	m.forceDeletedBranches = append(m.forceDeletedBranches, branchName)
+	delete(m.branches, branchName)
+	return nil
+}
+
+// MoveBeforeDelete makes a leased delete of branchName find it at sha, as if
+// someone committed after the lease was checked
+func (m *SophisticatedGitClient) MoveBeforeDelete(branchName, sha string) {
+	if m.movesBeforeDelete == nil {
+		m.movesBeforeDelete = make(map[string]string)
+	}
+	m.movesBeforeDelete[branchName] = sha
+}
+
+// DeleteLocalBranchAt fails like update-ref -d when the branch is no longer at sha
+func (m *SophisticatedGitClient) DeleteLocalBranchAt(branchName, sha string, force bool) error {
+	failure := "DeleteLocalBranch"
+	if force {
+		failure = "ForceDeleteLocalBranch"
+	}
+	if err, exists := m.commandFailures[failure]; exists {
+		return err
+	}
+	if branchName == m.currentBranch {
+		return fmt.Errorf("cannot delete current branch %s", branchName)
+	}
+
+	data, exists := m.branches[branchName]
+	if !exists {
+		return fmt.Errorf("branch %s not found", branchName)
+	}
+	if moved, ok := m.movesBeforeDelete[branchName]; ok {
+		data.CommitSHA = moved
+		m.branches[branchName] = data
+	}
+	if data.CommitSHA != sha {
+		return fmt.Errorf("cannot lock ref 'refs/heads/%s': is at %s but expected %s", branchName, data.CommitSHA, sha)
+	}
+
+	if force {
+		m.forceDeletedBranches = append(m.forceDeletedBranches, branchName)
+	}
+	delete(m.branches, branchName)
+	return nil
+}
+
+func (m *SophisticatedGitClient) UpstreamContains(branchName string) (bool, bool, error) {
+	if err, exists := m.commandFailures["UpstreamContains"]; exists {
+		return false, true, err
+	}
+
+	state := m.upstreamStates[branchName]
+	return state.Contains, state.HasUpstream, nil
+}
+
 func (m *SophisticatedGitClient) DeleteRemoteBranch(remote, branchName string) error {
 	if err, exists := m.commandFailures["DeleteRemoteBranch"]; exists {
 		return err
@@ -279,6 +503,27 @@ func (m *SophisticatedGitClient) DeleteRemoteBranch(remote, branchName string) e
 	return nil
 }
 
+// DeleteRemoteBranchWithLease fails like git push --force-with-lease when the
+// remote branch is no longer at sha
+func (m *SophisticatedGitClient) DeleteRemoteBranchWithLease(remote, branchName, sha string) error {
+	if err, exists := m.commandFailures["DeleteRemoteBranchWithLease"]; exists {
+		return err
+	}
+
+	key := "remotes/" + remote + "/" + branchName
+	if data, exists := m.branches[key]; exists && data.CommitSHA != sha {
+		return fmt.Errorf("failed to delete remote branch %s/%s at %s: stale info", remote, branchName, sha)
+	}
+
+	m.deleteRemoteBranchCalls = append(m.deleteRemoteBranchCalls, DeleteRemoteBranchCall{
+		Remote:     remote,
+		BranchName: branchName,
+		LeaseSHA:   sha,
+	})
+	delete(m.branches, key)
+	return nil
+}
+
 func (m *SophisticatedGitClient) HasUnpushedCommits(branchName string) (bool, error) {
 	if err, exists := m.commandFailures["HasUnpushedCommits"]; exists {
 		return false, err
@@ -288,7 +533,7 @@ func (m *SophisticatedGitClient) HasUnpushedCommits(branchName string) (bool, er
 	return exists && count > 0, nil
 }
 
-func (m *SophisticatedGitClient) BranchExists(branchName string) (bool, error) {
+func (m *SophisticatedGitClient) BranchExists(branchName string, remotes []string) (bool, error) {
 	if err, exists := m.commandFailures["BranchExists"]; exists {
 		return false, err
 	}
@@ -306,6 +551,9 @@ func (m *SophisticatedGitClient) BranchExists(branchName string) (bool, error) {
 			if remoteName == "" {
 				remoteName = "origin"
 			}
+			if !containsString(remotes, remoteName) {
+				continue
+			}
 			// Check if requested branch matches remote/branch format
 			if branchName == remoteName+"/"+storedData.Name || branchName == storedName {
 				return true, nil
@@ -316,6 +564,137 @@ func (m *SophisticatedGitClient) BranchExists(branchName string) (bool, error) {
 	return false, nil
 }
 
+func (m *SophisticatedGitClient) ResolveRef(ref string) (string, error) {
+	if err, exists := m.commandFailures["ResolveRef"]; exists {
+		return "", err
+	}
+
+	if sha, exists := m.refs[ref]; exists {
+		return sha, nil
+	}
+
+	key := strings.TrimPrefix(ref, "refs/heads/")
+	if strings.HasPrefix(ref, "refs/remotes/") {
+		key = "remotes/" + strings.TrimPrefix(ref, "refs/remotes/")
+	}
+
+	data, exists := m.branches[key]
+	if !exists {
+		return "", fmt.Errorf("failed to resolve %s", ref)
+	}
+	return data.CommitSHA, nil
+}
+
+func (m *SophisticatedGitClient) CreateLocalBranch(branchName, sha string) error {
+	if err, exists := m.commandFailures["CreateLocalBranch"]; exists {
+		return err
+	}
+
+	if _, exists := m.branches[branchName]; exists {
+		return fmt.Errorf("a branch named '%s' already exists", branchName)
+	}
+
+	m.branches[branchName] = BranchData{
+		Name:       branchName,
+		CommitDate: time.Now(),
+		CommitSHA:  sha,
+	}
+	return nil
+}
+
+func (m *SophisticatedGitClient) PushBranch(remote, branchName, sha string) error {
+	if err, exists := m.commandFailures["PushBranch"]; exists {
+		return err
+	}
+
+	m.pushBranchCalls = append(m.pushBranchCalls, PushBranchCall{
+		Remote:     remote,
+		BranchName: branchName,
+		SHA:        sha,
+	})
+
+	m.branches["remotes/"+remote+"/"+branchName] = BranchData{
+		Name:       branchName,
+		CommitDate: time.Now(),
+		CommitSHA:  sha,
+		IsRemote:   true,
+		Remote:     remote,
+	}
+	return nil
+}
+
+func (m *SophisticatedGitClient) UpdateRef(ref, sha string) error {
+	if err, exists := m.commandFailures["UpdateRef"]; exists {
+		return err
+	}
+	m.refs[ref] = sha
+	return nil
+}
+
+func (m *SophisticatedGitClient) DeleteRef(ref string) error {
+	if err, exists := m.commandFailures["DeleteRef"]; exists {
+		return err
+	}
+	if _, exists := m.refs[ref]; !exists {
+		return fmt.Errorf("ref %s not found", ref)
+	}
+	delete(m.refs, ref)
+	return nil
+}
+
+func (m *SophisticatedGitClient) PushRef(remote, sha, ref string) error {
+	if err, exists := m.commandFailures["PushRef"]; exists {
+		return err
+	}
+	m.SetRemoteRef(remote, ref, sha)
+	return nil
+}
+
+func (m *SophisticatedGitClient) DeleteRemoteRef(remote, ref string) error {
+	if err, exists := m.commandFailures["DeleteRemoteRef"]; exists {
+		return err
+	}
+	if _, exists := m.remoteRefs[remote][ref]; !exists {
+		return fmt.Errorf("remote ref %s does not exist on %s", ref, remote)
+	}
+	delete(m.remoteRefs[remote], ref)
+	return nil
+}
+
+func (m *SophisticatedGitClient) ListRefs(prefix string) (map[string]string, error) {
+	if err, exists := m.commandFailures["ListRefs"]; exists {
+		return nil, err
+	}
+	refs := filterRefs(m.refs, prefix)
+	for key, data := range m.branches {
+		ref := "refs/heads/" + key
+		if data.IsRemote {
+			ref = "refs/" + key
+		}
+		if strings.HasPrefix(ref, prefix) {
+			refs[ref] = data.CommitSHA
+		}
+	}
+	return refs, nil
+}
+
+func (m *SophisticatedGitClient) ListRemoteRefs(remote, prefix string) (map[string]string, error) {
+	if err, exists := m.commandFailures["ListRemoteRefs"]; exists {
+		return nil, err
+	}
+	return filterRefs(m.remoteRefs[remote], prefix), nil
+}
+
+func filterRefs(refs map[string]string, prefix string) map[string]string {
+	filtered := make(map[string]string)
+	for ref, sha := range refs {
+		if strings.HasPrefix(ref, prefix) {
+			filtered[ref] = sha
+		}
+	}
+	return filtered
+}
+
 // Helper methods for output simulation
 func (m *SophisticatedGitClient) getMergedBranchesOutput(args []string) string {
 	var output []string
@@ -387,3 +766,184 @@ func (m *SophisticatedGitClient) getUnpushedCountOutput(args []string) string {
 	}
 	return fmt.Sprintf("%d", count)
 }
+
+func (m *SophisticatedGitClient) IsSquashMerged(branchRef, baseRef string) (bool, error) {
+	if err, exists := m.commandFailures["IsSquashMerged"]; exists {
+		return false, err
+	}
+
+	// Local branches by name, remote ones as remotes/<remote>/<name>
+	branchName := strings.TrimPrefix(strings.TrimPrefix(branchRef, "refs/heads/"), "refs/")
+	for _, name := range m.squashMergedByBase[baseRef] {
+		if name == branchName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *SophisticatedGitClient) CherryCounts(branchRef, baseRef string) (int, int, error) {
+	m.cherryCalls.Add(1)
+	if err, exists := m.commandFailures["CherryCounts"]; exists {
+		return 0, 0, err
+	}
+
+	count := m.cherryCountsByBase[baseRef][strings.TrimPrefix(branchRef, "refs/heads/")]
+	return count.Upstream, count.Total, nil
+}
+
+func (m *SophisticatedGitClient) GetUpstreamInfo(branchName string) (string, string, error) {
+	if err, exists := m.commandFailures["GetUpstreamInfo"]; exists {
+		return "", "", err
+	}
+
+	tracking := m.upstreamTracking[branchName]
+	return tracking.Upstream, tracking.Track, nil
+}
+
+// GetBranchMetadata renders the mocked branches the way for-each-ref prints
+// them with the service's NUL separated metadata format.
+func (m *SophisticatedGitClient) GetBranchMetadata(patterns ...string) (string, error) {
+	if err, exists := m.commandFailures["GetBranchMetadata"]; exists {
+		return "", err
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"refs/heads", "refs/remotes"}
+	}
+
+	var lines []string
+	for key, data := range m.branches {
+		refName := "refs/heads/" + key
+		if data.IsRemote {
+			refName = "refs/" + key
+		}
+		if !matchesRefPattern(refName, patterns) {
+			continue
+		}
+
+		head := " "
+		var upstream, track string
+		if !data.IsRemote {
+			if key == m.currentBranch {
+				head = "*"
+			}
+			tracking := m.upstreamTracking[key]
+			upstream, track = tracking.Upstream, tracking.Track
+			if count := m.unpushedCommits[key]; count > 0 && track == "" {
+				track = fmt.Sprintf("[ahead %d]", count)
+			}
+		}
+
+		lines = append(lines, strings.Join([]string{
+			refName,
+			"",
+			data.CommitSHA,
+			data.CommitDate.Format("2006-01-02 15:04:05 -0700"),
+			data.AuthorName,
+			"<" + data.AuthorEmail + ">",
+			upstream,
+			track,
+			head,
+			data.Subject,
+		}, "\x00"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func matchesRefPattern(refName string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if refName == pattern || strings.HasPrefix(refName, pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAheadBehind renders the configured divergences the way for-each-ref
+// prints %(ahead-behind); fail it with SetCommandFailure to simulate git < 2.41
+func (m *SophisticatedGitClient) GetAheadBehind(baseRef string) (string, error) {
+	if err, exists := m.commandFailures["GetAheadBehind"]; exists {
+		return "", err
+	}
+
+	var lines []string
+	for ref, divergence := range m.divergenceByBase[baseRef] {
+		lines = append(lines, fmt.Sprintf("%s\x00%d %d", ref, divergence.Ahead, divergence.Behind))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (m *SophisticatedGitClient) CountAheadBehind(ref, baseRef string) (int, int, error) {
+	if err, exists := m.commandFailures["CountAheadBehind"]; exists {
+		return 0, 0, err
+	}
+
+	divergence := m.divergenceByBase[baseRef][ref]
+	return divergence.Ahead, divergence.Behind, nil
+}
+
+func (m *SophisticatedGitClient) CountUniqueCommits(ref string, baseRefs []string) (int, error) {
+	if err, exists := m.commandFailures["CountUniqueCommits"]; exists {
+		return 0, err
+	}
+	return m.uniqueCommits[ref], nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFetchPrunes makes the next FetchPrune of remote drop its tracking refs for branches
+func (m *SophisticatedGitClient) SetFetchPrunes(remote string, branches []string) {
+	m.fetchPrunes[remote] = branches
+}
+
+// GetFetchCalls returns the remotes FetchPrune was called for, in order
+func (m *SophisticatedGitClient) GetFetchCalls() []string {
+	return m.fetchCalls
+}
+
+// SetGitDir sets the directory GitPath resolves names in
+func (m *SophisticatedGitClient) SetGitDir(dir string) {
+	m.gitDir = dir
+}
+
+// SetGitCommonDir sets the directory shared by every worktree; the git
+// directory when unset, as in a repository without linked worktrees
+func (m *SophisticatedGitClient) SetGitCommonDir(dir string) {
+	m.gitCommonDir = dir
+}
+
+func (m *SophisticatedGitClient) FetchPrune(remote string) error {
+	if err, exists := m.commandFailures["FetchPrune"]; exists {
+		return err
+	}
+	m.fetchCalls = append(m.fetchCalls, remote)
+	for _, branch := range m.fetchPrunes[remote] {
+		delete(m.branches, "remotes/"+remote+"/"+branch)
+	}
+	delete(m.fetchPrunes, remote)
+	return nil
+}
+
+func (m *SophisticatedGitClient) GitPath(name string) (string, error) {
+	if err, exists := m.commandFailures["GitPath"]; exists {
+		return "", err
+	}
+	if m.gitDir == "" {
+		return "", fmt.Errorf("no git directory set")
+	}
+	return filepath.Join(m.gitDir, name), nil
+}
+
+func (m *SophisticatedGitClient) GitCommonDir() (string, error) {
+	if m.gitCommonDir != "" {
+		return m.gitCommonDir, nil
+	}
+	return m.GitPath("")
+}
@@ -0,0 +1,157 @@
+package clean_git_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forkRemotes is a fork (origin) of the canonical repository (upstream)
+var forkRemotes = []git.Remote{
+	{Name: "origin", Push: true},
+	{Name: "upstream", MergeSource: true},
+}
+
+func newForkClient() *mocks.SophisticatedGitClient {
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	mockClient := mocks.NewMockedGitClient()
+	mockClient.ClearBranches()
+	for _, branch := range []mocks.BranchData{
+		{Name: "main", CommitSHA: "aaa111", CommitDate: old},
+		{Name: "feature/x", CommitSHA: "bbb222", CommitDate: old},
+		{Name: "main", CommitSHA: "aaa111", CommitDate: old, IsRemote: true, Remote: "origin"},
+		{Name: "feature/x", CommitSHA: "bbb222", CommitDate: old, IsRemote: true, Remote: "origin"},
+		{Name: "main", CommitSHA: "ccc333", CommitDate: old, IsRemote: true, Remote: "upstream"},
+		{Name: "feature/x", CommitSHA: "bbb222", CommitDate: old, IsRemote: true, Remote: "upstream"},
+	} {
+		mockClient.AddBranch(branch)
+	}
+	return mockClient
+}
+
+func TestBranchService_MultipleRemotes(t *testing.T) {
+	t.Run("MergedIntoAMergeSourceRemote", func(t *testing.T) {
+		mockClient := newForkClient()
+		// Our main and origin/main are behind upstream/main, which has feature/x
+		mockClient.SetMergedBranchesForBase("main", []string{})
+		mockClient.SetMergedBranchesForBase("origin/main", []string{"feature/stale"})
+		mockClient.SetMergedBranchesForBase("upstream/main", []string{
+			"feature/x", "remotes/origin/feature/x", "remotes/upstream/feature/x", "remotes/upstream/main",
+		})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+
+		branches, err := service.GetMergedBranches("main")
+		require.NoError(t, err)
+
+		var names []string
+		for _, branch := range branches {
+			names = append(names, branchKeyOf(branch))
+		}
+		assert.ElementsMatch(t, []string{"feature/x", "origin/feature/x"}, names,
+			"only branches of push remotes are candidates, and origin is not a merge source")
+	})
+
+	t.Run("RefusesToDeleteFromAMergeSource", func(t *testing.T) {
+		mockClient := newForkClient()
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/x", IsRemote: true, Remote: "upstream"})
+		assert.ErrorIs(t, err, git.ErrRemoteNotOwned)
+		err = service.ArchiveBranch(&git.Branch{Name: "feature/x", IsRemote: true, Remote: "upstream"}, git.ArchiveRefPrefix+"feature/x", true)
+		assert.ErrorIs(t, err, git.ErrRemoteNotOwned)
+		assert.Empty(t, mockClient.GetDeleteRemoteBranchCalls())
+
+		require.NoError(t, service.DeleteBranch(&git.Branch{Name: "feature/x", IsRemote: true}))
+		calls := mockClient.GetDeleteRemoteBranchCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "origin", calls[0].Remote, "branches without a remote belong to the first push remote")
+	})
+
+	t.Run("ListsTheRemotesEachBranchIsOn", func(t *testing.T) {
+		mockClient := newForkClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/local", CommitSHA: "ddd444"})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+
+		branches, err := service.GetBranchesWithTrackedRemotes()
+		require.NoError(t, err)
+
+		remotes := make(map[string][]string)
+		for _, branch := range branches {
+			require.NotEqual(t, "upstream", branch.Remote, "branches of merge-source remotes are not ours to list")
+			remotes[branchKeyOf(branch)] = branch.Remotes
+		}
+		assert.Equal(t, []string{"origin", "upstream"}, remotes["feature/x"])
+		assert.Equal(t, []string{"origin", "upstream"}, remotes["origin/feature/x"])
+		assert.Empty(t, remotes["feature/local"])
+		assert.Contains(t, remotes, "feature/local")
+	})
+
+	t.Run("ListsRemoteBranchesWithoutALocalCopy", func(t *testing.T) {
+		mockClient := newForkClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "teammate/y", CommitSHA: "eee555", IsRemote: true, Remote: "origin"})
+		mockClient.AddBranch(mocks.BranchData{Name: "canonical/z", CommitSHA: "fff666", IsRemote: true, Remote: "upstream"})
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+
+		branches, err := service.GetRemoteBranches()
+		require.NoError(t, err)
+
+		hasLocal := make(map[string]bool)
+		for _, branch := range branches {
+			require.True(t, branch.IsRemote)
+			hasLocal[branchKeyOf(branch)] = branch.HasLocal
+		}
+		assert.Equal(t, map[string]bool{
+			"origin/main":       true,
+			"origin/feature/x":  true,
+			"origin/teammate/y": false,
+		}, hasLocal, "only push remotes are listed")
+	})
+
+	t.Run("RemoteNamesThatPrefixEachOther", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.ClearBranches()
+		mockClient.AddBranch(mocks.BranchData{Name: "main", CommitSHA: "aaa111"})
+		mockClient.AddBranch(mocks.BranchData{Name: "x", CommitSHA: "bbb222", IsRemote: true, Remote: "team/fork"})
+		mockClient.AddBranch(mocks.BranchData{Name: "forked/y", CommitSHA: "ccc333", IsRemote: true, Remote: "team"})
+		remotes := []git.Remote{{Name: "team", Push: true}, {Name: "team/fork", Push: true}}
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: remotes})
+
+		branches, err := service.GetRemoteBranches()
+		require.NoError(t, err)
+
+		remoteOf := make(map[string]string)
+		for _, branch := range branches {
+			remoteOf[branch.Name] = branch.Remote
+		}
+		assert.Equal(t, map[string]string{"x": "team/fork", "forked/y": "team"}, remoteOf,
+			"the longest configured remote name wins")
+	})
+
+	t.Run("BaseOnAnyRemoteExists", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.ClearBranches()
+		mockClient.AddBranch(mocks.BranchData{Name: "develop", IsRemote: true, Remote: "upstream"})
+
+		service := git.NewBranchServiceWithClientOptions(mockClient, git.Options{Remotes: forkRemotes})
+		exists, err := service.BranchExists("upstream/develop")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		service = git.NewBranchServiceWithClient(mockClient, "origin")
+		exists, err = service.BranchExists("upstream/develop")
+		require.NoError(t, err)
+		assert.False(t, exists, "remotes that are not configured are not looked at")
+	})
+}
+
+func branchKeyOf(branch git.Branch) string {
+	if branch.IsRemote {
+		return branch.Remote + "/" + branch.Name
+	}
+	return branch.Name
+}
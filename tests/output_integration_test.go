@@ -0,0 +1,173 @@
+package clean_git_tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/output"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutput_ParseFormat(t *testing.T) {
+	for _, input := range []string{"text", "json", "ndjson"} {
+		format, err := output.ParseFormat(input)
+		require.NoError(t, err)
+		assert.Equal(t, output.Format(input), format)
+	}
+	assert.False(t, output.FormatText.IsMachine())
+	assert.True(t, output.FormatNDJSON.IsMachine())
+
+	_, err := output.ParseFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestOutput_Writer(t *testing.T) {
+	t.Run("JSONWritesOneArray", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := output.NewWriter(output.FormatJSON, &buf)
+		w.Write(map[string]string{"name": "feature/a"})
+		w.Write(map[string]string{"name": "feature/b"})
+		assert.Empty(t, buf.String(), "JSON records are written on Close")
+		require.NoError(t, w.Close())
+
+		var records []map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &records))
+		assert.Equal(t, []map[string]string{{"name": "feature/a"}, {"name": "feature/b"}}, records)
+	})
+
+	t.Run("JSONClosedTwiceWritesOnce", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := output.NewWriter(output.FormatJSON, &buf)
+		w.Write(map[string]string{"name": "feature/a"})
+		require.NoError(t, w.Close())
+		require.NoError(t, w.Close())
+
+		var records []map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &records), "an interrupted run closes before the deferred close")
+		assert.Len(t, records, 1)
+	})
+
+	t.Run("JSONWithoutRecordsIsEmptyArray", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, output.NewWriter(output.FormatJSON, &buf).Close())
+		assert.Equal(t, "[]\n", buf.String())
+	})
+
+	t.Run("NDJSONStreamsLines", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := output.NewWriter(output.FormatNDJSON, &buf)
+		w.Write(map[string]string{"reason": "too recent (< 1h old)"})
+		assert.Equal(t, `{"reason":"too recent (< 1h old)"}`+"\n", buf.String())
+		w.Write(map[string]string{"name": "b"})
+		require.NoError(t, w.Close())
+		assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 2)
+	})
+
+	t.Run("TextWritesNothing", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := output.NewWriter(output.FormatText, &buf)
+		w.Write(map[string]string{"name": "a"})
+		require.NoError(t, w.Close())
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestOutput_NewBranch(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	branch := git.Branch{
+		Name:           "origin/feature/a",
+		IsRemote:       true,
+		IsMerged:       true,
+		MergeMethod:    git.MergeMethodSquash,
+		LastCommitAt:   now.Add(-48 * time.Hour),
+		LastCommitSHA:  "abc1234",
+		AuthorUserName: "Jane",
+		AuthorEmail:    "jane@example.com",
+		Remote:         "origin",
+	}
+
+	record := output.NewBranch(branch, now)
+	assert.Equal(t, "remote", record.Type)
+	assert.Equal(t, output.MergeStatusMerged, record.MergeStatus)
+	assert.Equal(t, int64(48*60*60), record.AgeSeconds)
+	assert.Equal(t, "Jane", record.Author)
+
+	data, err := json.Marshal(record)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "squash", fields["mergeMethod"])
+	assert.Equal(t, []interface{}{}, fields["reasons"])
+	assert.NotContains(t, fields, "outcome")
+	assert.NotContains(t, fields, "uniqueCommits", "divergence is only reported when it was counted")
+
+	record = output.NewBranch(git.Branch{Name: "feature/b", LastCommitAt: now}, now)
+	assert.Equal(t, "local", record.Type)
+	assert.Equal(t, output.MergeStatusNotMerged, record.MergeStatus)
+
+	record = output.NewBranch(git.Branch{
+		Name:       "feature/c",
+		Subject:    "Add feature c",
+		Divergence: []git.Divergence{{Base: "main", Ahead: 0, Behind: 4}},
+	}, now)
+	assert.Equal(t, []output.Divergence{{Base: "main", Ahead: 0, Behind: 4}}, record.Divergence)
+	require.NotNil(t, record.UniqueCommits)
+	assert.Equal(t, 0, *record.UniqueCommits)
+	view := output.NewView(record)
+	assert.Equal(t, "Add feature c", view.Subject)
+	assert.Equal(t, 4, view.Divergence[0].Behind)
+}
+
+func TestOutput_Template(t *testing.T) {
+	now := time.Now()
+	record := output.NewBranch(git.Branch{
+		Name:          "feature/a",
+		LastCommitAt:  now.Add(-50 * time.Hour),
+		LastCommitSHA: "0123456789abcdef0123456789abcdef01234567",
+		AuthorEmail:   "jane@example.com",
+		Upstream:      "refs/remotes/origin/feature/a",
+	}, now)
+	record.Reasons = []string{"merged into main", "upstream gone"}
+
+	t.Run("RendersViewWithHelpers", func(t *testing.T) {
+		tmpl, err := output.ParseTemplate(`{{.Name}}\t{{.AuthorEmail}}\t{{.Age}}\t{{ago .LastCommitAt}}\t{{short .SHA}}\t{{short .Upstream}}\t{{.Reasons | join ", "}}`)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		w := output.NewTemplateWriter(tmpl, &buf)
+		w.Write(record)
+		require.NoError(t, w.Close())
+		assert.Equal(t, "feature/a\tjane@example.com\t2d\t2d ago\t0123456\torigin/feature/a\tmerged into main, upstream gone\n", buf.String())
+	})
+
+	t.Run("KeepsTrailingNewline", func(t *testing.T) {
+		tmpl, err := output.ParseTemplate("{{.Name}} {{.Age.Days}}\n")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buf, record))
+		assert.Equal(t, "feature/a 2\n", buf.String())
+	})
+
+	t.Run("SkipsEmptyLines", func(t *testing.T) {
+		tmpl, err := output.ParseTemplate("{{if .Qualifies}}{{.Name}}{{end}}")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buf, record))
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("RejectsMalformedTemplates", func(t *testing.T) {
+		for _, text := range []string{"{{.Name", "{{.Nmae}}", "{{nope .Name}}", "{{join .Reasons}}"} {
+			_, err := output.ParseTemplate(text)
+			assert.Error(t, err, text)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package clean_git_tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/plan"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const planSHA = "d6e352d5a399da2c9d9a69ffd7912dc36ebd2f8c"
+
+func TestPlan_WriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	branch := &git.Branch{
+		Name:           "feature/done",
+		AuthorUserName: "Alice",
+		LastCommitAt:   time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		MergeMethod:    git.MergeMethodSquash,
+	}
+	written := &plan.Plan{
+		Version:    plan.Version,
+		CreatedAt:  time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC),
+		Repository: "/src/repo",
+		Action:     plan.ActionDelete,
+		Branches:   []plan.Branch{plan.NewBranch(branch, planSHA, "merged into main")},
+	}
+	require.NoError(t, plan.Write(path, written))
+
+	read, err := plan.Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, written, read)
+
+	leased := read.Branches[0].GitBranch()
+	assert.Equal(t, planSHA, leased.ExpectedSHA)
+	assert.Equal(t, git.MergeMethodSquash, leased.MergeMethod, "squash merges must survive so apply can force delete them")
+}
+
+func TestPlan_ReadRejectsInvalidPlans(t *testing.T) {
+	valid := `{"name": "feature/a", "isRemote": false, "sha": "` + planSHA + `", "reason": "merged into main"}`
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "unsupported version",
+			content: `{"version": 99, "action": "delete", "branches": []}`,
+			wantErr: "unsupported plan version",
+		},
+		{
+			name:    "unknown action",
+			content: `{"version": 1, "action": "rename", "branches": []}`,
+			wantErr: "unknown action",
+		},
+		{
+			name:    "abbreviated SHA",
+			content: `{"version": 1, "action": "delete", "branches": [{"name": "feature/a", "sha": "d6e352d"}]}`,
+			wantErr: "not a full commit SHA",
+		},
+		{
+			name:    "branch listed twice",
+			content: `{"version": 1, "action": "delete", "branches": [` + valid + `, ` + valid + `]}`,
+			wantErr: "listed twice",
+		},
+		{
+			name:    "not JSON",
+			content: `feature/a`,
+			wantErr: "failed to parse plan",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			_, err := plan.Read(path)
+			require.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), tt.wantErr), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestBranchService_DeleteBranchWithLease(t *testing.T) {
+	t.Run("delete local branch still at its planned tip", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/leased", CommitSHA: planSHA})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/leased", IsMerged: true, ExpectedSHA: planSHA})
+		require.NoError(t, err)
+		assert.False(t, mockClient.HasBranch("feature/leased"))
+	})
+
+	t.Run("refuse local branch whose tip moved", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/leased", CommitSHA: "moved123"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		for name, remove := range map[string]func(*git.Branch) error{
+			"delete":       service.DeleteBranch,
+			"force delete": service.ForceDeleteBranch,
+			"archive": func(branch *git.Branch) error {
+				return service.ArchiveBranch(branch, git.ArchiveRefFor(time.Now(), branch.Name), false)
+			},
+		} {
+			err := remove(&git.Branch{Name: "feature/leased", IsMerged: true, ExpectedSHA: planSHA})
+
+			var staleErr *git.StaleTipError
+			require.ErrorAs(t, err, &staleErr, name)
+			assert.Equal(t, planSHA, staleErr.Expected)
+			assert.Equal(t, "moved123", staleErr.Actual)
+			assert.True(t, mockClient.HasBranch("feature/leased"), "%s must leave a moved branch alone", name)
+		}
+	})
+
+	t.Run("refuse local branch that moves while it is deleted", func(t *testing.T) {
+		for name, remove := range map[string]func(git.BranchService, *git.Branch) error{
+			"delete":       git.BranchService.DeleteBranch,
+			"force delete": git.BranchService.ForceDeleteBranch,
+			"archive": func(service git.BranchService, branch *git.Branch) error {
+				return service.ArchiveBranch(branch, git.ArchiveRefFor(time.Now(), branch.Name), false)
+			},
+		} {
+			mockClient := mocks.NewMockedGitClient()
+			mockClient.AddBranch(mocks.BranchData{Name: "feature/leased", CommitSHA: planSHA})
+			mockClient.MoveBeforeDelete("feature/leased", "moved123")
+			service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+			err := remove(service, &git.Branch{Name: "feature/leased", IsMerged: true, ExpectedSHA: planSHA})
+
+			var staleErr *git.StaleTipError
+			require.ErrorAs(t, err, &staleErr, name)
+			assert.Equal(t, "moved123", staleErr.Actual)
+			assert.True(t, mockClient.HasBranch("feature/leased"), "%s must leave a moved branch alone", name)
+		}
+	})
+
+	t.Run("delete remote branch with a lease", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/remote", CommitSHA: planSHA, IsRemote: true, Remote: "origin"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/remote", IsRemote: true, Remote: "origin", ExpectedSHA: planSHA})
+		require.NoError(t, err)
+
+		calls := mockClient.GetDeleteRemoteBranchCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "feature/remote", calls[0].BranchName)
+		assert.Equal(t, planSHA, calls[0].LeaseSHA)
+	})
+
+	t.Run("delete remote branch without a lease", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.AddBranch(mocks.BranchData{Name: "feature/remote", CommitSHA: planSHA, IsRemote: true, Remote: "origin"})
+		service := git.NewBranchServiceWithClient(mockClient, "origin")
+
+		err := service.DeleteBranch(&git.Branch{Name: "feature/remote", IsRemote: true, Remote: "origin"})
+		require.NoError(t, err)
+
+		calls := mockClient.GetDeleteRemoteBranchCalls()
+		require.Len(t, calls, 1)
+		assert.Empty(t, calls[0].LeaseSHA)
+	})
+}
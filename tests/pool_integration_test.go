@@ -0,0 +1,147 @@
+package clean_git_tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abey/clean-git/internal/git"
+	"github.com/abey/clean-git/internal/pool"
+	"github.com/abey/clean-git/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_Run(t *testing.T) {
+	t.Run("RunsEveryIndexOnce", func(t *testing.T) {
+		var mu sync.Mutex
+		seen := make(map[int]int)
+		started := pool.Run(context.Background(), 3, 10, func(i int) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[i]++
+		})
+
+		assert.Equal(t, 10, started)
+		assert.Len(t, seen, 10)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, 1, seen[i], "index %d", i)
+		}
+	})
+
+	t.Run("BoundsConcurrentCalls", func(t *testing.T) {
+		var running, peak int32
+		pool.Run(context.Background(), 2, 8, func(i int) {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+
+		assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	})
+
+	t.Run("TreatsZeroWorkersAsOne", func(t *testing.T) {
+		var calls int32
+		started := pool.Run(context.Background(), 0, 3, func(i int) {
+			atomic.AddInt32(&calls, 1)
+		})
+		assert.Equal(t, 3, started)
+		assert.Equal(t, int32(3), calls)
+	})
+
+	t.Run("StopsDispatchingWhenCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int32
+		started := pool.Run(ctx, 1, 10, func(i int) {
+			atomic.AddInt32(&calls, 1)
+			if i == 2 {
+				cancel()
+			}
+		})
+
+		assert.Equal(t, int32(started), atomic.LoadInt32(&calls))
+		assert.Equal(t, 3, started, "a single worker starts nothing after the call that cancelled")
+	})
+
+	t.Run("StartedCallsStayAPrefixWhenCancelled", func(t *testing.T) {
+		for attempt := 0; attempt < 50; attempt++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			var mu sync.Mutex
+			ran := make(map[int]bool)
+			started := pool.Run(ctx, 8, 200, func(i int) {
+				mu.Lock()
+				ran[i] = true
+				mu.Unlock()
+				if i == 20 {
+					cancel()
+				}
+			})
+			cancel()
+
+			require.Len(t, ran, started)
+			for i := 0; i < started; i++ {
+				require.True(t, ran[i], "index %d below %d did not run", i, started)
+			}
+			require.Less(t, started, 200)
+		}
+	})
+
+	t.Run("StartsNothingWhenAlreadyCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		started := pool.Run(ctx, 4, 5, func(i int) {
+			t.Errorf("index %d ran after cancellation", i)
+		})
+		assert.Equal(t, 0, started)
+	})
+}
+
+func TestBranchService_ParallelDetection(t *testing.T) {
+	newService := func(mockClient *mocks.SophisticatedGitClient, jobs int, ctx context.Context) git.BranchService {
+		return git.NewBranchServiceWithClientOptions(mockClient, git.Options{
+			RemoteName:         "origin",
+			DetectSquashMerges: true,
+			DetectRebaseMerges: true,
+			Jobs:               jobs,
+			Context:            ctx,
+		})
+	}
+
+	t.Run("MatchesSequentialResults", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetSquashMerged("main", []string{"feature/test"})
+
+		methods := func(jobs int) map[string]git.MergeMethod {
+			branches, err := newService(mockClient, jobs, nil).GetMergedBranches("main")
+			require.NoError(t, err)
+			result := make(map[string]git.MergeMethod)
+			for _, branch := range branches {
+				result[branch.Name] = branch.MergeMethod
+			}
+			return result
+		}
+
+		sequential := methods(1)
+		assert.Equal(t, git.MergeMethodSquash, sequential["feature/test"])
+		assert.Equal(t, sequential, methods(4))
+	})
+
+	t.Run("CancelledContextIsAnError", func(t *testing.T) {
+		mockClient := mocks.NewMockedGitClient()
+		mockClient.SetSquashMerged("main", []string{"feature/test"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := newService(mockClient, 4, ctx).GetMergedBranches("main")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}